@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/ratelimit"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/sentry"
 
 	"github.com/pedromspeixoto/posts-api/internal/config"
@@ -10,8 +11,11 @@ import (
 	"github.com/pedromspeixoto/posts-api/internal/domain"
 	"github.com/pedromspeixoto/posts-api/internal/http"
 	"github.com/pedromspeixoto/posts-api/internal/http/handlers"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/storage"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/validator"
+	"github.com/pedromspeixoto/posts-api/internal/scheduler"
 	"go.uber.org/fx"
 )
 
@@ -19,6 +23,12 @@ import (
 // @version 1.0
 // @description Posts API - Create blog posts and store in database
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	var cfgFilePath string
 	flag.StringVar(
@@ -35,12 +45,16 @@ func main() {
 		logger.ProvideLogger(),
 		validator.ProvideValidator(),
 		sentry.ProvideSentry(),
+		auth.ProvideAuth(),
+		ratelimit.ProvideRateLimit(),
+		storage.ProvideStorage(),
 		data.ProvideData(),
 		models.ProvideModels(),
 		domain.ProvideDomains(),
 		handlers.ProvideHandlers(),
 		// Invoke
 		http.InvokeServer(),
+		scheduler.InvokeScheduler(),
 	)
 
 	app.Run()
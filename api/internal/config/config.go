@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -16,6 +17,7 @@ const (
 )
 
 type UserDetails struct {
+	Email    string
 	Password string
 	Role     string
 }
@@ -26,6 +28,80 @@ type Config struct {
 	Port         string `envconfig:"APP_PORT" default:"8080"`
 	AllowedHosts string `envconfig:"ALLOWED_HOSTS" default:"*"`
 
+	// Concurrency control
+	RequireIfMatch bool `envconfig:"REQUIRE_IF_MATCH" required:"false" default:"false"`
+
+	// Pagination
+	PaginationCursorSecret string `envconfig:"PAGINATION_CURSOR_SECRET" required:"false"`
+
+	// Authentication
+	JWTSecret        string `envconfig:"JWT_HS256_SECRET" required:"false"`
+	JWTPublicKeyFile string `envconfig:"JWT_RS256_PUBLIC_KEY_FILE" required:"false"`
+	JWTJWKSFile      string `envconfig:"JWT_JWKS_FILE" required:"false"`
+	JWTIssuer        string `envconfig:"JWT_ISSUER" required:"false"`
+	JWTAudience      string `envconfig:"JWT_AUDIENCE" required:"false"`
+
+	// Users, registration is open so self-registered users only get to read and react
+	// unless operators opt in to a more privileged role
+	JWTAccessTokenTTL  time.Duration `envconfig:"JWT_ACCESS_TOKEN_TTL" required:"false" default:"15m"`
+	JWTRefreshTokenTTL time.Duration `envconfig:"JWT_REFRESH_TOKEN_TTL" required:"false" default:"720h"`
+	DefaultUserRole    string        `envconfig:"DEFAULT_USER_ROLE" required:"false" default:"viewer"`
+	Admin              UserDetails   `envconfig:"ADMIN" required:"false"`
+
+	// Rate limiting, requests per period for each route group, 0 disables the group
+	RateLimitEnabled       bool          `envconfig:"RATE_LIMIT_ENABLED" required:"false" default:"true"`
+	RateLimitStore         string        `envconfig:"RATE_LIMIT_STORE" required:"false" default:"memory"`
+	RateLimitReadRequests  int           `envconfig:"RATE_LIMIT_READ_REQUESTS" required:"false" default:"300"`
+	RateLimitReadPeriod    time.Duration `envconfig:"RATE_LIMIT_READ_PERIOD" required:"false" default:"1m"`
+	RateLimitWriteRequests int           `envconfig:"RATE_LIMIT_WRITE_REQUESTS" required:"false" default:"60"`
+	RateLimitWritePeriod   time.Duration `envconfig:"RATE_LIMIT_WRITE_PERIOD" required:"false" default:"1m"`
+	RateLimitAuthRequests  int           `envconfig:"RATE_LIMIT_AUTH_REQUESTS" required:"false" default:"10"`
+	RateLimitAuthPeriod    time.Duration `envconfig:"RATE_LIMIT_AUTH_PERIOD" required:"false" default:"1m"`
+
+	// Scheduler, which also runs queued background jobs like large imports. Replicas
+	// with it disabled never run them, so at least one replica has to keep it enabled.
+	// Job runs are recorded for as long as the retention, 0 keeps them forever
+	SchedulerEnabled      bool          `envconfig:"SCHEDULER_ENABLED" required:"false" default:"true"`
+	SchedulerInterval     time.Duration `envconfig:"SCHEDULER_INTERVAL" required:"false" default:"30s"`
+	SchedulerBatchSize    int           `envconfig:"SCHEDULER_BATCH_SIZE" required:"false" default:"100"`
+	SchedulerMaxAttempts  int           `envconfig:"SCHEDULER_MAX_ATTEMPTS" required:"false" default:"3"`
+	SchedulerRetryBackoff time.Duration `envconfig:"SCHEDULER_RETRY_BACKOFF" required:"false" default:"1s"`
+	JobRunRetention       time.Duration `envconfig:"JOB_RUN_RETENTION" required:"false" default:"168h"`
+
+	// Trash, how long deleted posts can be restored for, 0 keeps them forever
+	TrashRetention time.Duration `envconfig:"TRASH_RETENTION" required:"false" default:"720h"`
+
+	// Reactions, the kinds of reactions posts accept
+	ReactionKinds []string `envconfig:"REACTION_KINDS" required:"false" default:"like,love,laugh"`
+
+	// Idempotency, how long responses to requests with an Idempotency-Key are replayed for
+	// and how long a request in flight holds its key, past which a retry takes it over.
+	// The lease matches the request timeout, requests that crash or time out don't
+	// block their key for longer. Bodies are read whole to fingerprint requests, so
+	// their size is bounded
+	IdempotencyKeyTTL      time.Duration `envconfig:"IDEMPOTENCY_KEY_TTL" required:"false" default:"24h"`
+	IdempotencyKeyLease    time.Duration `envconfig:"IDEMPOTENCY_KEY_LEASE" required:"false" default:"60s"`
+	IdempotencyMaxBodySize int64         `envconfig:"IDEMPOTENCY_MAX_BODY_SIZE" required:"false" default:"1048576"`
+
+	// Batches, how many operations and bytes a batch takes and how many posts are
+	// inserted at a time
+	BatchMaxOperations int   `envconfig:"BATCH_MAX_OPERATIONS" required:"false" default:"1000"`
+	BatchMaxSize       int64 `envconfig:"BATCH_MAX_SIZE" required:"false" default:"10485760"`
+	BatchInsertSize    int   `envconfig:"BATCH_INSERT_SIZE" required:"false" default:"100"`
+
+	// Imports, uploads over the sync size run as background jobs
+	ImportSyncMaxSize int64 `envconfig:"IMPORT_SYNC_MAX_SIZE" required:"false" default:"1048576"`
+	ImportMaxSize     int64 `envconfig:"IMPORT_MAX_SIZE" required:"false" default:"104857600"`
+
+	// Media, where uploads are stored and what is accepted. MediaLocalShared tells that
+	// every replica mounts the same local dir, trivially true of a single replica, which
+	// background imports depend on
+	MediaStore        string   `envconfig:"MEDIA_STORE" required:"false" default:"local"`
+	MediaLocalDir     string   `envconfig:"MEDIA_LOCAL_DIR" required:"false" default:"media"`
+	MediaLocalShared  bool     `envconfig:"MEDIA_LOCAL_SHARED" required:"false" default:"false"`
+	MediaMaxSize      int64    `envconfig:"MEDIA_MAX_SIZE" required:"false" default:"10485760"`
+	MediaAllowedTypes []string `envconfig:"MEDIA_ALLOWED_TYPES" required:"false" default:"image/png,image/jpeg,image/gif,image/webp,application/pdf"`
+
 	// Logging
 	LoggerType  string `envconfig:"LOGGER_TYPE" required:"false" default:"zap"`
 	LoggerLevel int    `envconfig:"LOGGER_LEVEL" required:"false" default:"2"`
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Cursor marks a position in a keyset ordered result set. It holds the values of the
+// sort fields and the primary key of the row the next page should seek from.
+type Cursor struct {
+	Sort     string        `json:"s"`
+	Values   []CursorValue `json:"v"`
+	ID       uint          `json:"i"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// CursorValue is the value of a sort field, nil when the field is NULL.
+type CursorValue struct {
+	Value  interface{} `json:"v"`
+	IsTime bool        `json:"t,omitempty"`
+}
+
+// EncodeCursor serializes and signs a cursor into an opaque token.
+func EncodeCursor(c *Cursor, secret []byte) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signature := base64.RawURLEncoding.EncodeToString(sign(encoded, secret))
+	return fmt.Sprintf("%s.%s", encoded, signature), nil
+}
+
+// DecodeCursor verifies the signature of an opaque token and deserializes the cursor.
+func DecodeCursor(token string, secret []byte) (*Cursor, error) {
+	splits := strings.Split(token, ".")
+	if len(splits) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(splits[1])
+	if err != nil || !hmac.Equal(signature, sign(splits[0], secret)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(splits[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func sign(payload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// value returns the sort field value in a form that can be bound as a query parameter.
+func (c CursorValue) value() (interface{}, error) {
+	if !c.IsTime {
+		return c.Value, nil
+	}
+	raw, ok := c.Value.(string)
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// newCursor builds the cursor pointing at the given row for the sort fields.
+func newCursor(db *gorm.DB, q *query.Query, row interface{}, backward bool) (*Cursor, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return nil, err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%s has no primary key", stmt.Schema.Name)
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	cursor := &Cursor{
+		Sort:     q.SortString(),
+		Backward: backward,
+	}
+	for _, sort := range q.Sorts {
+		field := stmt.Schema.LookUpField(sort.Column)
+		if field == nil {
+			return nil, fmt.Errorf("sort field %s does not support cursors", sort.Field)
+		}
+		value, _ := field.ValueOf(context.Background(), rv)
+		v := reflect.ValueOf(value)
+		if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			cursor.Values = append(cursor.Values, CursorValue{})
+			continue
+		}
+		if v.Kind() == reflect.Ptr {
+			value = v.Elem().Interface()
+		}
+		if t, ok := value.(time.Time); ok {
+			cursor.Values = append(cursor.Values, CursorValue{Value: t.Format(time.RFC3339Nano), IsTime: true})
+			continue
+		}
+		cursor.Values = append(cursor.Values, CursorValue{Value: value})
+	}
+
+	id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(context.Background(), rv)
+	if pk, ok := id.(uint); ok {
+		cursor.ID = pk
+	}
+	return cursor, nil
+}
+
+// KeysetPage trims the extra row fetched to detect further pages, restores the
+// requested order for backward pages and sets the next and previous cursors.
+func KeysetPage[T any](db *gorm.DB, p *Pagination, rows []T) ([]T, error) {
+	hasMore := len(rows) > p.GetLimit()
+	if hasMore {
+		rows = rows[:p.GetLimit()]
+	}
+
+	backward := p.Cursor != nil && p.Cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, nil
+	}
+
+	// a forward page has a successor when more rows were found, a backward page
+	// always has one: the page it was requested from
+	if hasMore || backward {
+		next, err := newCursor(db, p.GetQuery(), &rows[len(rows)-1], false)
+		if err != nil {
+			return nil, err
+		}
+		p.NextCursor = next
+	}
+
+	// likewise for predecessors, mirrored
+	if (backward && hasMore) || (!backward && (p.Cursor != nil || p.GetPage() > 1)) {
+		prev, err := newCursor(db, p.GetQuery(), &rows[0], true)
+		if err != nil {
+			return nil, err
+		}
+		p.PrevCursor = prev
+	}
+
+	return rows, nil
+}
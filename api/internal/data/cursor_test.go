@@ -0,0 +1,147 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	created := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor *Cursor
+		values []interface{}
+	}{
+		{
+			name:   "time value",
+			cursor: &Cursor{Sort: "created_at.desc", Values: []CursorValue{{Value: created.Format(time.RFC3339Nano), IsTime: true}}, ID: 7},
+			values: []interface{}{created},
+		},
+		{
+			name:   "string and number values",
+			cursor: &Cursor{Sort: "title.asc,reading_time.desc", Values: []CursorValue{{Value: "hello"}, {Value: 3}}, ID: 42, Backward: true},
+			values: []interface{}{"hello", "3"},
+		},
+		{
+			name:   "null value",
+			cursor: &Cursor{Sort: "publish_at.desc", Values: []CursorValue{{}}, ID: 1},
+			values: []interface{}{nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := EncodeCursor(tt.cursor, secret)
+			if err != nil {
+				t.Fatalf("EncodeCursor() error = %v", err)
+			}
+			decoded, err := DecodeCursor(token, secret)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if decoded.Sort != tt.cursor.Sort || decoded.ID != tt.cursor.ID || decoded.Backward != tt.cursor.Backward {
+				t.Errorf("DecodeCursor() = %+v, want %+v", decoded, tt.cursor)
+			}
+			if len(decoded.Values) != len(tt.values) {
+				t.Fatalf("DecodeCursor() has %d values, want %d", len(decoded.Values), len(tt.values))
+			}
+			for i, want := range tt.values {
+				got, err := decoded.Values[i].value()
+				if err != nil {
+					t.Fatalf("value() error = %v", err)
+				}
+				if number, ok := got.(json.Number); ok {
+					got = number.String()
+				}
+				if wantTime, ok := want.(time.Time); ok {
+					if gotTime, ok := got.(time.Time); !ok || !gotTime.Equal(wantTime) {
+						t.Errorf("value() = %v, want %v", got, want)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("value() = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	secret := []byte("secret")
+	token, err := EncodeCursor(&Cursor{Sort: "created_at.desc", Values: []CursorValue{{Value: "x"}}, ID: 7}, secret)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"created_at.desc","v":[{"v":"x"}],"i":1}`))
+
+	tests := []struct {
+		name   string
+		token  string
+		secret []byte
+	}{
+		{name: "forged payload", token: forged + "." + signature, secret: secret},
+		{name: "truncated signature", token: payload + "." + signature[:len(signature)-2], secret: secret},
+		{name: "signature of another payload", token: payload + "." + base64.RawURLEncoding.EncodeToString(sign(forged, secret)), secret: secret},
+		{name: "other secret", token: token, secret: []byte("other")},
+		{name: "missing signature", token: payload, secret: secret},
+		{name: "extra part", token: token + ".x", secret: secret},
+		{name: "invalid base64 signature", token: payload + ".!!", secret: secret},
+		{name: "empty", token: "", secret: secret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.token, tt.secret); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsInvalidPayload(t *testing.T) {
+	secret := []byte("secret")
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "not base64", payload: "!!"},
+		{name: "not json", payload: base64.RawURLEncoding.EncodeToString([]byte("nope"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.payload + "." + base64.RawURLEncoding.EncodeToString(sign(tt.payload, secret))
+			if _, err := DecodeCursor(token, secret); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCursorValueRejectsInvalidTimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value CursorValue
+	}{
+		{name: "not a string", value: CursorValue{Value: 12, IsTime: true}},
+		{name: "not a time", value: CursorValue{Value: "yesterday", IsTime: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.value.value(); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("value() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
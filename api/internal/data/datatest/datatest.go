@@ -0,0 +1,325 @@
+// Package datatest provides databases for testing repositories without a MySQL server,
+// either building statements without running them or answering them from a script.
+package datatest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// AnyArg matches any argument of a statement.
+var AnyArg = anyArg{}
+
+type anyArg struct{}
+
+// DryRun returns a database that builds statements without running them.
+func DryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := sql.Open("mysql", "user:password@tcp(127.0.0.1:3306)/test")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	return open(t, conn, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+}
+
+// New returns a database whose statements are answered by the returned script, in the
+// order they're expected. Statements nobody expected fail the test. Transactions are
+// recorded but don't need to be expected.
+func New(t *testing.T) (*gorm.DB, *Script) {
+	t.Helper()
+	script := &Script{t: t}
+	db := open(t, sql.OpenDB(connector{script}), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	t.Cleanup(func() {
+		if remaining := script.remaining(); len(remaining) > 0 {
+			t.Errorf("expected statements were not run: %s", strings.Join(remaining, "; "))
+		}
+	})
+	return db, script
+}
+
+func open(t *testing.T, conn *sql.DB, config *gorm.Config) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), config)
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	return db
+}
+
+// Script is the list of statements a database built by New expects.
+type Script struct {
+	t            *testing.T
+	mu           sync.Mutex
+	expectations []*Expectation
+	transactions []string
+}
+
+// Expectation is a statement expected to run, matched by a fragment of its SQL.
+type Expectation struct {
+	fragment     string
+	args         []interface{}
+	columns      []string
+	rows         [][]driver.Value
+	lastInsertId int64
+	rowsAffected int64
+	err          error
+	done         bool
+}
+
+// Expect expects a statement whose SQL contains the fragment. It answers with no rows
+// and no rows affected unless told otherwise.
+func (s *Script) Expect(fragment string) *Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expectation := &Expectation{fragment: fragment}
+	s.expectations = append(s.expectations, expectation)
+	return expectation
+}
+
+// Transactions returns how the transactions begun so far ended, in order: "commit",
+// "rollback", or "open" for those still running.
+func (s *Script) Transactions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.transactions...)
+}
+
+// WithArgs also matches the arguments of the statement. AnyArg matches any argument.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	return e
+}
+
+// WillReturnRows answers a query with rows of the columns.
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]driver.Value) *Expectation {
+	e.columns, e.rows = columns, rows
+	return e
+}
+
+// WillReturnResult answers a statement with the id it inserted and the rows it affected.
+func (e *Expectation) WillReturnResult(lastInsertId, rowsAffected int64) *Expectation {
+	e.lastInsertId, e.rowsAffected = lastInsertId, rowsAffected
+	return e
+}
+
+// WillReturnError fails the statement with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// next matches a statement against the first expectation that hasn't run yet.
+func (s *Script) next(query string, args []driver.NamedValue) (*Expectation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, expectation := range s.expectations {
+		if expectation.done {
+			continue
+		}
+		if !strings.Contains(query, expectation.fragment) {
+			break
+		}
+		if expectation.args != nil && !matchArgs(expectation.args, args) {
+			s.t.Errorf("statement %s ran with %v, want %v", query, values(args), expectation.args)
+			return nil, fmt.Errorf("datatest: unexpected arguments")
+		}
+		expectation.done = true
+		return expectation, expectation.err
+	}
+	s.t.Errorf("unexpected statement %s %v", query, values(args))
+	return nil, fmt.Errorf("datatest: unexpected statement %s", query)
+}
+
+func (s *Script) remaining() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var remaining []string
+	for _, expectation := range s.expectations {
+		if !expectation.done {
+			remaining = append(remaining, expectation.fragment)
+		}
+	}
+	return remaining
+}
+
+func matchArgs(want []interface{}, got []driver.NamedValue) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i, arg := range want {
+		if arg == AnyArg {
+			continue
+		}
+		converted, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil || !reflect.DeepEqual(converted, got[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func values(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type connector struct {
+	script *Script
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{script: c.script}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return nil
+}
+
+type conn struct {
+	script *Script
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.script.mu.Lock()
+	defer c.script.mu.Unlock()
+	c.script.transactions = append(c.script.transactions, "open")
+	return &tx{script: c.script, index: len(c.script.transactions) - 1}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	expectation, err := c.script.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: expectation.columns, values: expectation.rows}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	expectation, err := c.script.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{lastInsertId: expectation.lastInsertId, rowsAffected: expectation.rowsAffected}, nil
+}
+
+// CheckNamedValue converts arguments to driver values the way database/sql does by
+// default, so expectations compare against the same values.
+func (c *conn) CheckNamedValue(value *driver.NamedValue) error {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+	value.Value = converted
+	return nil
+}
+
+type tx struct {
+	script *Script
+	index  int
+}
+
+func (t *tx) Commit() error {
+	return t.end("commit")
+}
+
+func (t *tx) Rollback() error {
+	return t.end("rollback")
+}
+
+func (t *tx) end(outcome string) error {
+	t.script.mu.Lock()
+	defer t.script.mu.Unlock()
+	t.script.transactions[t.index] = outcome
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return namedArgs
+}
+
+type result struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,149 @@
+package apikeys
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"gorm.io/gorm"
+)
+
+const scopeSeparator = " "
+
+// QuerySchema is the allow-list of fields API keys can be filtered and sorted by.
+var QuerySchema = query.Schema{
+	"owner_id":   {Column: "owner_id", Type: query.String, Operators: query.Equality},
+	"name":       {Column: "name", Type: query.String, Operators: query.Text, Sortable: true, Searchable: true},
+	"created_at": {Column: "created_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+}
+
+// APIKey is a credential for headless clients. Only the SHA-256 hash of the key is
+// stored, the prefix is kept to tell keys apart.
+type APIKey struct {
+	ID         uint `gorm:"primarykey"`
+	KeyId      string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     string
+	OwnerId    string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ScopeList returns the scopes granted to the key.
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// SetScopes sets the scopes granted to the key.
+func (k *APIKey) SetScopes(scopes []string) {
+	k.Scopes = strings.Join(scopes, scopeSeparator)
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyRepository is a repository for dealing with the API key object.
+type APIKeyRepository interface {
+	// List lists API keys from the database with pagination.
+	List(pagination *data.Pagination) ([]APIKey, *data.Pagination, error)
+	// GetByUUID gets an API key from the database by uuid.
+	GetByUUID(uuid string) (*APIKey, error)
+	// GetByHash gets an API key from the database by the hash of the key.
+	GetByHash(hash string) (*APIKey, error)
+	// Create creates an API key in the database.
+	Create(key *APIKey) error
+	// Revoke revokes an API key.
+	Revoke(key *APIKey) error
+	// Touch records the use of an API key, at most once per interval.
+	Touch(key *APIKey, interval time.Duration) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+func (a apiKeyRepository) List(pagination *data.Pagination) ([]APIKey, *data.Pagination, error) {
+	var keys []APIKey
+
+	result := a.db.Scopes(pagination.Paginate()).Find(&keys)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	keys, err := data.KeysetPage(a.db, pagination, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pagination details
+	if !pagination.SkipCount {
+		result = a.db.Model(&APIKey{}).Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
+
+	return keys, pagination, nil
+}
+
+func (a apiKeyRepository) GetByUUID(uuid string) (*APIKey, error) {
+	key := APIKey{}
+	result := a.db.Where("key_id = ?", uuid).First(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+func (a apiKeyRepository) GetByHash(hash string) (*APIKey, error) {
+	key := APIKey{}
+	result := a.db.Where("key_hash = ?", hash).First(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+func (a apiKeyRepository) Create(key *APIKey) error {
+	result := a.db.Create(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (a apiKeyRepository) Revoke(key *APIKey) error {
+	now := time.Now()
+	result := a.db.Model(key).Where("revoked_at IS NULL").Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+func (a apiKeyRepository) Touch(key *APIKey, interval time.Duration) error {
+	now := time.Now()
+	result := a.db.Model(key).
+		Where("last_used_at IS NULL OR last_used_at < ?", now.Add(-interval)).
+		UpdateColumn("last_used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
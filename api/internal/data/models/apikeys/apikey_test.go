@@ -0,0 +1,90 @@
+package apikeys
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+)
+
+func TestAPIKeyActive(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	earlier, later := now.Add(-time.Second), now.Add(time.Second)
+
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{name: "no expiry", key: APIKey{}, want: true},
+		{name: "expires later", key: APIKey{ExpiresAt: &later}, want: true},
+		{name: "expires now", key: APIKey{ExpiresAt: &now}, want: false},
+		{name: "expired", key: APIKey{ExpiresAt: &earlier}, want: false},
+		{name: "revoked", key: APIKey{RevokedAt: &earlier}, want: false},
+		{name: "revoked before expiring", key: APIKey{ExpiresAt: &later, RevokedAt: &earlier}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	key := APIKey{}
+	key.SetScopes([]string{"posts:read", "comments:write"})
+	if key.Scopes != "posts:read comments:write" {
+		t.Errorf("SetScopes() stored %q", key.Scopes)
+	}
+	if got, want := key.ScopeList(), []string{"posts:read", "comments:write"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ScopeList() = %v, want %v", got, want)
+	}
+	if got := (&APIKey{}).ScopeList(); len(got) != 0 {
+		t.Errorf("ScopeList() of a key without scopes = %v, want none", got)
+	}
+}
+
+func TestAPIKeyRepositoryGetByHash(t *testing.T) {
+	db, script := datatest.New(t)
+	script.Expect("SELECT * FROM `api_keys` WHERE key_hash = ?").WithArgs("hash").
+		WillReturnRows([]string{"id", "key_id", "key_hash", "scopes", "owner_id"}, []driver.Value{int64(3), "k1", "hash", "posts:read", "u1"})
+
+	key, err := NewAPIKeyRepository(db).GetByHash("hash")
+	if err != nil {
+		t.Fatalf("GetByHash() error = %v", err)
+	}
+	if key.KeyId != "k1" || key.OwnerId != "u1" || key.Scopes != "posts:read" {
+		t.Errorf("GetByHash() = %+v", key)
+	}
+}
+
+func TestAPIKeyRepositoryRevoke(t *testing.T) {
+	db, script := datatest.New(t)
+	// revoking twice keeps the first revocation time
+	script.Expect("UPDATE `api_keys` SET `revoked_at`=?,`updated_at`=? WHERE revoked_at IS NULL AND `id` = ?").
+		WithArgs(datatest.AnyArg, datatest.AnyArg, int64(3)).WillReturnResult(0, 1)
+
+	key := &APIKey{ID: 3}
+	if err := NewAPIKeyRepository(db).Revoke(key); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if key.RevokedAt == nil {
+		t.Error("Revoke() didn't set the revocation time")
+	}
+}
+
+func TestAPIKeyRepositoryTouch(t *testing.T) {
+	db, script := datatest.New(t)
+	// uses within the interval aren't written
+	script.Expect("UPDATE `api_keys` SET `last_used_at`=? WHERE (last_used_at IS NULL OR last_used_at < ?) AND `id` = ?").
+		WithArgs(datatest.AnyArg, datatest.AnyArg, int64(3))
+
+	if err := NewAPIKeyRepository(db).Touch(&APIKey{ID: 3}, time.Minute); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package comments
+
+import (
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"gorm.io/gorm"
+)
+
+// QuerySchema is the allow-list of fields comments can be filtered and sorted by.
+var QuerySchema = query.Schema{
+	"comment_id": {Column: "comment_id", Type: query.String, Operators: query.Equality},
+	"parent_id":  {Column: "parent_id", Type: query.String, Operators: query.Equality},
+	"author_id":  {Column: "author_id", Type: query.String, Operators: query.Equality},
+	"content":    {Column: "content", Type: query.String, Operators: query.Text, Searchable: true},
+	"created_at": {Column: "created_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+	"updated_at": {Column: "updated_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+}
+
+// Comment is a comment on a post. Replies point at the comment they answer through
+// their ParentId, top level comments have none.
+type Comment struct {
+	gorm.Model
+	CommentId string
+	PostId    string
+	ParentId  *string
+	AuthorId  string
+	Content   string
+}
+
+// CommentRepository is a repository for dealing with the comment object. Creating and
+// deleting comments keeps the comment count of their post up to date.
+type CommentRepository interface {
+	// List lists the comments of a post with pagination.
+	List(postId string, pagination *data.Pagination) ([]Comment, *data.Pagination, error)
+	// GetByUUID gets a comment of a post by uuid. Soft deleted comments aren't found.
+	GetByUUID(postId string, uuid string) (*Comment, error)
+	// Create creates a comment and counts it on its post.
+	Create(comment *Comment) error
+	// Update updates the content of a comment.
+	Update(comment *Comment) error
+	// Delete soft deletes a comment and discounts it from its post. Its replies are
+	// kept and still point at it.
+	Delete(comment *Comment) error
+}
+
+type commentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepository{
+		db: db,
+	}
+}
+
+func (c commentRepository) List(postId string, pagination *data.Pagination) ([]Comment, *data.Pagination, error) {
+	var comments []Comment
+
+	result := c.db.Where("post_id = ?", postId).Scopes(pagination.Paginate()).Find(&comments)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	comments, err := data.KeysetPage(c.db, pagination, comments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pagination details
+	if !pagination.SkipCount {
+		result = c.db.Model(&Comment{}).Where("post_id = ?", postId).Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
+
+	return comments, pagination, nil
+}
+
+func (c commentRepository) GetByUUID(postId string, uuid string) (*Comment, error) {
+	comment := Comment{}
+	result := c.db.Where("post_id = ? AND comment_id = ?", postId, uuid).First(&comment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &comment, nil
+}
+
+func (c commentRepository) Create(comment *Comment) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Create(comment)
+		if result.Error != nil {
+			return result.Error
+		}
+		return count(tx, comment.PostId, "comment_count + 1")
+	})
+}
+
+func (c commentRepository) Update(comment *Comment) error {
+	result := c.db.Model(comment).Select("content").Updates(comment)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (c commentRepository) Delete(comment *Comment) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(comment)
+		if result.Error != nil {
+			return result.Error
+		}
+		// a concurrent delete got there first
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return count(tx, comment.PostId, "GREATEST(comment_count, 1) - 1")
+	})
+}
+
+// count updates the comment count of a post. It doesn't bump the version of the post,
+// comments aren't edits and mustn't fail the preconditions of its editors.
+func count(tx *gorm.DB, postId string, expr string) error {
+	return tx.Table("posts").Where("post_id = ?", postId).
+		UpdateColumn("comment_count", gorm.Expr(expr)).Error
+}
+
+// DeleteByPosts hard deletes the comments of posts, for when the posts themselves are
+// hard deleted. It must run in the transaction deleting the posts.
+func DeleteByPosts(tx *gorm.DB, postIds ...string) error {
+	return tx.Unscoped().Where("post_id IN ?", postIds).Delete(&Comment{}).Error
+}
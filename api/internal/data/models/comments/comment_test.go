@@ -0,0 +1,68 @@
+package comments
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+)
+
+func TestCommentRepositoryCounts(t *testing.T) {
+	tests := []struct {
+		name             string
+		script           func(script *datatest.Script)
+		run              func(repository CommentRepository) error
+		wantTransactions []string
+	}{
+		{
+			name: "creating counts the comment",
+			script: func(script *datatest.Script) {
+				script.Expect("INSERT INTO `comments`").WillReturnResult(1, 1)
+				script.Expect("UPDATE `posts` SET `comment_count`=comment_count + 1 WHERE post_id = ?").WithArgs("p1").WillReturnResult(0, 1)
+			},
+			run: func(repository CommentRepository) error {
+				return repository.Create(&Comment{CommentId: "c1", PostId: "p1", Content: "hi"})
+			},
+			wantTransactions: []string{"commit"},
+		},
+		{
+			name: "deleting discounts the comment",
+			script: func(script *datatest.Script) {
+				script.Expect("UPDATE `comments` SET `deleted_at`=? WHERE `comments`.`id` = ? AND `comments`.`deleted_at` IS NULL").WithArgs(datatest.AnyArg, int64(3)).WillReturnResult(0, 1)
+				script.Expect("UPDATE `posts` SET `comment_count`=GREATEST(comment_count, 1) - 1 WHERE post_id = ?").WithArgs("p1").WillReturnResult(0, 1)
+			},
+			run: func(repository CommentRepository) error {
+				comment := &Comment{CommentId: "c1", PostId: "p1"}
+				comment.ID = 3
+				return repository.Delete(comment)
+			},
+			wantTransactions: []string{"commit"},
+		},
+		{
+			name: "deleting a deleted comment discounts nothing",
+			script: func(script *datatest.Script) {
+				script.Expect("UPDATE `comments` SET `deleted_at`=?").WillReturnResult(0, 0)
+			},
+			run: func(repository CommentRepository) error {
+				comment := &Comment{CommentId: "c1", PostId: "p1"}
+				comment.ID = 3
+				return repository.Delete(comment)
+			},
+			wantTransactions: []string{"commit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			tt.script(script)
+
+			if err := tt.run(NewCommentRepository(db)); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got := script.Transactions(); !reflect.DeepEqual(got, tt.wantTransactions) {
+				t.Errorf("transactions = %v, want %v", got, tt.wantTransactions)
+			}
+		})
+	}
+}
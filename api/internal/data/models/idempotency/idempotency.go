@@ -0,0 +1,139 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxClaimAttempts bounds how many times a claim is retried when the key it collides
+// with expires or is released in the meantime.
+const maxClaimAttempts = 3
+
+var (
+	ErrKeyContended = errors.New("idempotency key is contended")
+	ErrClaimLost    = errors.New("idempotency key was taken over by another request")
+)
+
+// IdempotencyKey records a request made with an Idempotency-Key header, so retries of
+// it get the same response instead of running it again. Keys are scoped to the client
+// that sent them, and the response is only set once the request has completed.
+type IdempotencyKey struct {
+	ID          uint `gorm:"primarykey"`
+	Scope       string
+	Key         string `gorm:"column:idempotency_key"`
+	Fingerprint string
+	// StatusCode is nil while the request is still in flight.
+	StatusCode      *int
+	ResponseHeaders map[string]string `gorm:"serializer:json"`
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	// ExpiresAt ends the lease of a request in flight, and how long the response of a
+	// completed one is kept for.
+	ExpiresAt time.Time
+}
+
+// InFlight reports whether the request the key was claimed for hasn't completed yet.
+func (k *IdempotencyKey) InFlight() bool {
+	return k.StatusCode == nil
+}
+
+// IdempotencyKeyRepository is a repository for dealing with the idempotency key object.
+type IdempotencyKeyRepository interface {
+	// Claim claims a key for a request. If the key is already claimed and hasn't
+	// expired, the existing claim is returned along with false and nothing is stored.
+	// Otherwise the key is stored, replacing any claim that had expired by the time
+	// the key was created, and returned along with true.
+	Claim(key *IdempotencyKey) (*IdempotencyKey, bool, error)
+	// Complete stores the response of the request a key was claimed for, along with
+	// its new expiry. It fails with ErrClaimLost if the claim expired and the key was
+	// taken over in the meantime.
+	Complete(key *IdempotencyKey) error
+	// Release deletes a claim, so the request can be retried with the same key.
+	Release(key *IdempotencyKey) error
+	// PurgeExpired deletes up to limit keys that expired before the given time and
+	// returns how many were deleted, unless ctx is done first.
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyKeyRepository(db *gorm.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{
+		db: db,
+	}
+}
+
+func (i idempotencyKeyRepository) Claim(key *IdempotencyKey) (*IdempotencyKey, bool, error) {
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		// the unique key on scope and key makes concurrent claims race safely, only
+		// one of them inserts a row
+		key.ID = 0
+		result := i.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return key, true, nil
+		}
+
+		existing := IdempotencyKey{}
+		result = i.db.Where("scope = ? AND idempotency_key = ?", key.Scope, key.Key).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// released in the meantime
+			continue
+		}
+		if existing.ExpiresAt.After(key.CreatedAt) {
+			return &existing, false, nil
+		}
+
+		// expired claims are as good as missing
+		result = i.db.Where("id = ? AND expires_at <= ?", existing.ID, key.CreatedAt).Delete(&IdempotencyKey{})
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+	}
+	return nil, false, ErrKeyContended
+}
+
+func (i idempotencyKeyRepository) Complete(key *IdempotencyKey) error {
+	result := i.db.Model(key).Select("status_code", "response_headers", "response_body", "expires_at").Updates(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
+
+func (i idempotencyKeyRepository) Release(key *IdempotencyKey) error {
+	result := i.db.Delete(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (i idempotencyKeyRepository) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	db := i.db.WithContext(ctx)
+	var ids []uint
+	result := db.Model(&IdempotencyKey{}).Where("expires_at < ?", before).Limit(limit).Pluck("id", &ids)
+	if result.Error != nil || len(ids) == 0 {
+		return 0, result.Error
+	}
+
+	result = db.Delete(&IdempotencyKey{}, ids)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+)
+
+const (
+	insertKey = "INSERT INTO `idempotency_keys`"
+	selectKey = "SELECT * FROM `idempotency_keys` WHERE scope = ? AND idempotency_key = ? LIMIT 1"
+	deleteKey = "DELETE FROM `idempotency_keys` WHERE id = ? AND expires_at <= ?"
+)
+
+var keyColumns = []string{"id", "scope", "idempotency_key", "fingerprint", "status_code", "created_at", "expires_at"}
+
+func TestIdempotencyKeyRepositoryClaim(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	existing := func(expiresAt time.Time) []driver.Value {
+		return []driver.Value{int64(3), "user:u1", "k1", "other", int64(201), now.Add(-time.Hour), expiresAt}
+	}
+
+	tests := []struct {
+		name        string
+		script      func(script *datatest.Script)
+		wantClaimed bool
+		wantId      uint
+		wantErr     error
+	}{
+		{
+			name: "free key",
+			script: func(script *datatest.Script) {
+				script.Expect(insertKey+" (`scope`,`idempotency_key`,`fingerprint`,`status_code`,`response_headers`,`response_body`,`created_at`,`expires_at`) VALUES (?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE `id`=`id`").WillReturnResult(7, 1)
+			},
+			wantClaimed: true,
+			wantId:      7,
+		},
+		{
+			name: "claimed key",
+			script: func(script *datatest.Script) {
+				script.Expect(insertKey).WillReturnResult(0, 0)
+				script.Expect(selectKey).WithArgs("user:u1", "k1").WillReturnRows(keyColumns, existing(now.Add(time.Minute)))
+			},
+			wantId: 3,
+		},
+		{
+			name: "expired claim is replaced",
+			script: func(script *datatest.Script) {
+				script.Expect(insertKey).WillReturnResult(0, 0)
+				script.Expect(selectKey).WillReturnRows(keyColumns, existing(now))
+				script.Expect(deleteKey).WithArgs(int64(3), now).WillReturnResult(0, 1)
+				script.Expect(insertKey).WillReturnResult(8, 1)
+			},
+			wantClaimed: true,
+			wantId:      8,
+		},
+		{
+			name: "claim released in the meantime",
+			script: func(script *datatest.Script) {
+				script.Expect(insertKey).WillReturnResult(0, 0)
+				script.Expect(selectKey).WillReturnRows(keyColumns)
+				script.Expect(insertKey).WillReturnResult(9, 1)
+			},
+			wantClaimed: true,
+			wantId:      9,
+		},
+		{
+			name: "contended key",
+			script: func(script *datatest.Script) {
+				for i := 0; i < maxClaimAttempts; i++ {
+					script.Expect(insertKey).WillReturnResult(0, 0)
+					script.Expect(selectKey).WillReturnRows(keyColumns, existing(now.Add(-time.Second)))
+					script.Expect(deleteKey).WillReturnResult(0, 0)
+				}
+			},
+			wantErr: ErrKeyContended,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			tt.script(script)
+
+			got, claimed, err := NewIdempotencyKeyRepository(db).Claim(&IdempotencyKey{
+				Scope:       "user:u1",
+				Key:         "k1",
+				Fingerprint: "sum",
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(time.Minute),
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Claim() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Claim() error = %v", err)
+			}
+			if claimed != tt.wantClaimed || got.ID != tt.wantId {
+				t.Errorf("Claim() = %d, %v, want %d, %v", got.ID, claimed, tt.wantId, tt.wantClaimed)
+			}
+			if !claimed && (got.Fingerprint != "other" || got.InFlight()) {
+				t.Errorf("Claim() = %+v, want the existing claim", got)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyRepositoryComplete(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		want         error
+	}{
+		{name: "completed", rowsAffected: 1},
+		{name: "taken over", rowsAffected: 0, want: ErrClaimLost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			script.Expect("UPDATE `idempotency_keys` SET `status_code`=?,`response_headers`=?,`response_body`=?,`expires_at`=? WHERE `id` = ?").
+				WithArgs(int64(201), datatest.AnyArg, []byte(`{}`), datatest.AnyArg, int64(7)).WillReturnResult(0, tt.rowsAffected)
+
+			status := 201
+			err := NewIdempotencyKeyRepository(db).Complete(&IdempotencyKey{ID: 7, StatusCode: &status, ResponseBody: []byte(`{}`), ExpiresAt: time.Now()})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Complete() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyRepositoryPurgeExpired(t *testing.T) {
+	before := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, script := datatest.New(t)
+	script.Expect("SELECT `id` FROM `idempotency_keys` WHERE expires_at < ? LIMIT 5").WithArgs(before).
+		WillReturnRows([]string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	script.Expect("DELETE FROM `idempotency_keys` WHERE `idempotency_keys`.`id` IN (?,?)").WithArgs(int64(1), int64(2)).WillReturnResult(0, 2)
+
+	purged, err := NewIdempotencyKeyRepository(db).PurgeExpired(context.Background(), before, 5)
+	if err != nil || purged != 2 {
+		t.Errorf("PurgeExpired() = %d, %v, want 2", purged, err)
+	}
+}
@@ -0,0 +1,169 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job kinds
+const (
+	KindPostsImport = "posts_import"
+)
+
+// ErrNotRunning is returned when recording the progress or outcome of a job that isn't
+// running anymore, like a job another replica failed as abandoned.
+var ErrNotRunning = errors.New("job is not running anymore")
+
+// Owner is who submitted a job, which runs on their behalf.
+type Owner struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	KeyId   string   `json:"key_id,omitempty"`
+}
+
+// Job is a piece of work submitted through the API that runs in the background, unlike
+// the recurring jobs of the scheduler, which only record their runs.
+type Job struct {
+	ID     uint `gorm:"primarykey"`
+	JobId  string
+	Kind   string
+	Status string
+	Owner  Owner             `gorm:"serializer:json"`
+	Params map[string]string `gorm:"serializer:json"`
+	// InputKey is the blob store key of the input of the job, if any.
+	InputKey  string
+	Processed int
+	// Result is the JSON encoded outcome of the job once it succeeded.
+	Result     string
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// JobRepository is a repository for dealing with the job object.
+type JobRepository interface {
+	// Create queues a job.
+	Create(job *Job) error
+	// GetByUUID gets a job from the database by uuid.
+	GetByUUID(uuid string) (*Job, error)
+	// ClaimNext marks the oldest queued job as running and returns it, or returns
+	// gorm.ErrRecordNotFound if none is queued. Concurrent claims never get the same job.
+	ClaimNext(now time.Time) (*Job, error)
+	// ListStale lists up to limit running jobs that haven't been updated since before
+	// the given time.
+	ListStale(before time.Time, limit int) ([]Job, error)
+	// Renew records that the running job with the id is still making progress.
+	Renew(id uint, now time.Time) error
+	// Abandon fails a running job with the reason if it still hasn't been updated since
+	// before the given time, and reports whether it did. Jobs renewed in the meantime
+	// are left alone.
+	Abandon(job *Job, before time.Time, reason string) (bool, error)
+	// Update records the progress or outcome of a running job. Jobs that aren't running
+	// anymore are left alone and ErrNotRunning is returned.
+	Update(job *Job) error
+}
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{
+		db: db,
+	}
+}
+
+func (j jobRepository) Create(job *Job) error {
+	result := j.db.Create(job)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (j jobRepository) GetByUUID(uuid string) (*Job, error) {
+	job := Job{}
+	result := j.db.Where("job_id = ?", uuid).Limit(1).Find(&job)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &job, nil
+}
+
+func (j jobRepository) ClaimNext(now time.Time) (*Job, error) {
+	job := Job{}
+	err := j.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", StatusQueued).
+			Order("created_at").Order("id").Limit(1).
+			Find(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		job.Status = StatusRunning
+		job.StartedAt = &now
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (j jobRepository) ListStale(before time.Time, limit int) ([]Job, error) {
+	var stale []Job
+	result := j.db.Where("status = ? AND updated_at < ?", StatusRunning, before).Limit(limit).Find(&stale)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return stale, nil
+}
+
+func (j jobRepository) Update(job *Job) error {
+	// the outcome of a job that was abandoned in the meantime mustn't overwrite the
+	// failure recorded by whoever abandoned it
+	result := j.db.Model(job).Where("status = ?", StatusRunning).Select("*").Omit("created_at").Updates(job)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotRunning
+	}
+	return nil
+}
+
+func (j jobRepository) Renew(id uint, now time.Time) error {
+	result := j.db.Model(&Job{}).Where("id = ? AND status = ?", id, StatusRunning).Update("updated_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (j jobRepository) Abandon(job *Job, before time.Time, reason string) (bool, error) {
+	now := time.Now()
+	result := j.db.Model(&Job{}).
+		Where("id = ? AND status = ? AND updated_at < ?", job.ID, StatusRunning, before).
+		Updates(map[string]interface{}{"status": StatusFailed, "error": reason, "finished_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	job.Status, job.Error, job.FinishedAt = StatusFailed, reason, &now
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job and job run statuses. Only jobs are ever queued, job runs start running.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// JobRun records a single run of a background job.
+type JobRun struct {
+	ID         uint `gorm:"primarykey"`
+	Job        string
+	Status     string
+	Attempts   int
+	Processed  int
+	Error      string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// JobRunRepository is a repository for dealing with the job run object.
+type JobRunRepository interface {
+	// Create records the start of a job run.
+	Create(run *JobRun) error
+	// Update records the outcome of a job run.
+	Update(run *JobRun) error
+	// PurgeStarted deletes up to limit job runs started before the given time and
+	// returns how many were deleted, unless ctx is done first.
+	PurgeStarted(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type jobRunRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRunRepository(db *gorm.DB) JobRunRepository {
+	return &jobRunRepository{
+		db: db,
+	}
+}
+
+func (j jobRunRepository) Create(run *JobRun) error {
+	result := j.db.Create(run)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (j jobRunRepository) Update(run *JobRun) error {
+	result := j.db.Save(run)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (j jobRunRepository) PurgeStarted(ctx context.Context, before time.Time, limit int) (int, error) {
+	db := j.db.WithContext(ctx)
+	var ids []uint
+	result := db.Model(&JobRun{}).Where("started_at < ?", before).Limit(limit).Pluck("id", &ids)
+	if result.Error != nil || len(ids) == 0 {
+		return 0, result.Error
+	}
+
+	result = db.Delete(&JobRun{}, ids)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+	"gorm.io/gorm"
+)
+
+var jobColumns = []string{"id", "job_id", "kind", "status", "owner", "params", "created_at", "updated_at"}
+
+// anyArgs matches n arguments of any value, followed by the given ones.
+func anyArgs(n int, args ...interface{}) []interface{} {
+	matched := make([]interface{}, 0, n+len(args))
+	for i := 0; i < n; i++ {
+		matched = append(matched, datatest.AnyArg)
+	}
+	return append(matched, args...)
+}
+
+func TestJobRepositoryClaimNext(t *testing.T) {
+	const selectQueued = "SELECT * FROM `jobs` WHERE status = ? ORDER BY created_at,id LIMIT 1 FOR UPDATE SKIP LOCKED"
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("claims the oldest queued job", func(t *testing.T) {
+		db, script := datatest.New(t)
+		// jobs claimed by other replicas are skipped rather than waited for
+		script.Expect(selectQueued).WithArgs(StatusQueued).
+			WillReturnRows(jobColumns, []driver.Value{int64(3), "j1", KindPostsImport, StatusQueued, `{"subject":"u1"}`, `{}`, now, now})
+		script.Expect("UPDATE `jobs` SET").WillReturnResult(0, 1)
+
+		job, err := NewJobRepository(db).ClaimNext(now)
+		if err != nil {
+			t.Fatalf("ClaimNext() error = %v", err)
+		}
+		if job.JobId != "j1" || job.Status != StatusRunning || job.StartedAt == nil || !job.StartedAt.Equal(now) {
+			t.Errorf("ClaimNext() = %+v, want j1 running since now", job)
+		}
+		if job.Owner.Subject != "u1" {
+			t.Errorf("ClaimNext() owner = %+v, want u1", job.Owner)
+		}
+		if got := script.Transactions(); !reflect.DeepEqual(got, []string{"commit"}) {
+			t.Errorf("transactions = %v, want the claim committed", got)
+		}
+	})
+
+	t.Run("nothing queued", func(t *testing.T) {
+		db, script := datatest.New(t)
+		script.Expect(selectQueued).WillReturnRows(jobColumns)
+
+		if _, err := NewJobRepository(db).ClaimNext(now); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("ClaimNext() error = %v, want %v", err, gorm.ErrRecordNotFound)
+		}
+	})
+}
+
+func TestJobRepositoryRenew(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, script := datatest.New(t)
+	// jobs that were abandoned in the meantime stay failed
+	script.Expect("UPDATE `jobs` SET `updated_at`=? WHERE id = ? AND status = ?").WithArgs(now, int64(3), StatusRunning).WillReturnResult(0, 1)
+
+	if err := NewJobRepository(db).Renew(3, now); err != nil {
+		t.Errorf("Renew() error = %v", err)
+	}
+}
+
+func TestJobRepositoryUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		want         error
+	}{
+		{name: "running job", rowsAffected: 1},
+		{name: "job abandoned in the meantime", rowsAffected: 0, want: ErrNotRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			// the outcome of a job that was abandoned in the meantime is dropped
+			script.Expect("UPDATE `jobs` SET `job_id`=?,`kind`=?,`status`=?,`owner`=?,`params`=?,`input_key`=?,`processed`=?,`result`=?,`error`=?,`updated_at`=?,`started_at`=?,`finished_at`=? WHERE status = ? AND `id` = ?").
+				WithArgs(anyArgs(12, StatusRunning, int64(3))...).WillReturnResult(0, tt.rowsAffected)
+
+			finishedAt := time.Now()
+			job := &Job{ID: 3, JobId: "j1", Status: StatusSucceeded, FinishedAt: &finishedAt}
+			if err := NewJobRepository(db).Update(job); !errors.Is(err, tt.want) {
+				t.Errorf("Update() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobRepositoryAbandon(t *testing.T) {
+	before := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		wantAbandoned bool
+	}{
+		{name: "stale job", rowsAffected: 1, wantAbandoned: true},
+		{name: "job renewed in the meantime", rowsAffected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			script.Expect("UPDATE `jobs` SET `error`=?,`finished_at`=?,`status`=?,`updated_at`=? WHERE id = ? AND status = ? AND updated_at < ?").
+				WithArgs("interrupted", datatest.AnyArg, StatusFailed, datatest.AnyArg, int64(3), StatusRunning, before).WillReturnResult(0, tt.rowsAffected)
+
+			job := &Job{ID: 3, Status: StatusRunning}
+			abandoned, err := NewJobRepository(db).Abandon(job, before, "interrupted")
+			if err != nil || abandoned != tt.wantAbandoned {
+				t.Fatalf("Abandon() = %v, %v, want %v", abandoned, err, tt.wantAbandoned)
+			}
+			if failed := job.Status == StatusFailed; failed != tt.wantAbandoned {
+				t.Errorf("job status = %s after Abandon() = %v", job.Status, abandoned)
+			}
+		})
+	}
+}
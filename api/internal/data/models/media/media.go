@@ -0,0 +1,80 @@
+package media
+
+import (
+	"gorm.io/gorm"
+)
+
+// Media is the metadata of an uploaded file, whose content is kept in a blob store
+// under StorageKey. Media are immutable once uploaded.
+type Media struct {
+	gorm.Model
+	MediaId     string
+	OwnerId     string
+	Filename    string
+	ContentType string
+	Size        int64
+	// Checksum is the hex encoded SHA-256 of the content.
+	Checksum   string
+	StorageKey string
+}
+
+// MediaRepository is a repository for dealing with the media object.
+type MediaRepository interface {
+	// GetByUUID gets media from the database by uuid.
+	GetByUUID(uuid string) (*Media, error)
+	// FindMissing returns the uuids, among the ones given, that no media go by.
+	FindMissing(uuids []string) ([]string, error)
+	// Create creates media in the database.
+	Create(media *Media) error
+}
+
+type mediaRepository struct {
+	db *gorm.DB
+}
+
+func NewMediaRepository(db *gorm.DB) MediaRepository {
+	return &mediaRepository{
+		db: db,
+	}
+}
+
+func (m mediaRepository) GetByUUID(uuid string) (*Media, error) {
+	media := Media{}
+	result := m.db.Where("media_id = ?", uuid).First(&media)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &media, nil
+}
+
+func (m mediaRepository) FindMissing(uuids []string) ([]string, error) {
+	if len(uuids) == 0 {
+		return nil, nil
+	}
+
+	var found []string
+	result := m.db.Model(&Media{}).Where("media_id IN ?", uuids).Pluck("media_id", &found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	exists := make(map[string]bool, len(found))
+	for _, uuid := range found {
+		exists[uuid] = true
+	}
+	var missing []string
+	for _, uuid := range uuids {
+		if !exists[uuid] {
+			missing = append(missing, uuid)
+		}
+	}
+	return missing, nil
+}
+
+func (m mediaRepository) Create(media *Media) error {
+	result := m.db.Create(media)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
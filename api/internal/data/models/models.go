@@ -1,7 +1,13 @@
 package models
 
 import (
+	"github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/comments"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/idempotency"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/media"
 	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/users"
 	"go.uber.org/fx"
 )
 
@@ -9,6 +15,17 @@ func ProvideModels() fx.Option {
 	return fx.Options(
 		fx.Provide(
 			posts.NewPostRepository,
+			posts.NewPostRevisionRepository,
+			posts.NewTagRepository,
+			posts.NewPostReactionRepository,
+			media.NewMediaRepository,
+			idempotency.NewIdempotencyKeyRepository,
+			comments.NewCommentRepository,
+			apikeys.NewAPIKeyRepository,
+			jobs.NewJobRunRepository,
+			jobs.NewJobRepository,
+			users.NewUserRepository,
+			users.NewRefreshTokenRepository,
 		),
 	)
 }
@@ -0,0 +1,71 @@
+package posts
+
+import (
+	"gorm.io/gorm"
+)
+
+// PostMedia attaches uploaded media to a post, ordered by position.
+type PostMedia struct {
+	PostId   string `gorm:"primaryKey"`
+	MediaId  string `gorm:"primaryKey"`
+	Position uint
+}
+
+// TableName keeps gorm from pluralizing media.
+func (PostMedia) TableName() string {
+	return "post_media"
+}
+
+// saveMedia replaces the media attached to a post with post.Media. A nil slice leaves
+// them untouched, an empty one detaches them all. It must run in a transaction.
+func saveMedia(tx *gorm.DB, post *Post) error {
+	if post.Media == nil {
+		return nil
+	}
+
+	if err := deleteMedia(tx, post.PostId); err != nil {
+		return err
+	}
+	if len(post.Media) == 0 {
+		return nil
+	}
+
+	links := make([]PostMedia, 0, len(post.Media))
+	for i, mediaId := range post.Media {
+		links = append(links, PostMedia{PostId: post.PostId, MediaId: mediaId, Position: uint(i)})
+	}
+	return tx.Create(&links).Error
+}
+
+// loadMedia fills in the media attached to posts, in position order.
+func loadMedia(db *gorm.DB, posts ...*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byPostId := map[string]*Post{}
+	postIds := make([]string, 0, len(posts))
+	for _, post := range posts {
+		post.Media = []string{}
+		byPostId[post.PostId] = post
+		postIds = append(postIds, post.PostId)
+	}
+
+	var links []PostMedia
+	result := db.Where("post_id IN ?", postIds).Order("post_id, position").Find(&links)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, link := range links {
+		if post, ok := byPostId[link.PostId]; ok {
+			post.Media = append(post.Media, link.MediaId)
+		}
+	}
+	return nil
+}
+
+// deleteMedia detaches all media from posts. The media themselves are kept.
+func deleteMedia(tx *gorm.DB, postIds ...string) error {
+	return tx.Where("post_id IN ?", postIds).Delete(&PostMedia{}).Error
+}
@@ -1,39 +1,168 @@
 package posts
 
 import (
+	"context"
 	"errors"
-	"math"
+	"fmt"
+	"time"
 
 	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/comments"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrVersionConflict = errors.New("post version conflict")
+	ErrSlugTaken       = errors.New("slug is taken by another post")
+)
+
+// upsertColumns are the columns upserts replace on posts whose post id is taken. Like
+// updates, replacements keep the author, status and creation time of the post.
+var upsertColumns = []string{
+	"updated_at", "title", "slug", "content", "format", "publish_at", "content_html", "excerpt", "reading_time",
+}
+
+// Post statuses. Only published posts are visible to readers.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// QuerySchema is the allow-list of fields posts can be filtered, sorted and searched by.
+var QuerySchema = query.Schema{
+	"post_id":      {Column: "post_id", Type: query.String, Operators: query.Equality},
+	"author_id":    {Column: "author_id", Type: query.String, Operators: query.Equality},
+	"title":        {Column: "title", Type: query.String, Operators: query.Text, Sortable: true, Searchable: true},
+	"slug":         {Column: "slug", Type: query.String, Operators: query.Equality},
+	"status":       {Column: "status", Type: query.String, Operators: query.Equality},
+	"format":       {Column: "format", Type: query.String, Operators: query.Equality},
+	"reading_time": {Column: "reading_time", Type: query.Integer, Operators: query.Ordered, Sortable: true},
+	"published_at": {Column: "published_at", Type: query.Time, Operators: query.Ordered},
+	"content":      {Column: "content", Type: query.String, Operators: query.Text, Sortable: true, Searchable: true},
+	"created_at":   {Column: "created_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+	"updated_at":   {Column: "updated_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+	"publish_at":   {Column: "publish_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+	"tag":          {Type: query.String, Operators: query.Equality, Condition: tagCondition},
+}
+
 type Post struct {
 	gorm.Model
-	PostId  string
-	Content string
+	PostId      string
+	AuthorId    string
+	Title       string
+	Slug        string
+	Content     string
+	Format      string
+	Status      string
+	PublishedAt *time.Time
+	PublishAt   *time.Time
+	Version     uint
+	// ContentHTML, Excerpt and ReadingTime are rendered from the content on every
+	// write, so reads never pay for rendering.
+	ContentHTML string
+	Excerpt     string
+	ReadingTime uint
+	// ReactionCount is the count of the reaction kind posts are sorted by, only set
+	// when listing posts sorted by one.
+	ReactionCount uint `gorm:"->"`
+	// CommentCount is maintained by the CommentRepository, saving a post never
+	// overwrites it.
+	CommentCount uint `gorm:"->"`
+	// Tags are the normalized names of the tags the post is filed under, stored in
+	// the post_tags join table.
+	Tags []string `gorm:"-"`
+	// Reactions are the counts of each kind of reaction to the post, stored in the
+	// post_reaction_counts table.
+	Reactions map[string]uint `gorm:"-"`
+	// Media are the ids of the media attached to the post, in order, stored in the
+	// post_media join table.
+	Media []string `gorm:"-"`
+}
+
+type SearchMode string
+
+const (
+	SearchModeNatural SearchMode = "natural"
+	SearchModeBoolean SearchMode = "boolean"
+)
+
+// SearchResult is a post matched by a full-text search along with its relevance.
+type SearchResult struct {
+	Post
+	Score float64
 }
 
 // postRepository is a repository for dealing with the post object.
 type PostRepository interface {
-	// List lists posts from the database with pagination.
-	List(limit, page int) ([]Post, *data.Pagination, error)
-	// GetByUUID gets a post from the database by uuid.
+	// List lists posts from the database with offset or keyset pagination.
+	List(pagination *data.Pagination) ([]Post, *data.Pagination, error)
+	// Stream calls fn with every post matching a query, in its sort order. Posts are
+	// read through a database cursor and handed over batchSize at a time, so memory use
+	// doesn't grow with the number of posts; the slice is reused between calls. It
+	// stops at the first error fn returns.
+	Stream(q *query.Query, batchSize int, fn func(posts []Post) error) error
+	// Search runs a full-text search on the post content, ordered by relevance.
+	Search(q string, mode SearchMode, pagination *data.Pagination) ([]SearchResult, *data.Pagination, error)
+	// GetByUUID gets a post from the database by uuid. Soft deleted posts aren't found.
 	GetByUUID(uuid string) (*Post, error)
+	// GetBySlug gets a post from the database by any slug it went by. The slug of the
+	// post returned is the current one, which differs from the one asked for if the
+	// post has been renamed since. Soft deleted posts aren't found.
+	GetBySlug(slug string) (*Post, error)
+	// ListTrashed lists soft deleted posts from the database with pagination.
+	ListTrashed(pagination *data.Pagination) ([]Post, *data.Pagination, error)
+	// GetTrashedByUUID gets a soft deleted post from the database by uuid.
+	GetTrashedByUUID(uuid string) (*Post, error)
+	// GetManyByUUID gets the posts going by any of the uuids from the database, soft
+	// deleted ones included, without their tags, reactions and media.
+	GetManyByUUID(uuids []string) ([]Post, error)
 	// Get gets a post from the database by id.
 	Get(id uint) (*Post, error)
-	// Create creates a post in the database.
+	// Create creates a post in the database, along with its slug, tags, media and first
+	// revision.
 	Create(post *Post) error
-	// Upsert creates or updates a post if the post already exists.
-	Upsert(post *Post) error
+	// CreateMany creates posts like Create in a single transaction, inserting them
+	// batchSize at a time.
+	CreateMany(posts []*Post, batchSize int) error
+	// Upsert creates a post at the post id it comes with, like Create, in a single
+	// INSERT ... ON DUPLICATE KEY UPDATE, and reports whether it did. If a post, trashed
+	// or not, already has the post id, it's replaced instead, keeping its author, and
+	// false is returned. canReplace is called with the replaced post, as it is after
+	// the replacement, and any error it returns undoes the replacement and is returned.
+	// Replacements that keep the content and format add no revision. ErrSlugTaken is
+	// returned when the slug collides with another post.
+	Upsert(post *Post, canReplace func(existing *Post) error) (bool, error)
+	// Transaction runs fn with a repository whose methods all run in a single
+	// transaction, committed if fn returns nil and rolled back otherwise.
+	Transaction(fn func(repository PostRepository) error) error
 	// Update updates a post config in the database. Should be paired with Get
 	// to retrieve the existing object, then the object modified and passed to this
-	// method.
+	// method. The update only succeeds if the stored version still matches the
+	// version of the object, otherwise ErrVersionConflict is returned.
 	Update(post *Post) error
-	// SoftDelete soft deletes a post record from the database.
+	// Revise updates a post like Update and appends its new content as a revision
+	// edited by editorId, in a single transaction.
+	Revise(post *Post, editorId string) (*PostRevision, error)
+	// PublishDue locks up to limit unpublished posts whose publish_at is due, skipping
+	// posts locked by other schedulers, applies publish to each and saves them, all in
+	// a single transaction. The transaction is rolled back if ctx is done first.
+	PublishDue(ctx context.Context, now time.Time, limit int, publish func(post *Post) error) ([]Post, error)
+	// SoftDelete soft deletes a post record from the database if its version matches.
 	SoftDelete(post *Post) error
-	// HardDelete hard deletes a post record from the database.
+	// Restore restores a soft deleted post if its version matches.
+	Restore(post *Post) error
+	// HardDelete hard deletes a post record, its revisions, tag links, slugs, comments,
+	// reactions and media links from the database if its version matches.
 	HardDelete(post *Post) error
+	// PurgeTrashed hard deletes up to limit posts soft deleted before the given time,
+	// along with their revisions, tag links, slugs, comments, reactions and media links.
+	// It returns how many posts were deleted. The transaction is rolled back if ctx is
+	// done first.
+	PurgeTrashed(ctx context.Context, before time.Time, limit int) (int, error)
 }
 
 type postRepository struct {
@@ -46,84 +175,554 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 	}
 }
 
-func (p postRepository) List(limit, page int) ([]Post, *data.Pagination, error) {
+func (p postRepository) List(pagination *data.Pagination) ([]Post, *data.Pagination, error) {
 	var posts []Post
 
-	// pagination object
-	pagination := &data.Pagination{
-		Limit: limit,
-		Page:  page,
+	source, err := withReactionCount(p.db, pagination.GetQuery())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := source.Scopes(pagination.Paginate()).Find(&posts)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	posts, err = data.KeysetPage(p.db, pagination, posts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := loadDetails(p.db, postRefs(posts)...); err != nil {
+		return nil, nil, err
 	}
-	p.db.Scopes(pagination.Paginate()).Find(&posts)
 
 	// pagination details
-	p.db.Model(&Post{}).Count(&pagination.TotalRows)
-	pagination.TotalPages = int(math.Ceil(float64(pagination.TotalRows) / float64(pagination.GetLimit())))
+	if !pagination.SkipCount {
+		result = p.db.Model(&Post{}).Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
 
 	return posts, pagination, nil
 }
 
+func (p postRepository) Stream(q *query.Query, batchSize int, fn func(posts []Post) error) error {
+	source, err := withReactionCount(p.db, q)
+	if err != nil {
+		return err
+	}
+
+	rows, err := source.Model(&Post{}).Scopes(data.Sorted(q)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]Post, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := loadDetails(p.db, postRefs(batch)...); err != nil {
+			return err
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		batch = append(batch, Post{})
+		if err := p.db.ScanRows(rows, &batch[len(batch)-1]); err != nil {
+			return err
+		}
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func (p postRepository) Search(q string, mode SearchMode, pagination *data.Pagination) ([]SearchResult, *data.Pagination, error) {
+	var results []SearchResult
+
+	match := "MATCH(title, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	if mode == SearchModeBoolean {
+		match = "MATCH(title, content) AGAINST (? IN BOOLEAN MODE)"
+	}
+
+	result := p.db.Model(&Post{}).
+		Select(fmt.Sprintf("posts.*, %s AS score", match), q).
+		Where(match, q).
+		Scopes(pagination.Where()).
+		Order("score DESC").Order("id ASC").
+		Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).
+		Find(&results)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	refs := make([]*Post, 0, len(results))
+	for i := range results {
+		refs = append(refs, &results[i].Post)
+	}
+	if err := loadDetails(p.db, refs...); err != nil {
+		return nil, nil, err
+	}
+
+	// pagination details
+	if !pagination.SkipCount {
+		result = p.db.Model(&Post{}).Where(match, q).Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
+
+	return results, pagination, nil
+}
+
 func (p postRepository) GetByUUID(uuid string) (*Post, error) {
 	post := Post{}
-	result := p.db.Unscoped().Where("post_id = ?", uuid).Find(&post)
+	result := p.db.Where("post_id = ?", uuid).Find(&post)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	if result.RowsAffected == 0 {
 		return nil, gorm.ErrRecordNotFound
 	}
+	if err := loadDetails(p.db, &post); err != nil {
+		return nil, err
+	}
 	return &post, nil
 }
 
+func (p postRepository) GetBySlug(slug string) (*Post, error) {
+	post := Post{}
+	result := p.db.Select("`posts`.*").
+		Joins("JOIN `post_slugs` ON `post_slugs`.`post_id` = `posts`.`post_id`").
+		Where("`post_slugs`.`slug` = ?", slug).
+		Find(&post)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := loadDetails(p.db, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (p postRepository) ListTrashed(pagination *data.Pagination) ([]Post, *data.Pagination, error) {
+	var posts []Post
+
+	source, err := withReactionCount(p.db.Unscoped(), pagination.GetQuery())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := source.Where("deleted_at IS NOT NULL").Scopes(pagination.Paginate()).Find(&posts)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	posts, err = data.KeysetPage(p.db, pagination, posts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := loadDetails(p.db, postRefs(posts)...); err != nil {
+		return nil, nil, err
+	}
+
+	// pagination details
+	if !pagination.SkipCount {
+		result = p.db.Unscoped().Model(&Post{}).Where("deleted_at IS NOT NULL").Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
+
+	return posts, pagination, nil
+}
+
+func (p postRepository) GetTrashedByUUID(uuid string) (*Post, error) {
+	post := Post{}
+	result := p.db.Unscoped().Where("post_id = ? AND deleted_at IS NOT NULL", uuid).Find(&post)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := loadDetails(p.db, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (p postRepository) GetManyByUUID(uuids []string) ([]Post, error) {
+	var found []Post
+	if len(uuids) == 0 {
+		return found, nil
+	}
+	result := p.db.Unscoped().Where("post_id IN ?", uuids).Find(&found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return found, nil
+}
+
 func (p postRepository) Get(id uint) (*Post, error) {
 	post := Post{}
 	result := p.db.First(&post, id)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	if err := loadDetails(p.db, &post); err != nil {
+		return nil, err
+	}
 	return &post, nil
 }
 
 func (p postRepository) Create(post *Post) error {
-	result := p.db.Create(post)
-	if result.Error != nil {
-		return result.Error
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		if err := assignSlug(tx, post); err != nil {
+			return err
+		}
+		result := tx.Create(post)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := saveTags(tx, post); err != nil {
+			return err
+		}
+		if err := saveMedia(tx, post); err != nil {
+			return err
+		}
+		_, err := appendRevision(tx, post, post.AuthorId)
+		return err
+	})
+}
+
+func (p postRepository) CreateMany(posts []*Post, batchSize int) error {
+	if len(posts) == 0 {
+		return nil
 	}
-	return nil
+
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		for _, post := range posts {
+			if err := assignSlug(tx, post); err != nil {
+				return err
+			}
+		}
+		result := tx.CreateInBatches(posts, batchSize)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		revisions := make([]PostRevision, 0, len(posts))
+		for _, post := range posts {
+			if err := saveTags(tx, post); err != nil {
+				return err
+			}
+			if err := saveMedia(tx, post); err != nil {
+				return err
+			}
+			revisions = append(revisions, PostRevision{
+				PostId:   post.PostId,
+				Revision: 1,
+				EditorId: post.AuthorId,
+				Content:  post.Content,
+				Format:   post.Format,
+			})
+		}
+		return tx.CreateInBatches(revisions, batchSize).Error
+	})
 }
 
-func (p postRepository) Upsert(upsertPost *Post) error {
-	post, err := p.Get(upsertPost.ID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return p.Create(upsertPost)
+func (p postRepository) Upsert(post *Post, canReplace func(existing *Post) error) (bool, error) {
+	created := false
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := assignSlug(tx, post); err != nil {
+			return err
 		}
+
+		// the unique key on post_id makes concurrent upserts race safely, the row is
+		// inserted by one and replaced by the others. LAST_INSERT_ID(id) makes MySQL
+		// report the id of the replaced row as well, which gorm sets on the post.
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "post_id"}},
+			DoUpdates: append(clause.AssignmentColumns(upsertColumns),
+				clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("version + 1")},
+				clause.Assignment{Column: clause.Column{Name: "id"}, Value: gorm.Expr("LAST_INSERT_ID(id)")}),
+		}).Create(post)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		editorId := post.AuthorId
+		// MySQL counts inserted rows once and updated rows twice
+		created = result.RowsAffected == 1
+		if !created {
+			existing := Post{}
+			result = tx.Unscoped().Where("post_id = ?", post.PostId).Limit(1).Find(&existing)
+			if result.Error != nil {
+				return result.Error
+			}
+			// MySQL updates on conflicts with any unique key, the update may have landed
+			// on the post whose unique slug collided rather than on the one at the post
+			// id. Returning an error rolls it back.
+			if result.RowsAffected == 0 || existing.ID != post.ID {
+				return ErrSlugTaken
+			}
+			if err := canReplace(&existing); err != nil {
+				return err
+			}
+			post.ID, post.AuthorId, post.Version = existing.ID, existing.AuthorId, existing.Version
+			post.Status, post.PublishedAt = existing.Status, existing.PublishedAt
+			post.CreatedAt, post.UpdatedAt = existing.CreatedAt, existing.UpdatedAt
+		}
+
+		if err := saveTags(tx, post); err != nil {
+			return err
+		}
+		if err := saveMedia(tx, post); err != nil {
+			return err
+		}
+
+		// like updates, replacements only make a revision when the content changes
+		if !created {
+			latest, err := latestRevision(tx, post.PostId)
+			if err != nil {
+				return err
+			}
+			if latest != nil && latest.Content == post.Content && latest.Format == post.Format {
+				return nil
+			}
+		}
+		_, err := appendRevision(tx, post, editorId)
 		return err
+	})
+	if err != nil {
+		post.Slug = ""
+		return false, err
 	}
-	post.Content = upsertPost.Content
-	return p.Update(upsertPost)
+	return created, nil
+}
+
+func (p postRepository) Transaction(fn func(repository PostRepository) error) error {
+	// transactions opened by the repository methods become savepoints of this one
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		return fn(postRepository{db: tx})
+	})
 }
 
 func (p postRepository) Update(post *Post) error {
-	result := p.db.Save(post)
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		return update(tx, post)
+	})
+}
+
+func (p postRepository) Revise(post *Post, editorId string) (*PostRevision, error) {
+	var revision *PostRevision
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := update(tx, post); err != nil {
+			return err
+		}
+		var err error
+		revision, err = appendRevision(tx, post, editorId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// update saves the post, its tags and media with a compare-and-swap on the version,
+// so concurrent writers can't clobber each other. Posts without a slug get a new
+// one. It must run in a transaction.
+func update(db *gorm.DB, post *Post) error {
+	if post.Slug == "" {
+		if err := assignSlug(db, post); err != nil {
+			return err
+		}
+	}
+
+	version := post.Version
+	post.Version++
+	result := db.Model(post).Where("version = ?", version).Select("*").Omit("created_at").Updates(post)
 	if result.Error != nil {
+		post.Version = version
 		return result.Error
 	}
-	return nil
+	if result.RowsAffected == 0 {
+		post.Version = version
+		return ErrVersionConflict
+	}
+	if err := saveTags(db, post); err != nil {
+		return err
+	}
+	return saveMedia(db, post)
+}
+
+func (p postRepository) PublishDue(ctx context.Context, now time.Time, limit int, publish func(post *Post) error) ([]Post, error) {
+	var due []Post
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND publish_at <= ?", []string{StatusDraft, StatusInReview}, now).
+			Order("publish_at").Limit(limit).
+			Find(&due)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for i := range due {
+			if err := publish(&due[i]); err != nil {
+				return err
+			}
+			due[i].Version++
+			result = tx.Model(&due[i]).Select("*").Omit("created_at").Updates(&due[i])
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
 }
 
 func (p postRepository) SoftDelete(post *Post) error {
-	result := p.db.Delete(post)
+	result := p.db.Where("version = ?", post.Version).Delete(post)
 	if result.Error != nil {
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
 	return nil
 }
 
-func (p postRepository) HardDelete(post *Post) error {
-	result := p.db.Unscoped().Delete(post)
+func (p postRepository) Restore(post *Post) error {
+	version := post.Version
+	post.Version++
+	result := p.db.Unscoped().Model(post).Where("version = ?", version).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": post.Version})
 	if result.Error != nil {
+		post.Version = version
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		post.Version = version
+		return ErrVersionConflict
+	}
+	post.DeletedAt = gorm.DeletedAt{}
 	return nil
 }
+
+func (p postRepository) HardDelete(post *Post) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("version = ?", post.Version).Delete(post)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if err := deleteTags(tx, post.PostId); err != nil {
+			return err
+		}
+		if err := deleteSlugs(tx, post.PostId); err != nil {
+			return err
+		}
+		if err := comments.DeleteByPosts(tx, post.PostId); err != nil {
+			return err
+		}
+		if err := deleteReactions(tx, post.PostId); err != nil {
+			return err
+		}
+		if err := deleteMedia(tx, post.PostId); err != nil {
+			return err
+		}
+		return tx.Where("post_id = ?", post.PostId).Delete(&PostRevision{}).Error
+	})
+}
+
+func (p postRepository) PurgeTrashed(ctx context.Context, before time.Time, limit int) (int, error) {
+	var purged []Post
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Select("id", "post_id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Limit(limit).
+			Find(&purged)
+		if result.Error != nil || len(purged) == 0 {
+			return result.Error
+		}
+
+		ids := make([]uint, 0, len(purged))
+		postIds := make([]string, 0, len(purged))
+		for _, post := range purged {
+			ids = append(ids, post.ID)
+			postIds = append(postIds, post.PostId)
+		}
+		if err := tx.Where("post_id IN ?", postIds).Delete(&PostRevision{}).Error; err != nil {
+			return err
+		}
+		if err := deleteTags(tx, postIds...); err != nil {
+			return err
+		}
+		if err := deleteSlugs(tx, postIds...); err != nil {
+			return err
+		}
+		if err := comments.DeleteByPosts(tx, postIds...); err != nil {
+			return err
+		}
+		if err := deleteReactions(tx, postIds...); err != nil {
+			return err
+		}
+		if err := deleteMedia(tx, postIds...); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Post{}, ids).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(purged), nil
+}
+
+// loadDetails fills in what posts keep outside of the posts table, their tags,
+// reaction counts and media.
+func loadDetails(db *gorm.DB, posts ...*Post) error {
+	if err := loadTags(db, posts...); err != nil {
+		return err
+	}
+	if err := loadReactions(db, posts...); err != nil {
+		return err
+	}
+	return loadMedia(db, posts...)
+}
+
+// postRefs returns pointers to the posts of a slice, to fill them in place.
+func postRefs(posts []Post) []*Post {
+	refs := make([]*Post, 0, len(posts))
+	for i := range posts {
+		refs = append(refs, &posts[i])
+	}
+	return refs
+}
@@ -0,0 +1,224 @@
+package posts
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+)
+
+func TestPostRepositoryPublishDue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "post_id", "status", "version", "publish_at"}
+	errPublish := errors.New("can't publish")
+
+	tests := []struct {
+		name             string
+		publishErr       error
+		wantPublished    []string
+		wantTransactions []string
+	}{
+		{name: "due posts are published", wantPublished: []string{"p1", "p2"}, wantTransactions: []string{"commit"}},
+		{name: "failing to publish one rolls every one back", publishErr: errPublish, wantTransactions: []string{"rollback"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			// posts locked by another scheduler are skipped rather than waited for
+			script.Expect("SELECT * FROM `posts` WHERE (status IN (?,?) AND publish_at <= ?) AND `posts`.`deleted_at` IS NULL ORDER BY publish_at LIMIT 10 FOR UPDATE SKIP LOCKED").
+				WithArgs(StatusDraft, StatusInReview, now).
+				WillReturnRows(columns, []driver.Value{int64(1), "p1", StatusDraft, int64(2), now}, []driver.Value{int64(2), "p2", StatusInReview, int64(5), now})
+			if tt.publishErr == nil {
+				script.Expect("UPDATE `posts` SET").WillReturnResult(0, 1)
+				script.Expect("UPDATE `posts` SET").WillReturnResult(0, 1)
+			}
+
+			published, err := NewPostRepository(db).PublishDue(context.Background(), now, 10, func(post *Post) error {
+				if tt.publishErr != nil {
+					return tt.publishErr
+				}
+				post.Status = StatusPublished
+				return nil
+			})
+			if !errors.Is(err, tt.publishErr) {
+				t.Fatalf("PublishDue() error = %v, want %v", err, tt.publishErr)
+			}
+			var got []string
+			for _, post := range published {
+				got = append(got, post.PostId)
+				if post.Status != StatusPublished {
+					t.Errorf("post %s wasn't published", post.PostId)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantPublished) {
+				t.Errorf("PublishDue() = %v, want %v", got, tt.wantPublished)
+			}
+			if len(published) > 0 && (published[0].Version != 3 || published[1].Version != 6) {
+				t.Errorf("published versions = %d, %d, want them bumped", published[0].Version, published[1].Version)
+			}
+			if got := script.Transactions(); !reflect.DeepEqual(got, tt.wantTransactions) {
+				t.Errorf("transactions = %v, want %v", got, tt.wantTransactions)
+			}
+		})
+	}
+}
+
+func TestPostRepositoryUpsert(t *testing.T) {
+	const (
+		selectSlug     = "SELECT * FROM `post_slugs` WHERE slug = ? LIMIT 1"
+		insertSlug     = "INSERT INTO `post_slugs`"
+		upsertPost     = "ON DUPLICATE KEY UPDATE `updated_at`=VALUES(`updated_at`),`title`=VALUES(`title`),`slug`=VALUES(`slug`),`content`=VALUES(`content`),`format`=VALUES(`format`),`publish_at`=VALUES(`publish_at`),`content_html`=VALUES(`content_html`),`excerpt`=VALUES(`excerpt`),`reading_time`=VALUES(`reading_time`),`version`=version + 1,`id`=LAST_INSERT_ID(id)"
+		selectExisting = "SELECT * FROM `posts` WHERE post_id = ? LIMIT 1"
+		selectLatest   = "SELECT * FROM `post_revisions` WHERE post_id = ? ORDER BY revision DESC LIMIT 1"
+		selectLast     = "SELECT COALESCE(MAX(revision), 0) FROM `post_revisions` WHERE post_id = ?"
+		insertRevision = "INSERT INTO `post_revisions`"
+	)
+	existing := []driver.Value{int64(5), "p1", "u1", int64(4), StatusPublished}
+	existingColumns := []string{"id", "post_id", "author_id", "version", "status"}
+	revisionColumns := []string{"id", "post_id", "revision", "content", "format"}
+	errNotAllowed := errors.New("not allowed")
+
+	tests := []struct {
+		name             string
+		script           func(script *datatest.Script)
+		canReplace       error
+		wantCreated      bool
+		wantErr          error
+		wantAuthor       string
+		wantTransactions []string
+	}{
+		{
+			name: "creates",
+			script: func(script *datatest.Script) {
+				script.Expect(selectSlug).WithArgs("hello")
+				script.Expect(insertSlug).WillReturnResult(0, 1)
+				script.Expect(upsertPost).WillReturnResult(5, 1)
+				script.Expect(selectLast).WithArgs("p1").WillReturnRows([]string{"revision"}, []driver.Value{int64(0)})
+				script.Expect(insertRevision).WillReturnResult(1, 1)
+			},
+			wantCreated:      true,
+			wantAuthor:       "u2",
+			wantTransactions: []string{"commit"},
+		},
+		{
+			name: "replaces",
+			script: func(script *datatest.Script) {
+				script.Expect(selectSlug).WithArgs("hello")
+				script.Expect(insertSlug).WillReturnResult(0, 1)
+				script.Expect(upsertPost).WillReturnResult(5, 2)
+				script.Expect(selectExisting).WithArgs("p1").WillReturnRows(existingColumns, existing)
+				script.Expect(selectLatest).WithArgs("p1").WillReturnRows(revisionColumns, []driver.Value{int64(1), "p1", int64(1), "old", "markdown"})
+				script.Expect(selectLast).WillReturnRows([]string{"revision"}, []driver.Value{int64(1)})
+				script.Expect(insertRevision).WillReturnResult(2, 1)
+			},
+			wantAuthor:       "u1",
+			wantTransactions: []string{"commit"},
+		},
+		{
+			name: "replacing with the same content makes no revision",
+			script: func(script *datatest.Script) {
+				script.Expect(selectSlug).WithArgs("hello")
+				script.Expect(insertSlug).WillReturnResult(0, 1)
+				script.Expect(upsertPost).WillReturnResult(5, 2)
+				script.Expect(selectExisting).WillReturnRows(existingColumns, existing)
+				script.Expect(selectLatest).WillReturnRows(revisionColumns, []driver.Value{int64(1), "p1", int64(1), "hi", "markdown"})
+			},
+			wantAuthor:       "u1",
+			wantTransactions: []string{"commit"},
+		},
+		{
+			name: "replacement refused",
+			script: func(script *datatest.Script) {
+				script.Expect(selectSlug).WithArgs("hello")
+				script.Expect(insertSlug).WillReturnResult(0, 1)
+				script.Expect(upsertPost).WillReturnResult(5, 2)
+				script.Expect(selectExisting).WillReturnRows(existingColumns, existing)
+			},
+			canReplace:       errNotAllowed,
+			wantErr:          errNotAllowed,
+			wantTransactions: []string{"rollback"},
+		},
+		{
+			name: "update landed on the post with the colliding slug",
+			script: func(script *datatest.Script) {
+				script.Expect(selectSlug).WithArgs("hello")
+				script.Expect(insertSlug).WillReturnResult(0, 1)
+				script.Expect(upsertPost).WillReturnResult(9, 2)
+				script.Expect(selectExisting).WillReturnRows(existingColumns, existing)
+			},
+			wantErr:          ErrSlugTaken,
+			wantTransactions: []string{"rollback"},
+		},
+		{
+			name: "update landed on the post with the colliding slug, none at the post id",
+			script: func(script *datatest.Script) {
+				script.Expect(selectSlug).WithArgs("hello")
+				script.Expect(insertSlug).WillReturnResult(0, 1)
+				script.Expect(upsertPost).WillReturnResult(9, 2)
+				script.Expect(selectExisting).WillReturnRows(existingColumns)
+			},
+			wantErr:          ErrSlugTaken,
+			wantTransactions: []string{"rollback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			tt.script(script)
+
+			post := &Post{PostId: "p1", AuthorId: "u2", Title: "Hello", Content: "hi", Format: "markdown", Version: 1}
+			replaced := false
+			created, err := NewPostRepository(db).Upsert(post, func(existing *Post) error {
+				replaced = true
+				if existing.ID != 5 {
+					t.Errorf("canReplace() called with post %d, want the one at the post id", existing.ID)
+				}
+				return tt.canReplace
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Upsert() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := script.Transactions(); !reflect.DeepEqual(got, tt.wantTransactions) {
+				t.Errorf("transactions = %v, want %v", got, tt.wantTransactions)
+			}
+			if tt.wantErr != nil {
+				if post.Slug != "" {
+					t.Errorf("failed Upsert() left slug %s on the post", post.Slug)
+				}
+				return
+			}
+			if created != tt.wantCreated || replaced == created {
+				t.Errorf("Upsert() = %v with canReplace() called = %v, want %v", created, replaced, tt.wantCreated)
+			}
+			if post.ID != 5 || post.AuthorId != tt.wantAuthor || post.Slug != "hello" {
+				t.Errorf("Upsert() post = %d by %s at %s, want 5 by %s at hello", post.ID, post.AuthorId, post.Slug, tt.wantAuthor)
+			}
+		})
+	}
+}
+
+func TestPostRepositoryPurgeTrashed(t *testing.T) {
+	before := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, script := datatest.New(t)
+	script.Expect("SELECT `id`,`post_id` FROM `posts` WHERE deleted_at IS NOT NULL AND deleted_at < ? LIMIT 5").WithArgs(before).
+		WillReturnRows([]string{"id", "post_id"}, []driver.Value{int64(1), "p1"}, []driver.Value{int64(2), "p2"})
+	// everything kept about the posts outside of the posts table goes along with them
+	for _, table := range []string{"post_revisions", "post_tags", "post_slugs", "comments", "post_reactions", "post_reaction_counts", "post_media"} {
+		script.Expect("DELETE FROM `"+table+"` WHERE post_id IN (?,?)").WithArgs("p1", "p2").WillReturnResult(0, 1)
+	}
+	script.Expect("DELETE FROM `posts` WHERE `posts`.`id` IN (?,?)").WithArgs(int64(1), int64(2)).WillReturnResult(0, 2)
+
+	purged, err := NewPostRepository(db).PurgeTrashed(context.Background(), before, 5)
+	if err != nil || purged != 2 {
+		t.Errorf("PurgeTrashed() = %d, %v, want 2", purged, err)
+	}
+	if got := script.Transactions(); !reflect.DeepEqual(got, []string{"commit"}) {
+		t.Errorf("transactions = %v, want the purge committed at once", got)
+	}
+}
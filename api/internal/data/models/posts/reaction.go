@@ -0,0 +1,174 @@
+package posts
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrReactionSorts = errors.New("posts can only be sorted by one reaction count at a time")
+
+// reactionCountColumn is the column the count of the reaction kind posts are sorted
+// by is exposed as.
+const reactionCountColumn = "reaction_count"
+
+// PostReaction is the reaction of a principal to a post. Principals react at most once
+// per kind.
+type PostReaction struct {
+	PostId      string `gorm:"primaryKey"`
+	Kind        string `gorm:"primaryKey"`
+	PrincipalId string `gorm:"primaryKey"`
+	CreatedAt   time.Time
+}
+
+// PostReactionCount is the number of reactions of a kind to a post, denormalized from
+// the post reactions.
+type PostReactionCount struct {
+	PostId string `gorm:"primaryKey"`
+	Kind   string `gorm:"primaryKey"`
+	Count  uint
+}
+
+// ReactionSortField returns the name of the field sorting posts by their count of a
+// reaction kind, as in likes for like.
+func ReactionSortField(kind string) string {
+	return kind + "s"
+}
+
+// QuerySchemaWithReactions returns QuerySchema along with the fields sorting posts by
+// their count of each of the reaction kinds.
+func QuerySchemaWithReactions(kinds []string) query.Schema {
+	schema := make(query.Schema, len(QuerySchema)+len(kinds))
+	for name, field := range QuerySchema {
+		schema[name] = field
+	}
+	for _, kind := range kinds {
+		schema[ReactionSortField(kind)] = query.Field{Column: reactionCountColumn, Type: query.Integer, Sortable: true}
+	}
+	return schema
+}
+
+// PostReactionRepository is a repository for dealing with reactions to posts. Reacting
+// is idempotent, and keeps the reaction counts in step in the same transaction.
+type PostReactionRepository interface {
+	// React records the reaction of a principal to a post. It reports whether the
+	// reaction is new.
+	React(postId, kind, principalId string) (bool, error)
+	// Unreact removes the reaction of a principal to a post. It reports whether there
+	// was a reaction to remove.
+	Unreact(postId, kind, principalId string) (bool, error)
+}
+
+type postReactionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostReactionRepository(db *gorm.DB) PostReactionRepository {
+	return &postReactionRepository{
+		db: db,
+	}
+}
+
+func (p postReactionRepository) React(postId, kind, principalId string) (bool, error) {
+	reacted := false
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		reaction := PostReaction{PostId: postId, Kind: kind, PrincipalId: principalId}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		reacted = true
+
+		count := PostReactionCount{PostId: postId, Kind: kind, Count: 1}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("`count` + 1")}),
+		}).Create(&count).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return reacted, nil
+}
+
+func (p postReactionRepository) Unreact(postId, kind, principalId string) (bool, error) {
+	unreacted := false
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("post_id = ? AND kind = ? AND principal_id = ?", postId, kind, principalId).Delete(&PostReaction{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		unreacted = true
+
+		return tx.Model(&PostReactionCount{}).Where("post_id = ? AND kind = ?", postId, kind).
+			UpdateColumn("count", gorm.Expr("GREATEST(`count`, 1) - 1")).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return unreacted, nil
+}
+
+// withReactionCount scopes a query of posts to a derived posts table that exposes the
+// count of the reaction kind the query sorts by, if any, so it can be sorted and
+// paginated like any other column.
+func withReactionCount(db *gorm.DB, q *query.Query) (*gorm.DB, error) {
+	kind := ""
+	for _, sort := range q.Sorts {
+		if sort.Column != reactionCountColumn {
+			continue
+		}
+		if kind != "" {
+			return nil, ErrReactionSorts
+		}
+		kind = strings.TrimSuffix(sort.Field, "s")
+	}
+	if kind == "" {
+		return db, nil
+	}
+
+	counted := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Post{}).
+		Select("`posts`.*, COALESCE(`post_reaction_counts`.`count`, 0) AS "+reactionCountColumn).
+		Joins("LEFT JOIN `post_reaction_counts` ON `post_reaction_counts`.`post_id` = `posts`.`post_id` AND `post_reaction_counts`.`kind` = ?", kind)
+	return db.Table("(?) AS posts", counted), nil
+}
+
+// loadReactions fills in the reaction counts of posts, leaving out kinds nobody
+// reacted with.
+func loadReactions(db *gorm.DB, posts ...*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byPostId := map[string]*Post{}
+	postIds := make([]string, 0, len(posts))
+	for _, post := range posts {
+		post.Reactions = map[string]uint{}
+		byPostId[post.PostId] = post
+		postIds = append(postIds, post.PostId)
+	}
+
+	var counts []PostReactionCount
+	result := db.Where("post_id IN ? AND `count` > 0", postIds).Find(&counts)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, count := range counts {
+		if post, ok := byPostId[count.PostId]; ok {
+			post.Reactions[count.Kind] = count.Count
+		}
+	}
+	return nil
+}
+
+// deleteReactions deletes the reactions to posts and their counts.
+func deleteReactions(tx *gorm.DB, postIds ...string) error {
+	if err := tx.Where("post_id IN ?", postIds).Delete(&PostReaction{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("post_id IN ?", postIds).Delete(&PostReactionCount{}).Error
+}
@@ -0,0 +1,116 @@
+package posts
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+)
+
+func TestPostReactionRepositoryReact(t *testing.T) {
+	tests := []struct {
+		name        string
+		new         bool
+		wantReacted bool
+	}{
+		{name: "new reaction counted", new: true, wantReacted: true},
+		{name: "repeated reaction counted once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			if tt.new {
+				script.Expect("INSERT INTO `post_reactions` (`post_id`,`kind`,`principal_id`,`created_at`) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE").
+					WithArgs("p1", "like", "u1", datatest.AnyArg).WillReturnResult(0, 1)
+				script.Expect("INSERT INTO `post_reaction_counts` (`post_id`,`kind`,`count`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `count`=`count` + 1").
+					WithArgs("p1", "like", int64(1)).WillReturnResult(0, 1)
+			} else {
+				script.Expect("INSERT INTO `post_reactions`").WillReturnResult(0, 0)
+			}
+
+			reacted, err := NewPostReactionRepository(db).React("p1", "like", "u1")
+			if err != nil || reacted != tt.wantReacted {
+				t.Errorf("React() = %v, %v, want %v", reacted, err, tt.wantReacted)
+			}
+			if got := script.Transactions(); !reflect.DeepEqual(got, []string{"commit"}) {
+				t.Errorf("transactions = %v, want the reaction and its count committed together", got)
+			}
+		})
+	}
+}
+
+func TestPostReactionRepositoryUnreact(t *testing.T) {
+	tests := []struct {
+		name          string
+		reacted       bool
+		wantUnreacted bool
+	}{
+		{name: "reaction removed from the count", reacted: true, wantUnreacted: true},
+		{name: "missing reaction left uncounted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			if tt.reacted {
+				script.Expect("DELETE FROM `post_reactions` WHERE post_id = ? AND kind = ? AND principal_id = ?").WithArgs("p1", "like", "u1").WillReturnResult(0, 1)
+				// counts never go below zero, even if they drifted
+				script.Expect("UPDATE `post_reaction_counts` SET `count`=GREATEST(`count`, 1) - 1 WHERE post_id = ? AND kind = ?").WithArgs("p1", "like").WillReturnResult(0, 1)
+			} else {
+				script.Expect("DELETE FROM `post_reactions`").WillReturnResult(0, 0)
+			}
+
+			unreacted, err := NewPostReactionRepository(db).Unreact("p1", "like", "u1")
+			if err != nil || unreacted != tt.wantUnreacted {
+				t.Errorf("Unreact() = %v, %v, want %v", unreacted, err, tt.wantUnreacted)
+			}
+		})
+	}
+}
+
+func TestWithReactionCount(t *testing.T) {
+	schema := QuerySchemaWithReactions([]string{"like", "love"})
+	sortBy := func(fields ...string) *query.Query {
+		q := &query.Query{}
+		for _, field := range fields {
+			q.Sorts = append(q.Sorts, query.Sort{Field: field, Column: schema[field].Column, Desc: true})
+		}
+		return q
+	}
+
+	tests := []struct {
+		name     string
+		q        *query.Query
+		wantSQL  string
+		wantVars []interface{}
+		wantErr  error
+	}{
+		{name: "posts table without reaction sorts", q: sortBy("created_at"), wantSQL: "SELECT * FROM `posts`", wantVars: []interface{}{}},
+		{name: "derived table with the count sorted by", q: sortBy(ReactionSortField("like"), "created_at"), wantSQL: "FROM (SELECT `posts`.*, COALESCE(`post_reaction_counts`.`count`, 0) AS reaction_count FROM `posts` LEFT JOIN `post_reaction_counts` ON `post_reaction_counts`.`post_id` = `posts`.`post_id` AND `post_reaction_counts`.`kind` = ?) AS posts", wantVars: []interface{}{"like"}},
+		{name: "one reaction sort at a time", q: sortBy("likes", "loves"), wantErr: ErrReactionSorts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := datatest.DryRun(t)
+			scoped, err := withReactionCount(db.Model(&Post{}), tt.q)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("withReactionCount() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			stmt := scoped.Find(&[]Post{}).Statement
+			if sql := stmt.SQL.String(); !strings.Contains(sql, tt.wantSQL) {
+				t.Errorf("withReactionCount() SQL = %s, want it to contain %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("withReactionCount() vars = %v, want %v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
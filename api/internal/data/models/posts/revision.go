@@ -0,0 +1,120 @@
+package posts
+
+import (
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"gorm.io/gorm"
+)
+
+// RevisionQuerySchema is the allow-list of fields post revisions can be filtered and
+// sorted by.
+var RevisionQuerySchema = query.Schema{
+	"revision":   {Column: "revision", Type: query.Integer, Operators: query.Ordered, Sortable: true},
+	"editor_id":  {Column: "editor_id", Type: query.String, Operators: query.Equality},
+	"created_at": {Column: "created_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+}
+
+// PostRevision is an immutable snapshot of the content of a post, appended on every
+// content change.
+type PostRevision struct {
+	ID        uint `gorm:"primarykey"`
+	PostId    string
+	Revision  uint
+	EditorId  string
+	Content   string
+	Format    string
+	CreatedAt time.Time
+}
+
+// PostRevisionRepository is a repository for reading post revisions. Revisions are
+// written by the PostRepository, along with the post change they record.
+type PostRevisionRepository interface {
+	// List lists the revisions of a post with pagination.
+	List(postId string, pagination *data.Pagination) ([]PostRevision, *data.Pagination, error)
+	// Get gets a revision of a post by its number.
+	Get(postId string, revision uint) (*PostRevision, error)
+}
+
+type postRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostRevisionRepository(db *gorm.DB) PostRevisionRepository {
+	return &postRevisionRepository{
+		db: db,
+	}
+}
+
+func (p postRevisionRepository) List(postId string, pagination *data.Pagination) ([]PostRevision, *data.Pagination, error) {
+	var revisions []PostRevision
+
+	result := p.db.Where("post_id = ?", postId).Scopes(pagination.Paginate()).Find(&revisions)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	revisions, err := data.KeysetPage(p.db, pagination, revisions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pagination details
+	if !pagination.SkipCount {
+		result = p.db.Model(&PostRevision{}).Where("post_id = ?", postId).Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
+
+	return revisions, pagination, nil
+}
+
+func (p postRevisionRepository) Get(postId string, revision uint) (*PostRevision, error) {
+	rev := PostRevision{}
+	result := p.db.Where("post_id = ? AND revision = ?", postId, revision).First(&rev)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &rev, nil
+}
+
+// latestRevision gets the latest revision of a post, or nil if it has none. It must run
+// in the transaction that changed the post, like appendRevision.
+func latestRevision(tx *gorm.DB, postId string) (*PostRevision, error) {
+	var revisions []PostRevision
+	result := tx.Where("post_id = ?", postId).Order("revision DESC").Limit(1).Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return &revisions[0], nil
+}
+
+// appendRevision records the current content of a post as its next revision. It must
+// run in the transaction that changed the post, which holds the lock on the post row.
+func appendRevision(tx *gorm.DB, post *Post, editorId string) (*PostRevision, error) {
+	var last uint
+	result := tx.Model(&PostRevision{}).Where("post_id = ?", post.PostId).
+		Select("COALESCE(MAX(revision), 0)").Scan(&last)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	revision := &PostRevision{
+		PostId:   post.PostId,
+		Revision: last + 1,
+		EditorId: editorId,
+		Content:  post.Content,
+		Format:   post.Format,
+	}
+	result = tx.Create(revision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revision, nil
+}
@@ -0,0 +1,82 @@
+package posts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/pkg/slug"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SlugMaxLength is the maximum length, in characters, of the slug generated from a
+// title, leaving room for collision suffixes.
+const SlugMaxLength = 80
+
+// slugAttempts is how many numbered suffixes are tried on slug collisions before
+// falling back to the post id as suffix.
+const slugAttempts = 20
+
+// PostSlug is a slug a post goes or went by. Slugs are never handed to another post
+// while the post they belong to exists, so old links keep resolving to it.
+type PostSlug struct {
+	ID        uint `gorm:"primarykey"`
+	Slug      string
+	PostId    string
+	CreatedAt time.Time
+}
+
+// NormalizeSlug returns the slug of a title, empty if nothing is left of it.
+func NormalizeSlug(title string) string {
+	return slug.Make(title, SlugMaxLength)
+}
+
+// assignSlug sets the slug of a post from its title, or from its post id when it has
+// no title. Colliding slugs get a numbered suffix, as in hello-world-2, and slugs the
+// post already went by are reused. It must run in the transaction that changed the
+// post.
+func assignSlug(tx *gorm.DB, post *Post) error {
+	base := NormalizeSlug(post.Title)
+	if base == "" {
+		base = post.PostId
+	}
+
+	for n := 1; n <= slugAttempts+1; n++ {
+		candidate := base
+		switch {
+		case n > slugAttempts:
+			candidate = fmt.Sprintf("%s-%s", base, post.PostId)
+		case n > 1:
+			candidate = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		owner := PostSlug{}
+		result := tx.Where("slug = ?", candidate).Limit(1).Find(&owner)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if owner.PostId == post.PostId {
+				post.Slug = candidate
+				return nil
+			}
+			continue
+		}
+
+		// another writer may claim the slug in the meantime
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&PostSlug{Slug: candidate, PostId: post.PostId})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			post.Slug = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("no free slug for post %s", post.PostId)
+}
+
+// deleteSlugs frees the slugs posts went by.
+func deleteSlugs(tx *gorm.DB, postIds ...string) error {
+	return tx.Where("post_id IN ?", postIds).Delete(&PostSlug{}).Error
+}
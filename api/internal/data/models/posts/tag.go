@@ -0,0 +1,219 @@
+package posts
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/slug"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagMaxLength is the maximum length, in characters, of a normalized tag name.
+const TagMaxLength = 50
+
+// TagQuerySchema is the allow-list of fields tags can be filtered and sorted by.
+var TagQuerySchema = query.Schema{
+	"name":       {Column: "name", Type: query.String, Operators: query.Text, Sortable: true},
+	"post_count": {Column: "post_count", Type: query.Integer, Operators: query.Ordered, Sortable: true},
+	"created_at": {Column: "created_at", Type: query.Time, Operators: query.Ordered, Sortable: true},
+}
+
+// Tag is a label posts can be filed under. Names are stored normalized, so tags that
+// only differ in case or punctuation are the same tag.
+type Tag struct {
+	ID        uint `gorm:"primarykey"`
+	Name      string
+	CreatedAt time.Time
+}
+
+// PostTag links a post to one of its tags.
+type PostTag struct {
+	PostId string `gorm:"primaryKey"`
+	TagId  uint   `gorm:"primaryKey"`
+}
+
+// TagCount is a tag along with the number of posts filed under it.
+type TagCount struct {
+	Tag
+	PostCount int64
+}
+
+// NormalizeTag returns the normalized name of a tag, empty if nothing is left of it.
+func NormalizeTag(name string) string {
+	return slug.Fold(name, TagMaxLength)
+}
+
+// NormalizeTags normalizes tag names, dropping empty and duplicate ones, in name order.
+func NormalizeTags(names []string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, name := range names {
+		tag := NormalizeTag(name)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagCondition filters posts by the tags they are filed under.
+func tagCondition(op query.Operator, value interface{}) clause.Expression {
+	var tags []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, tag := range v {
+			tags = append(tags, NormalizeTag(tag.(string)))
+		}
+	default:
+		tags = append(tags, NormalizeTag(v.(string)))
+	}
+
+	membership := "? IN (SELECT `post_tags`.`post_id` FROM `post_tags` JOIN `tags` ON `tags`.`id` = `post_tags`.`tag_id` WHERE `tags`.`name` IN ?)"
+	if op == query.Ne {
+		membership = "? NOT IN (SELECT `post_tags`.`post_id` FROM `post_tags` JOIN `tags` ON `tags`.`id` = `post_tags`.`tag_id` WHERE `tags`.`name` IN ?)"
+	}
+	return clause.Expr{SQL: membership, Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: "post_id"}, tags}}
+}
+
+// TagRepository is a repository for reading tags. The tags of a post are written by
+// the PostRepository, along with the post.
+type TagRepository interface {
+	// List lists the tags in use along with how many posts are filed under them, only
+	// counting published posts if publishedOnly is set. Tags without posts are left out.
+	List(publishedOnly bool, pagination *data.Pagination) ([]TagCount, *data.Pagination, error)
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{
+		db: db,
+	}
+}
+
+func (t tagRepository) List(publishedOnly bool, pagination *data.Pagination) ([]TagCount, *data.Pagination, error) {
+	var tags []TagCount
+
+	counts := t.db.Model(&Tag{}).
+		Select("`tags`.`id`, `tags`.`name`, `tags`.`created_at`, COUNT(*) AS post_count").
+		Joins("JOIN `post_tags` ON `post_tags`.`tag_id` = `tags`.`id`").
+		Joins("JOIN `posts` ON `posts`.`post_id` = `post_tags`.`post_id` AND `posts`.`deleted_at` IS NULL").
+		Group("`tags`.`id`")
+	if publishedOnly {
+		counts = counts.Where("`posts`.`status` = ?", StatusPublished)
+	}
+
+	result := t.db.Table("(?) AS tags", counts).Scopes(pagination.Paginate()).Find(&tags)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	tags, err := data.KeysetPage(t.db, pagination, tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pagination details
+	if !pagination.SkipCount {
+		result = t.db.Table("(?) AS tags", counts).Scopes(pagination.Where()).Count(&pagination.TotalRows)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		pagination.TotalPages = data.GetTotalPages(pagination.TotalRows, pagination.GetLimit())
+	}
+
+	return tags, pagination, nil
+}
+
+// saveTags files a post under exactly its tags, creating the tags that don't exist
+// yet. Nil tags are left untouched. It must run in the transaction that changed the
+// post.
+func saveTags(tx *gorm.DB, post *Post) error {
+	if post.Tags == nil {
+		return nil
+	}
+
+	var tagIds []uint
+	if len(post.Tags) > 0 {
+		// concurrent writers may create the same tags, so conflicts are ignored and
+		// the ids read back afterwards
+		tags := make([]Tag, 0, len(post.Tags))
+		for _, name := range post.Tags {
+			tags = append(tags, Tag{Name: name})
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags)
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Model(&Tag{}).Where("name IN ?", post.Tags).Pluck("id", &tagIds)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	unlink := tx.Where("post_id = ?", post.PostId)
+	if len(tagIds) > 0 {
+		unlink = unlink.Where("tag_id NOT IN ?", tagIds)
+	}
+	if err := unlink.Delete(&PostTag{}).Error; err != nil {
+		return err
+	}
+
+	links := make([]PostTag, 0, len(tagIds))
+	for _, tagId := range tagIds {
+		links = append(links, PostTag{PostId: post.PostId, TagId: tagId})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
+
+// loadTags fills in the tags of posts, in name order.
+func loadTags(db *gorm.DB, posts ...*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byPostId := map[string]*Post{}
+	postIds := make([]string, 0, len(posts))
+	for _, post := range posts {
+		post.Tags = []string{}
+		byPostId[post.PostId] = post
+		postIds = append(postIds, post.PostId)
+	}
+
+	var links []struct {
+		PostId string
+		Name   string
+	}
+	result := db.Model(&PostTag{}).
+		Select("`post_tags`.`post_id`, `tags`.`name`").
+		Joins("JOIN `tags` ON `tags`.`id` = `post_tags`.`tag_id`").
+		Where("`post_tags`.`post_id` IN ?", postIds).
+		Order("`tags`.`name`").
+		Find(&links)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, link := range links {
+		if post, ok := byPostId[link.PostId]; ok {
+			post.Tags = append(post.Tags, link.Name)
+		}
+	}
+	return nil
+}
+
+// deleteTags unfiles posts from all their tags. Tags left without posts are kept.
+func deleteTags(tx *gorm.DB, postIds ...string) error {
+	return tx.Where("post_id IN ?", postIds).Delete(&PostTag{}).Error
+}
@@ -0,0 +1,98 @@
+package posts
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/datatest"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{name: "case and punctuation folded", names: []string{"Go Lang!", "  DevOps "}, want: []string{"devops", "go-lang"}},
+		{name: "duplicates after folding dropped", names: []string{"Go", "go", "GO!"}, want: []string{"go"}},
+		{name: "empty tags dropped", names: []string{"?!", "", "go"}, want: []string{"go"}},
+		{name: "other scripts kept", names: []string{"日本", "Привет"}, want: []string{"privet", "日本"}},
+		{name: "cut to the maximum length", names: []string{strings.Repeat("a", TagMaxLength+10)}, want: []string{strings.Repeat("a", TagMaxLength)}},
+		{name: "no tags", names: nil, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeTags(tt.names); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTags(%q) = %q, want %q", tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       query.Operator
+		value    interface{}
+		wantSQL  string
+		wantTags []interface{}
+	}{
+		{name: "filed under a tag", op: query.Eq, value: "Go Lang", wantSQL: "`posts`.`post_id` IN (SELECT", wantTags: []interface{}{"go-lang"}},
+		{name: "filed under any of the tags", op: query.In, value: []interface{}{"Go", "Rust"}, wantSQL: "`posts`.`post_id` IN (SELECT", wantTags: []interface{}{"go", "rust"}},
+		{name: "not filed under a tag", op: query.Ne, value: "go", wantSQL: "`posts`.`post_id` NOT IN (SELECT", wantTags: []interface{}{"go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := datatest.DryRun(t).Model(&Post{}).Where(tagCondition(tt.op, tt.value)).Find(&[]Post{}).Statement
+			if sql := stmt.SQL.String(); !strings.Contains(sql, tt.wantSQL) {
+				t.Errorf("tagCondition() SQL = %s, want it to contain %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantTags) {
+				t.Errorf("tagCondition() vars = %v, want %v", stmt.Vars, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestTagRepositoryList(t *testing.T) {
+	const counts = "SELECT `tags`.`id`, `tags`.`name`, `tags`.`created_at`, COUNT(*) AS post_count FROM `tags` JOIN `post_tags` ON `post_tags`.`tag_id` = `tags`.`id` JOIN `posts` ON `posts`.`post_id` = `post_tags`.`post_id` AND `posts`.`deleted_at` IS NULL"
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		publishedOnly bool
+		wantSQL       string
+		wantArgs      []interface{}
+	}{
+		{name: "every post counted", wantSQL: counts + " GROUP BY `tags`.`id`) AS tags"},
+		{name: "published posts counted", publishedOnly: true, wantSQL: counts + " WHERE `posts`.`status` = ? GROUP BY `tags`.`id`) AS tags", wantArgs: []interface{}{StatusPublished}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, script := datatest.New(t)
+			script.Expect(tt.wantSQL+" ORDER BY `tags`.`post_count` DESC,`tags`.`id` DESC LIMIT 11").WithArgs(tt.wantArgs...).
+				WillReturnRows([]string{"id", "name", "created_at", "post_count"}, []driver.Value{int64(1), "go", now, int64(3)}, []driver.Value{int64(2), "rust", now, int64(1)})
+			script.Expect("SELECT count(*) FROM ("+tt.wantSQL).WithArgs(tt.wantArgs...).WillReturnRows([]string{"count"}, []driver.Value{int64(2)})
+
+			pagination := &data.Pagination{Limit: 10, Query: &query.Query{Sorts: []query.Sort{{Field: "post_count", Column: "post_count", Desc: true}}}}
+			tags, pagination, err := NewTagRepository(db).List(tt.publishedOnly, pagination)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(tags) != 2 || tags[0].Name != "go" || tags[0].PostCount != 3 || tags[1].PostCount != 1 {
+				t.Errorf("List() = %+v, want the tags with their post counts", tags)
+			}
+			if pagination.TotalRows != 2 {
+				t.Errorf("List() total rows = %d, want 2", pagination.TotalRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package users
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a long lived token used to obtain new access tokens. Only the
+// SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint `gorm:"primarykey"`
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RefreshTokenRepository is a repository for dealing with the refresh token object.
+type RefreshTokenRepository interface {
+	// GetByHash gets a refresh token from the database by the hash of the token.
+	GetByHash(hash string) (*RefreshToken, error)
+	// Create creates a refresh token in the database.
+	Create(token *RefreshToken) error
+	// Rotate revokes a refresh token and creates its replacement in a single
+	// transaction. It fails with gorm.ErrRecordNotFound if the token was already revoked.
+	Rotate(old *RefreshToken, replacement *RefreshToken) error
+	// Revoke revokes a refresh token.
+	Revoke(token *RefreshToken) error
+	// RevokeAllForUser revokes every active refresh token of a user.
+	RevokeAllForUser(userId string) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+func (r refreshTokenRepository) GetByHash(hash string) (*RefreshToken, error) {
+	token := RefreshToken{}
+	result := r.db.Where("token_hash = ?", hash).First(&token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r refreshTokenRepository) Create(token *RefreshToken) error {
+	result := r.db.Create(token)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r refreshTokenRepository) Rotate(old *RefreshToken, replacement *RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// only one concurrent refresh can win the rotation
+		result := tx.Model(old).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(replacement).Error
+	})
+}
+
+func (r refreshTokenRepository) Revoke(token *RefreshToken) error {
+	result := r.db.Model(token).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r refreshTokenRepository) RevokeAllForUser(userId string) error {
+	result := r.db.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userId).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package users
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+var ErrDuplicateEmail = errors.New("a user with this email already exists")
+
+// mysqlErrDuplicateEntry is returned by MySQL when a unique key is violated.
+const mysqlErrDuplicateEntry = 1062
+
+type User struct {
+	gorm.Model
+	UserId       string
+	Email        string
+	PasswordHash string
+	Role         string
+}
+
+// UserRepository is a repository for dealing with the user object.
+type UserRepository interface {
+	// GetByUUID gets a user from the database by uuid.
+	GetByUUID(uuid string) (*User, error)
+	// GetByEmail gets a user from the database by email.
+	GetByEmail(email string) (*User, error)
+	// Create creates a user in the database, failing with ErrDuplicateEmail if the
+	// email is already registered.
+	Create(user *User) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{
+		db: db,
+	}
+}
+
+func (u userRepository) GetByUUID(uuid string) (*User, error) {
+	user := User{}
+	result := u.db.Where("user_id = ?", uuid).First(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (u userRepository) GetByEmail(email string) (*User, error) {
+	user := User{}
+	result := u.db.Where("email = ?", email).First(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (u userRepository) Create(user *User) error {
+	result := u.db.Create(user)
+	if result.Error != nil {
+		if isDuplicateEntry(result.Error) {
+			return ErrDuplicateEmail
+		}
+		return result.Error
+	}
+	return nil
+}
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
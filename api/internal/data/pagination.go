@@ -4,19 +4,25 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// DefaultSort is used when a query has no sort keys.
+var DefaultSort = query.Sort{Field: "created_at", Column: "created_at"}
+
 type Pagination struct {
-	Limit       int
-	Page        int
-	Sort        string
-	Filter      map[string]string
-	Search      map[string]string
-	SearchQuery string
-	TotalRows   int64
-	TotalPages  int
-	Data        interface{}
+	Limit      int
+	Page       int
+	Query      *query.Query
+	Cursor     *Cursor
+	SkipCount  bool
+	NextCursor *Cursor
+	PrevCursor *Cursor
+	TotalRows  int64
+	TotalPages int
+	Data       interface{}
 }
 
 func (p *Pagination) GetOffset() int {
@@ -37,47 +43,128 @@ func (p *Pagination) GetPage() int {
 	return p.Page
 }
 
-func (p *Pagination) GetSort() string {
-	if p.Sort == "" {
-		p.Sort = "created_at asc"
+func (p *Pagination) GetQuery() *query.Query {
+	if p.Query == nil {
+		p.Query = &query.Query{}
+	}
+	if len(p.Query.Sorts) == 0 {
+		p.Query.Sorts = []query.Sort{DefaultSort}
 	}
-	return p.Sort
+	return p.Query
 }
 
-func (p *Pagination) GetFilter() map[string]string {
-	if len(p.Filter) == 0 {
-		p.Filter = map[string]string{}
+// SetCursor continues the pagination from a decoded cursor. The sort keys the cursor
+// was issued for are re-validated against the schema and take over the query sort.
+func (p *Pagination) SetCursor(cursor *Cursor, schema query.Schema) error {
+	sorts, errs := schema.ParseSort(cursor.Sort)
+	if len(errs) > 0 || len(sorts) != len(cursor.Values) {
+		return ErrInvalidCursor
 	}
-	return p.Filter
+	if p.Query != nil && len(p.Query.Sorts) > 0 && p.Query.SortString() != cursor.Sort {
+		return fmt.Errorf("%w: issued for sort %s", ErrInvalidCursor, cursor.Sort)
+	}
+
+	p.GetQuery().Sorts = sorts
+	p.Cursor = cursor
+	return nil
 }
 
-func (p *Pagination) GetSearch() map[string]string {
-	if len(p.Search) == 0 {
-		p.Search = map[string]string{}
-	}
-	return p.Search
+// Where applies the filter and search conditions only, so it can be shared between
+// the page query and the count query.
+func (p *Pagination) Where() func(db *gorm.DB) *gorm.DB {
+	return p.GetQuery().Where()
 }
 
+// Paginate fetches one row past the limit so callers can tell whether another page
+// exists. With a cursor it seeks on (sort fields, id) instead of using an offset.
 func (p *Pagination) Paginate() func(db *gorm.DB) *gorm.DB {
-	// build search query
-	if len(p.GetSearch()) > 0 {
-		p.GetSearch()
-		first := true
-		for field, filter := range p.GetSearch() {
-			if first {
-				p.SearchQuery = fmt.Sprintf("%s LIKE '%%%s%%'", field, filter)
-				first = false
-			} else {
-				p.SearchQuery = fmt.Sprintf("%s OR %s LIKE '%%%s%%'", p.SearchQuery, field, filter)
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(p.Where()).Limit(p.GetLimit() + 1)
+
+		keys := sortKeys(p.GetQuery().Sorts)
+		backward := p.Cursor != nil && p.Cursor.Backward
+		for _, key := range keys {
+			db = db.Order(clause.OrderByColumn{Column: columnOf(key.Column), Desc: key.Desc != backward})
+		}
+
+		if p.Cursor == nil {
+			return db.Offset(p.GetOffset())
+		}
+
+		values := make([]interface{}, 0, len(keys))
+		for _, cursorValue := range p.Cursor.Values {
+			value, err := cursorValue.value()
+			if err != nil {
+				db.AddError(err)
+				return db
 			}
+			values = append(values, value)
 		}
+		values = append(values, p.Cursor.ID)
+
+		// (a > x) OR (a = x AND b > y) OR (a = x AND b = y AND id > z)
+		var seeks []clause.Expression
+		for i, key := range keys {
+			// the primary key breaking ties is never NULL
+			past := seekPast(key.Column, values[i], key.Desc != backward, i < len(keys)-1)
+			if past == nil {
+				continue
+			}
+			var conditions []clause.Expression
+			for j := 0; j < i; j++ {
+				// a nil value compares as IS NULL
+				conditions = append(conditions, clause.Eq{Column: columnOf(keys[j].Column), Value: values[j]})
+			}
+			seeks = append(seeks, clause.And(append(conditions, past)...))
+		}
+		return db.Where(query.Or(seeks...))
+	}
+}
+
+// seekPast returns the condition on a sort field of the rows ordered after the given
+// value, or nil if there are none. NULLs sort before any value ascending and after
+// any value descending, as MySQL orders them.
+func seekPast(column string, value interface{}, desc, nullable bool) clause.Expression {
+	switch {
+	case desc && value == nil:
+		return nil
+	case desc && !nullable:
+		return clause.Lt{Column: columnOf(column), Value: value}
+	case desc:
+		return query.Or(clause.Lt{Column: columnOf(column), Value: value}, clause.Eq{Column: columnOf(column), Value: nil})
+	case value == nil:
+		return clause.Neq{Column: columnOf(column), Value: nil}
+	default:
+		return clause.Gt{Column: columnOf(column), Value: value}
 	}
+}
 
+// Sorted applies the filter and search conditions and the sort keys of a query
+// without paginating, for reading every matching row in order.
+func Sorted(q *query.Query) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		return db.Offset(p.GetOffset()).Limit(p.GetLimit()).Order(p.GetSort()).Where(p.GetFilter()).Where(p.SearchQuery)
+		sorts := q.Sorts
+		if len(sorts) == 0 {
+			sorts = []query.Sort{DefaultSort}
+		}
+		db = db.Scopes(q.Where())
+		for _, key := range sortKeys(sorts) {
+			db = db.Order(clause.OrderByColumn{Column: columnOf(key.Column), Desc: key.Desc})
+		}
+		return db
 	}
 }
 
+// sortKeys returns the sort keys rows are ordered by, the primary key breaking ties so
+// every row has a unique position.
+func sortKeys(sorts []query.Sort) []query.Sort {
+	return append(append([]query.Sort{}, sorts...), query.Sort{Column: "id", Desc: sorts[len(sorts)-1].Desc})
+}
+
+func columnOf(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
 func GetTotalPages(rows int64, limit int) int {
 	return int(math.Ceil(float64(rows) / float64(limit)))
 }
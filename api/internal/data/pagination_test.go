@@ -0,0 +1,106 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type entry struct {
+	ID        uint `gorm:"primarykey"`
+	Title     string
+	PublishAt *time.Time
+}
+
+// dryRun returns a database that builds statements without running them.
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := sql.Open("mysql", "user:password@tcp(127.0.0.1:3306)/test")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	return db
+}
+
+func TestPaginateSeeksPastCursor(t *testing.T) {
+	publishAt := CursorValue{Value: "2024-03-01T12:00:00Z", IsTime: true}
+	sortBy := func(desc bool) *query.Query {
+		return &query.Query{Sorts: []query.Sort{{Field: "publish_at", Column: "publish_at", Desc: desc}}}
+	}
+
+	tests := []struct {
+		name   string
+		query  *query.Query
+		cursor *Cursor
+		want   string
+	}{
+		{
+			name:   "descending",
+			query:  sortBy(true),
+			cursor: &Cursor{Values: []CursorValue{publishAt}, ID: 5},
+			want:   "SELECT * FROM `entries` WHERE ((`entries`.`publish_at` < ? OR `entries`.`publish_at` IS NULL) OR (`entries`.`publish_at` = ? AND `entries`.`id` < ?)) ORDER BY `entries`.`publish_at` DESC,`entries`.`id` DESC LIMIT 11",
+		},
+		{
+			name:   "descending from null",
+			query:  sortBy(true),
+			cursor: &Cursor{Values: []CursorValue{{}}, ID: 5},
+			want:   "SELECT * FROM `entries` WHERE (`entries`.`publish_at` IS NULL AND `entries`.`id` < ?) ORDER BY `entries`.`publish_at` DESC,`entries`.`id` DESC LIMIT 11",
+		},
+		{
+			name:   "ascending",
+			query:  sortBy(false),
+			cursor: &Cursor{Values: []CursorValue{publishAt}, ID: 5},
+			want:   "SELECT * FROM `entries` WHERE (`entries`.`publish_at` > ? OR (`entries`.`publish_at` = ? AND `entries`.`id` > ?)) ORDER BY `entries`.`publish_at`,`entries`.`id` LIMIT 11",
+		},
+		{
+			name:   "ascending from null",
+			query:  sortBy(false),
+			cursor: &Cursor{Values: []CursorValue{{}}, ID: 5},
+			want:   "SELECT * FROM `entries` WHERE (`entries`.`publish_at` IS NOT NULL OR (`entries`.`publish_at` IS NULL AND `entries`.`id` > ?)) ORDER BY `entries`.`publish_at`,`entries`.`id` LIMIT 11",
+		},
+		{
+			name:   "backward from null",
+			query:  sortBy(true),
+			cursor: &Cursor{Values: []CursorValue{{}}, ID: 5, Backward: true},
+			want:   "SELECT * FROM `entries` WHERE (`entries`.`publish_at` IS NOT NULL OR (`entries`.`publish_at` IS NULL AND `entries`.`id` > ?)) ORDER BY `entries`.`publish_at`,`entries`.`id` LIMIT 11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pagination{Query: tt.query, Cursor: tt.cursor}
+			stmt := dryRun(t).Scopes(p.Paginate()).Find(&[]entry{}).Statement
+			if got := stmt.SQL.String(); got != tt.want {
+				t.Errorf("Paginate() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetPageIssuesCursorsForNulls(t *testing.T) {
+	publishAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := []entry{{ID: 3, PublishAt: &publishAt}, {ID: 2}, {ID: 1}}
+
+	p := &Pagination{Limit: 2, Query: &query.Query{Sorts: []query.Sort{{Field: "publish_at", Column: "publish_at", Desc: true}}}}
+	page, err := KeysetPage(dryRun(t), p, rows)
+	if err != nil {
+		t.Fatalf("KeysetPage() error = %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("KeysetPage() returned %d rows, want 2", len(page))
+	}
+	if p.NextCursor == nil {
+		t.Fatal("KeysetPage() issued no next cursor")
+	}
+	if p.NextCursor.ID != 2 || len(p.NextCursor.Values) != 1 || p.NextCursor.Values[0].Value != nil {
+		t.Errorf("NextCursor = %+v, want a NULL value at id 2", p.NextCursor)
+	}
+}
@@ -0,0 +1,166 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	listSeparator  = ","
+	partSeparator  = ":"
+	valueSeparator = "|"
+	sortSeparator  = "."
+)
+
+// Error describes why a query parameter was rejected.
+type Error struct {
+	Parameter string `json:"parameter"`
+	Value     string `json:"value,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Errors collects every problem found in a set of query parameters.
+type Errors []Error
+
+func (e Errors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, fmt.Sprintf("%s: %s", err.Parameter, err.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ParseFilters parses filters in the form field:operator:value, separated by commas.
+// The operator may be omitted for equality (field:value), and the in operator takes
+// values separated by pipes (field:in:a|b).
+func (s Schema) ParseFilters(raw string) ([]Filter, Errors) {
+	var filters []Filter
+	var errs Errors
+	for _, item := range strings.Split(raw, listSeparator) {
+		if item == "" {
+			continue
+		}
+		filter, err := s.parseFilter(item)
+		if err != nil {
+			errs = append(errs, *err)
+			continue
+		}
+		filters = append(filters, *filter)
+	}
+	return filters, errs
+}
+
+func (s Schema) parseFilter(item string) (*Filter, *Error) {
+	parts := strings.SplitN(item, partSeparator, 2)
+	if len(parts) != 2 {
+		return nil, &Error{Parameter: "filter", Value: item, Message: "malformed filter, should be field:operator:value"}
+	}
+
+	name, rest := parts[0], parts[1]
+	field, ok := s[name]
+	if !ok {
+		return nil, &Error{Parameter: "filter", Value: item, Message: fmt.Sprintf("unknown filter field %s", name)}
+	}
+
+	op, value := Eq, rest
+	if parts := strings.SplitN(rest, partSeparator, 2); len(parts) == 2 && isOperator(parts[0]) {
+		op, value = Operator(parts[0]), parts[1]
+	}
+	if !field.allows(op) {
+		return nil, &Error{Parameter: "filter", Value: item, Message: fmt.Sprintf("operator %s is not allowed on field %s", op, name)}
+	}
+
+	filter := &Filter{Field: name, Column: field.Column, Operator: op, condition: field.Condition}
+	switch op {
+	case In:
+		var values []interface{}
+		for _, v := range strings.Split(value, valueSeparator) {
+			typed, err := field.Type.parse(v)
+			if err != nil {
+				return nil, &Error{Parameter: "filter", Value: item, Message: err.Error()}
+			}
+			values = append(values, typed)
+		}
+		filter.Value = values
+	case Contains, Prefix:
+		filter.Value = value
+	default:
+		typed, err := field.Type.parse(value)
+		if err != nil {
+			return nil, &Error{Parameter: "filter", Value: item, Message: err.Error()}
+		}
+		filter.Value = typed
+	}
+	return filter, nil
+}
+
+// ParseSort parses sort keys in the form field.direction, separated by commas. The
+// direction is asc or desc and defaults to asc.
+func (s Schema) ParseSort(raw string) ([]Sort, Errors) {
+	var sorts []Sort
+	var errs Errors
+	for _, item := range strings.Split(raw, listSeparator) {
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, sortSeparator, 2)
+		name, direction := parts[0], "asc"
+		if len(parts) == 2 {
+			direction = strings.ToLower(parts[1])
+		}
+
+		field, ok := s[name]
+		if !ok || !field.Sortable {
+			errs = append(errs, Error{Parameter: "sort", Value: item, Message: fmt.Sprintf("field %s is not sortable", name)})
+			continue
+		}
+		if direction != "asc" && direction != "desc" {
+			errs = append(errs, Error{Parameter: "sort", Value: item, Message: "malformed order in sort query, should be asc or desc"})
+			continue
+		}
+		sorts = append(sorts, Sort{Field: name, Column: field.Column, Desc: direction == "desc"})
+	}
+	return sorts, errs
+}
+
+// SearchFields returns the columns of the fields free text search runs against.
+func (s Schema) SearchFields() []string {
+	var columns []string
+	for _, field := range s {
+		if field.Searchable {
+			columns = append(columns, field.Column)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func isOperator(value string) bool {
+	switch Operator(value) {
+	case Eq, Ne, Gt, Gte, Lt, Lte, In, Contains, Prefix:
+		return true
+	}
+	return false
+}
+
+func (t Type) parse(value string) (interface{}, error) {
+	switch t {
+	case Integer:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an integer", value)
+		}
+		return i, nil
+	case Time:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not a RFC 3339 timestamp or a date", value)
+	}
+	return value, nil
+}
@@ -0,0 +1,181 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Type is the type values of a field are parsed into before being bound to a query.
+type Type int
+
+const (
+	String Type = iota
+	Integer
+	Time
+)
+
+type Operator string
+
+const (
+	Eq       Operator = "eq"
+	Ne       Operator = "ne"
+	Gt       Operator = "gt"
+	Gte      Operator = "gte"
+	Lt       Operator = "lt"
+	Lte      Operator = "lte"
+	In       Operator = "in"
+	Contains Operator = "contains"
+	Prefix   Operator = "prefix"
+	// NotNull isn't exposed to the query language, it's only used by filters built
+	// internally.
+	NotNull Operator = "notnull"
+)
+
+// Common operator sets for field definitions.
+var (
+	Equality = []Operator{Eq, Ne, In}
+	Ordered  = []Operator{Eq, Ne, In, Gt, Gte, Lt, Lte}
+	Text     = []Operator{Eq, Ne, In, Contains, Prefix}
+)
+
+// Field describes a field of a model that is exposed to the query language.
+type Field struct {
+	Column     string
+	Type       Type
+	Operators  []Operator
+	Sortable   bool
+	Searchable bool
+	// Condition builds the filter condition of fields that aren't a column of the
+	// model, like a membership in a join table. Fields without one are compared
+	// against their column.
+	Condition func(op Operator, value interface{}) clause.Expression
+}
+
+func (f Field) allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema is the allow-list of fields, keyed by their public name, that a model can be
+// filtered, sorted and searched by. Anything not in the schema is rejected.
+type Schema map[string]Field
+
+type Filter struct {
+	Field     string
+	Column    string
+	Operator  Operator
+	Value     interface{}
+	condition func(op Operator, value interface{}) clause.Expression
+}
+
+// Filter builds a filter on a field of the schema, for filters added internally
+// rather than parsed from the query language.
+func (s Schema) Filter(field string, op Operator, value interface{}) Filter {
+	return Filter{
+		Field:     field,
+		Column:    s[field].Column,
+		Operator:  op,
+		Value:     value,
+		condition: s[field].Condition,
+	}
+}
+
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Query is a parsed and validated set of filters, sort keys and a search term.
+type Query struct {
+	Filters      []Filter
+	Sorts        []Sort
+	Search       string
+	SearchFields []string
+}
+
+// Where returns a scope applying the filters and search term as parameterized conditions.
+func (q *Query) Where() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, filter := range q.Filters {
+			db = db.Where(filter.expression())
+		}
+		if q.Search != "" && len(q.SearchFields) > 0 {
+			var exprs []clause.Expression
+			for _, column := range q.SearchFields {
+				exprs = append(exprs, clause.Like{Column: columnOf(column), Value: "%" + escapeLike(q.Search) + "%"})
+			}
+			db = db.Where(Or(exprs...))
+		}
+		return db
+	}
+}
+
+// SortString returns the canonical representation of the sort keys, in the same
+// syntax accepted by Schema.ParseSort.
+func (q *Query) SortString() string {
+	var keys []string
+	for _, sort := range q.Sorts {
+		direction := "asc"
+		if sort.Desc {
+			direction = "desc"
+		}
+		keys = append(keys, fmt.Sprintf("%s.%s", sort.Field, direction))
+	}
+	return strings.Join(keys, ",")
+}
+
+func (f Filter) expression() clause.Expression {
+	if f.condition != nil {
+		return f.condition(f.Operator, f.Value)
+	}
+
+	column := columnOf(f.Column)
+	switch f.Operator {
+	case Ne:
+		return clause.Neq{Column: column, Value: f.Value}
+	case Gt:
+		return clause.Gt{Column: column, Value: f.Value}
+	case Gte:
+		return clause.Gte{Column: column, Value: f.Value}
+	case Lt:
+		return clause.Lt{Column: column, Value: f.Value}
+	case Lte:
+		return clause.Lte{Column: column, Value: f.Value}
+	case In:
+		return clause.IN{Column: column, Values: f.Value.([]interface{})}
+	case Contains:
+		return clause.Like{Column: column, Value: "%" + escapeLike(f.Value.(string)) + "%"}
+	case Prefix:
+		return clause.Like{Column: column, Value: escapeLike(f.Value.(string)) + "%"}
+	case NotNull:
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{column}}
+	}
+	return clause.Eq{Column: column, Value: f.Value}
+}
+
+// Or joins expressions with OR. A single expression is returned as is, as gorm would
+// otherwise OR it with the preceding conditions instead of AND.
+func Or(exprs ...clause.Expression) clause.Expression {
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return clause.Or(exprs...)
+}
+
+func columnOf(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+var testSchema = Schema{
+	"title":      {Column: "title", Type: String, Operators: Text, Sortable: true, Searchable: true},
+	"status":     {Column: "status", Type: String, Operators: Equality},
+	"views":      {Column: "views", Type: Integer, Operators: Ordered, Sortable: true},
+	"created_at": {Column: "created_at", Type: Time, Operators: Ordered, Sortable: true},
+	"content":    {Column: "content", Type: String, Operators: Text, Searchable: true},
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []Filter
+	}{
+		{name: "empty", raw: ""},
+		{name: "implicit equality", raw: "status:draft", want: []Filter{{Field: "status", Column: "status", Operator: Eq, Value: "draft"}}},
+		{name: "explicit operator", raw: "views:gte:10", want: []Filter{{Field: "views", Column: "views", Operator: Gte, Value: int64(10)}}},
+		{name: "in", raw: "status:in:draft|published", want: []Filter{{Field: "status", Column: "status", Operator: In, Value: []interface{}{"draft", "published"}}}},
+		{name: "date", raw: "created_at:lt:2024-01-02", want: []Filter{{Field: "created_at", Column: "created_at", Operator: Lt, Value: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}}},
+		{name: "value containing the separator", raw: "title:a:b", want: []Filter{{Field: "title", Column: "title", Operator: Eq, Value: "a:b"}}},
+		{name: "contains keeps the raw value", raw: "title:contains:50%", want: []Filter{{Field: "title", Column: "title", Operator: Contains, Value: "50%"}}},
+		{
+			name: "several",
+			raw:  "status:draft,views:lt:3",
+			want: []Filter{
+				{Field: "status", Column: "status", Operator: Eq, Value: "draft"},
+				{Field: "views", Column: "views", Operator: Lt, Value: int64(3)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := testSchema.ParseFilters(tt.raw)
+			if len(errs) > 0 {
+				t.Fatalf("ParseFilters() errors = %v", errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFiltersRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		message string
+	}{
+		{name: "malformed", raw: "status", message: "malformed filter, should be field:operator:value"},
+		{name: "unknown field", raw: "password:x", message: "unknown filter field password"},
+		{name: "operator not allowed on field", raw: "status:gt:draft", message: "operator gt is not allowed on field status"},
+		{name: "ordering on text", raw: "title:lte:a", message: "operator lte is not allowed on field title"},
+		{name: "internal operator", raw: "views:notnull:1", message: "notnull:1 is not an integer"},
+		{name: "not an integer", raw: "views:gt:ten", message: "ten is not an integer"},
+		{name: "not an integer in list", raw: "views:in:1|two", message: "two is not an integer"},
+		{name: "not a time", raw: "created_at:gt:yesterday", message: "yesterday is not a RFC 3339 timestamp or a date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := testSchema.ParseFilters(tt.raw)
+			if len(got) > 0 {
+				t.Errorf("ParseFilters() = %+v, want no filters", got)
+			}
+			if len(errs) != 1 || errs[0].Parameter != "filter" || errs[0].Value != tt.raw || errs[0].Message != tt.message {
+				t.Errorf("ParseFilters() errors = %+v, want %q", errs, tt.message)
+			}
+		})
+	}
+}
+
+func TestParseFiltersCollectsEveryError(t *testing.T) {
+	filters, errs := testSchema.ParseFilters("status:draft,password:x,views:gt:ten")
+	if len(filters) != 1 || len(errs) != 2 {
+		t.Errorf("ParseFilters() = %d filters and %d errors, want 1 and 2", len(filters), len(errs))
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []Sort
+		invalid []string
+	}{
+		{name: "default direction", raw: "title", want: []Sort{{Field: "title", Column: "title"}}},
+		{name: "descending", raw: "views.DESC", want: []Sort{{Field: "views", Column: "views", Desc: true}}},
+		{
+			name: "several",
+			raw:  "created_at.desc,title.asc",
+			want: []Sort{{Field: "created_at", Column: "created_at", Desc: true}, {Field: "title", Column: "title"}},
+		},
+		{name: "unknown field", raw: "password.asc", invalid: []string{"password.asc"}},
+		{name: "field not sortable", raw: "status", invalid: []string{"status"}},
+		{name: "bad direction", raw: "title.up", invalid: []string{"title.up"}},
+		{name: "valid and invalid", raw: "title,content.desc", want: []Sort{{Field: "title", Column: "title"}}, invalid: []string{"content.desc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := testSchema.ParseSort(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort() = %+v, want %+v", got, tt.want)
+			}
+			var invalid []string
+			for _, err := range errs {
+				invalid = append(invalid, err.Value)
+			}
+			if !reflect.DeepEqual(invalid, tt.invalid) {
+				t.Errorf("ParseSort() rejected %v, want %v", invalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestSortString(t *testing.T) {
+	sorts, _ := testSchema.ParseSort("created_at.desc,title")
+	q := &Query{Sorts: sorts}
+	if got, want := q.SortString(), "created_at.desc,title.asc"; got != want {
+		t.Errorf("SortString() = %q, want %q", got, want)
+	}
+}
+
+func TestSearchFields(t *testing.T) {
+	if got, want := testSchema.SearchFields(), []string{"content", "title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchFields() = %v, want %v", got, want)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "50%", want: `50\%`},
+		{value: "snake_case", want: `snake\_case`},
+		{value: `back\slash`, want: `back\\slash`},
+		{value: `\%_`, want: `\\\%\_`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := escapeLike(tt.value); got != tt.want {
+				t.Errorf("escapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterExpression(t *testing.T) {
+	title := columnOf("title")
+	tests := []struct {
+		name   string
+		filter Filter
+		want   clause.Expression
+	}{
+		{name: "equality", filter: testSchema.Filter("status", Eq, "draft"), want: clause.Eq{Column: columnOf("status"), Value: "draft"}},
+		{name: "contains escapes wildcards", filter: testSchema.Filter("title", Contains, "100%_done"), want: clause.Like{Column: title, Value: `%100\%\_done%`}},
+		{name: "prefix escapes wildcards", filter: testSchema.Filter("title", Prefix, "a_b"), want: clause.Like{Column: title, Value: `a\_b%`}},
+		{name: "in", filter: testSchema.Filter("status", In, []interface{}{"a", "b"}), want: clause.IN{Column: columnOf("status"), Values: []interface{}{"a", "b"}}},
+		{
+			name: "custom condition",
+			filter: Schema{"tag": {Condition: func(op Operator, value interface{}) clause.Expression {
+				return clause.Expr{SQL: "tag = ?", Vars: []interface{}{value}}
+			}}}.Filter("tag", Eq, "go"),
+			want: clause.Expr{SQL: "tag = ?", Vars: []interface{}{"go"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.expression(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expression() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	apikeysdto "github.com/pedromspeixoto/posts-api/internal/dto/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// lastUsedInterval bounds how often the last used timestamp of a key is written, so
+// busy clients don't turn every request into a write.
+const lastUsedInterval = time.Minute
+
+var ErrExpiryInPast = errors.New("expires_at must be in the future")
+
+// APIKeyService provides methods pertaining to managing and verifying API keys.
+type APIKeyService interface {
+	auth.APIKeyVerifier
+
+	// CreateAPIKey creates an API key owned by the calling principal
+	CreateAPIKey(ctx context.Context, request *apikeysdto.APIKeyRequest) (int, *apikeysdto.CreatedAPIKeyResponse, error)
+	// ListAPIKeys retrieves all API keys with pagination.
+	ListAPIKeys(ctx context.Context, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// RevokeAPIKey revokes an API key by uuid
+	RevokeAPIKey(ctx context.Context, uuid string) (int, *apikeysdto.APIKeyResponse, error)
+}
+
+type APIKeyServiceDeps struct {
+	fx.In
+
+	Config           *config.Config
+	Logger           *logger.LoggingClient
+	APIKeyRepository apikeys.APIKeyRepository
+}
+
+type apiKeyService struct {
+	APIKeyServiceDeps
+	logger.Logger
+}
+
+func NewAPIKeyService(deps APIKeyServiceDeps) APIKeyService {
+	return &apiKeyService{
+		APIKeyServiceDeps: deps,
+		Logger:            deps.Logger.GetLogger(),
+	}
+}
+
+func (a *apiKeyService) CreateAPIKey(ctx context.Context, request *apikeysdto.APIKeyRequest) (int, *apikeysdto.CreatedAPIKeyResponse, error) {
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return http.StatusBadRequest, nil, ErrExpiryInPast
+	}
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+
+	key, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error generating api key: %v", err)
+	}
+
+	model := apikeysdto.ModelFromAPIKeyRequest(request, key, hash, principal.Subject)
+	err = a.APIKeyRepository.Create(model)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error creating new api key: %v", err)
+	}
+
+	return http.StatusCreated, apikeysdto.NewCreatedAPIKeyResponse(model, key), nil
+}
+
+func (a *apiKeyService) ListAPIKeys(ctx context.Context, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	if paginationRequest.Cursor != "" {
+		return http.StatusBadRequest, nil, fmt.Errorf("api keys don't support cursors, use page instead")
+	}
+
+	keys, pageEnv, err := a.APIKeyRepository.List(dto.ModelFromPaginationRequest(paginationRequest))
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error fetching api keys: %v", err)
+	}
+
+	pageEnv.Data = apikeysdto.NewAPIKeyListResponse(keys)
+	return http.StatusOK, dto.NewPaginationResponse(pageEnv), nil
+}
+
+func (a *apiKeyService) RevokeAPIKey(ctx context.Context, uuid string) (int, *apikeysdto.APIKeyResponse, error) {
+	key, err := a.APIKeyRepository.GetByUUID(uuid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusNotFound, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching api key: %v", err)
+	}
+
+	if key.RevokedAt == nil {
+		err = a.APIKeyRepository.Revoke(key)
+		if err != nil {
+			return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error revoking api key: %v", err)
+		}
+	}
+
+	return http.StatusOK, apikeysdto.NewAPIKeyResponse(key), nil
+}
+
+// VerifyAPIKey resolves an API key to a principal acting on behalf of the key owner,
+// limited to the scopes of the key.
+func (a *apiKeyService) VerifyAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	model, err := a.APIKeyRepository.GetByHash(auth.HashToken(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("unexpected error fetching api key: %v", err)
+	}
+	if !model.Active(time.Now()) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	// failing to record the last use shouldn't fail the request
+	if err := a.APIKeyRepository.Touch(model, lastUsedInterval); err != nil {
+		a.Warningf("error recording last use of api key %s: %v", model.KeyId, err)
+	}
+
+	return &auth.Principal{
+		Subject: model.OwnerId,
+		Scopes:  model.ScopeList(),
+		KeyId:   model.KeyId,
+	}, nil
+}
@@ -0,0 +1,277 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	apikeysdto "github.com/pedromspeixoto/posts-api/internal/dto/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// fakeAPIKeys is an in memory APIKeyRepository.
+type fakeAPIKeys struct {
+	keys     []*apikeys.APIKey
+	revokes  int
+	touches  int
+	touchErr error
+}
+
+func (f *fakeAPIKeys) List(pagination *data.Pagination) ([]apikeys.APIKey, *data.Pagination, error) {
+	var keys []apikeys.APIKey
+	for _, key := range f.keys {
+		keys = append(keys, *key)
+	}
+	return keys, pagination, nil
+}
+
+func (f *fakeAPIKeys) GetByUUID(uuid string) (*apikeys.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyId == uuid {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAPIKeys) GetByHash(hash string) (*apikeys.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeAPIKeys) Create(key *apikeys.APIKey) error {
+	f.keys = append(f.keys, key)
+	return nil
+}
+
+func (f *fakeAPIKeys) Revoke(key *apikeys.APIKey) error {
+	f.revokes++
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
+
+func (f *fakeAPIKeys) Touch(key *apikeys.APIKey, interval time.Duration) error {
+	f.touches++
+	return f.touchErr
+}
+
+func newTestService(repository *fakeAPIKeys) *apiKeyService {
+	return &apiKeyService{
+		APIKeyServiceDeps: APIKeyServiceDeps{APIKeyRepository: repository},
+		Logger:            logger.NewStdoutLogger(logger.LoggingLevelNone),
+	}
+}
+
+func adminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "admin-1", Roles: []string{auth.RoleAdmin}})
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	repository := &fakeAPIKeys{}
+	service := newTestService(repository)
+	expiresAt := time.Now().Add(time.Hour)
+
+	status, resp, err := service.CreateAPIKey(adminContext(), &apikeysdto.APIKeyRequest{
+		Name:      "ci",
+		Scopes:    []string{auth.ScopePostsRead, auth.ScopePostsWrite},
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("CreateAPIKey() = %d, %v", status, err)
+	}
+
+	if !strings.HasPrefix(resp.Key, auth.APIKeyPrefix) || !strings.HasPrefix(resp.Key, resp.Prefix) || len(resp.Prefix) >= len(resp.Key) {
+		t.Errorf("CreateAPIKey() key = %s, prefix = %s, want a prefixed key shown along a shorter prefix", resp.Key, resp.Prefix)
+	}
+	if resp.OwnerId != "admin-1" {
+		t.Errorf("CreateAPIKey() owner = %s, want the caller", resp.OwnerId)
+	}
+	if len(repository.keys) != 1 {
+		t.Fatalf("stored %d keys, want 1", len(repository.keys))
+	}
+	stored := repository.keys[0]
+	if stored.KeyHash != auth.HashToken(resp.Key) {
+		t.Error("the key isn't stored by its hash")
+	}
+	if strings.Contains(stored.KeyHash+stored.Prefix+stored.Name, resp.Key) {
+		t.Error("the key is stored in clear")
+	}
+	if got := stored.ScopeList(); !reflect.DeepEqual(got, []string{auth.ScopePostsRead, auth.ScopePostsWrite}) {
+		t.Errorf("stored scopes = %v", got)
+	}
+}
+
+func TestCreateAPIKeyRejects(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		request    *apikeysdto.APIKeyRequest
+		wantStatus int
+	}{
+		{name: "expiry in the past", ctx: adminContext(), request: &apikeysdto.APIKeyRequest{Name: "ci", Scopes: []string{auth.ScopePostsRead}, ExpiresAt: &past}, wantStatus: http.StatusBadRequest},
+		{name: "anonymous", ctx: context.Background(), request: &apikeysdto.APIKeyRequest{Name: "ci", Scopes: []string{auth.ScopePostsRead}}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeAPIKeys{}
+			status, _, err := newTestService(repository).CreateAPIKey(tt.ctx, tt.request)
+			if status != tt.wantStatus || err == nil {
+				t.Errorf("CreateAPIKey() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if len(repository.keys) != 0 {
+				t.Error("rejected key was stored")
+			}
+		})
+	}
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	earlier, later := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	key := func(hash string, expiresAt, revokedAt *time.Time) *apikeys.APIKey {
+		model := &apikeys.APIKey{KeyId: "k-" + hash, KeyHash: auth.HashToken(hash), OwnerId: "admin-1", ExpiresAt: expiresAt, RevokedAt: revokedAt}
+		model.SetScopes([]string{auth.ScopePostsRead})
+		return model
+	}
+	repository := &fakeAPIKeys{keys: []*apikeys.APIKey{
+		key("pak_active", nil, nil),
+		key("pak_expiring", &later, nil),
+		key("pak_expired", &earlier, nil),
+		key("pak_revoked", nil, &earlier),
+	}}
+	service := newTestService(repository)
+
+	tests := []struct {
+		name string
+		key  string
+		want *auth.Principal
+	}{
+		{name: "active", key: "pak_active", want: &auth.Principal{Subject: "admin-1", Scopes: []string{auth.ScopePostsRead}, KeyId: "k-pak_active"}},
+		{name: "not expired yet", key: "pak_expiring", want: &auth.Principal{Subject: "admin-1", Scopes: []string{auth.ScopePostsRead}, KeyId: "k-pak_expiring"}},
+		{name: "expired", key: "pak_expired"},
+		{name: "revoked", key: "pak_revoked"},
+		{name: "unknown", key: "pak_unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			touches := repository.touches
+			got, err := service.VerifyAPIKey(context.Background(), tt.key)
+			if tt.want == nil {
+				if !errors.Is(err, auth.ErrInvalidAPIKey) {
+					t.Errorf("VerifyAPIKey() = %+v, %v, want %v", got, err, auth.ErrInvalidAPIKey)
+				}
+				if repository.touches != touches {
+					t.Error("rejected key was recorded as used")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAPIKey() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VerifyAPIKey() = %+v, want %+v", got, tt.want)
+			}
+			if repository.touches != touches+1 {
+				t.Error("key use wasn't recorded")
+			}
+		})
+	}
+}
+
+func TestVerifyAPIKeyIgnoresFailingTouch(t *testing.T) {
+	repository := &fakeAPIKeys{keys: []*apikeys.APIKey{{KeyId: "k1", KeyHash: auth.HashToken("pak_active")}}, touchErr: errors.New("lock wait timeout")}
+	if _, err := newTestService(repository).VerifyAPIKey(context.Background(), "pak_active"); err != nil {
+		t.Errorf("VerifyAPIKey() error = %v, want failing to record the use ignored", err)
+	}
+}
+
+func TestAPIKeyPrincipalsAreLimitedToTheirScopes(t *testing.T) {
+	// the key is owned by an admin, but only grants reading
+	admin := &apikeys.APIKey{KeyId: "k1", KeyHash: auth.HashToken("pak_read"), OwnerId: "admin-1"}
+	admin.SetScopes([]string{auth.ScopePostsRead})
+	elevated := &apikeys.APIKey{KeyId: "k2", KeyHash: auth.HashToken("pak_admin"), OwnerId: "admin-1"}
+	elevated.SetScopes([]string{auth.ScopePostsWrite, auth.ScopeAdmin})
+	service := newTestService(&fakeAPIKeys{keys: []*apikeys.APIKey{admin, elevated}})
+
+	principal, err := service.VerifyAPIKey(context.Background(), "pak_read")
+	if err != nil {
+		t.Fatalf("VerifyAPIKey() error = %v", err)
+	}
+	if !principal.Can(auth.ScopePostsRead) {
+		t.Error("key can't do what it was granted")
+	}
+	if principal.Can(auth.ScopePostsWrite) || principal.IsAdmin() || principal.HasRole(auth.RoleAdmin) {
+		t.Errorf("key principal %+v inherits the role of its owner", principal)
+	}
+
+	principal, err = service.VerifyAPIKey(context.Background(), "pak_admin")
+	if err != nil {
+		t.Fatalf("VerifyAPIKey() error = %v", err)
+	}
+	if !principal.IsAdmin() || principal.HasRole(auth.RoleAdmin) || principal.Can(auth.ScopePostsRead) {
+		t.Errorf("key principal %+v, want admin overrides without the admin role or ungranted scopes", principal)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	earlier := time.Now().Add(-time.Hour)
+	repository := &fakeAPIKeys{keys: []*apikeys.APIKey{
+		{KeyId: "active"},
+		{KeyId: "revoked", RevokedAt: &earlier},
+	}}
+	service := newTestService(repository)
+
+	tests := []struct {
+		name        string
+		keyId       string
+		wantStatus  int
+		wantRevokes int
+	}{
+		{name: "active", keyId: "active", wantStatus: http.StatusOK, wantRevokes: 1},
+		{name: "already revoked", keyId: "revoked", wantStatus: http.StatusOK},
+		{name: "unknown", keyId: "unknown", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revokes := repository.revokes
+			status, resp, err := service.RevokeAPIKey(adminContext(), tt.keyId)
+			if status != tt.wantStatus {
+				t.Fatalf("RevokeAPIKey() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if repository.revokes-revokes != tt.wantRevokes {
+				t.Errorf("revoked %d times, want %d", repository.revokes-revokes, tt.wantRevokes)
+			}
+			if status == http.StatusOK && resp.RevokedAt == nil {
+				t.Error("RevokeAPIKey() answered with a key that isn't revoked")
+			}
+		})
+	}
+	if !repository.keys[1].RevokedAt.Equal(earlier) {
+		t.Error("revoking again changed the revocation time")
+	}
+}
+
+func TestListAPIKeysRejectsCursors(t *testing.T) {
+	status, _, err := newTestService(&fakeAPIKeys{}).ListAPIKeys(adminContext(), &dto.PaginationRequest{Limit: 10, Cursor: "abc"})
+	if status != http.StatusBadRequest || err == nil {
+		t.Errorf("ListAPIKeys() = %d, %v, want %d", status, err, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,197 @@
+package comments
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/comments"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	commentsdto "github.com/pedromspeixoto/posts-api/internal/dto/comments"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// CommentService provides methods pertaining to managing the comments of posts.
+type CommentService interface {
+	// ListComments retrieves the comments of a post with offset or cursor pagination.
+	ListComments(ctx context.Context, postId string, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// CreateComment comments on a post, or replies to one of its comments
+	CreateComment(ctx context.Context, postId string, request *commentsdto.CommentRequest) (int, *commentsdto.CommentResponse, error)
+	// UpdateComment updates the content of a comment by uuid
+	UpdateComment(ctx context.Context, postId string, uuid string, request *commentsdto.CommentUpdateRequest) (int, *commentsdto.CommentResponse, error)
+	// DeleteComment deletes a comment by uuid
+	DeleteComment(ctx context.Context, postId string, uuid string) (int, *commentsdto.CommentResponse, error)
+}
+
+var (
+	ErrParentNotFound = errors.New("parent comment not found on this post")
+	ErrNotAuthor      = errors.New("only the author of a comment or an admin can modify it")
+)
+
+type CommentServiceDeps struct {
+	fx.In
+
+	Config            *config.Config
+	Logger            *logger.LoggingClient
+	CommentRepository comments.CommentRepository
+	PostService       posts.PostService
+}
+
+type commentService struct {
+	CommentServiceDeps
+	logger.Logger
+	cursorSecret []byte
+}
+
+func NewCommentService(deps CommentServiceDeps) (CommentService, error) {
+	service := &commentService{
+		CommentServiceDeps: deps,
+		Logger:             deps.Logger.GetLogger(),
+		cursorSecret:       []byte(deps.Config.PaginationCursorSecret),
+	}
+
+	// fall back to an ephemeral secret like posts do, cursors will not survive
+	// restarts or be shared between replicas
+	if len(service.cursorSecret) == 0 {
+		service.cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(service.cursorSecret); err != nil {
+			return nil, fmt.Errorf("error generating cursor secret: %v", err)
+		}
+	}
+
+	return service, nil
+}
+
+func (c *commentService) ListComments(ctx context.Context, postId string, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	pagination := dto.ModelFromPaginationRequest(paginationRequest)
+	if paginationRequest.Cursor != "" {
+		cursor, err := data.DecodeCursor(paginationRequest.Cursor, c.cursorSecret)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if err := pagination.SetCursor(cursor, comments.QuerySchema); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+	}
+
+	if statusCode, err := c.checkPost(ctx, postId); err != nil {
+		return statusCode, nil, err
+	}
+
+	list, pageEnv, err := c.CommentRepository.List(postId, pagination)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error fetching comments: %v", err)
+	}
+
+	pageEnv.Data = commentsdto.NewCommentListResponse(list)
+	response := dto.NewPaginationResponse(pageEnv)
+	if err := response.SetCursors(pageEnv, c.cursorSecret); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error encoding cursors: %v", err)
+	}
+	return http.StatusOK, response, nil
+}
+
+func (c *commentService) CreateComment(ctx context.Context, postId string, request *commentsdto.CommentRequest) (int, *commentsdto.CommentResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+
+	if statusCode, err := c.checkPost(ctx, postId); err != nil {
+		return statusCode, nil, err
+	}
+
+	// replies stay in the thread of the post they were made on
+	if request.ParentId != nil {
+		if _, err := c.CommentRepository.GetByUUID(postId, *request.ParentId); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return http.StatusBadRequest, nil, ErrParentNotFound
+			}
+			return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching parent comment: %v", err)
+		}
+	}
+
+	model := commentsdto.ModelFromCommentRequest(request, postId, principal.Subject)
+	err := c.CommentRepository.Create(model)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error creating new comment: %v", err)
+	}
+
+	return http.StatusCreated, commentsdto.NewCommentResponse(model), nil
+}
+
+func (c *commentService) UpdateComment(ctx context.Context, postId string, uuid string, request *commentsdto.CommentUpdateRequest) (int, *commentsdto.CommentResponse, error) {
+	comment, statusCode, err := c.getComment(ctx, postId, uuid)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	if !canModify(ctx, comment) {
+		return http.StatusForbidden, nil, ErrNotAuthor
+	}
+
+	comment.Content = request.Content
+	err = c.CommentRepository.Update(comment)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error updating comment: %v", err)
+	}
+
+	return http.StatusOK, commentsdto.NewCommentResponse(comment), nil
+}
+
+func (c *commentService) DeleteComment(ctx context.Context, postId string, uuid string) (int, *commentsdto.CommentResponse, error) {
+	comment, statusCode, err := c.getComment(ctx, postId, uuid)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	if !canModify(ctx, comment) {
+		return http.StatusForbidden, nil, ErrNotAuthor
+	}
+
+	err = c.CommentRepository.Delete(comment)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error deleting comment: %v", err)
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+// checkPost checks the post exists and the caller can see it, comments of posts
+// hidden from the caller are hidden as well.
+func (c *commentService) checkPost(ctx context.Context, postId string) (int, error) {
+	statusCode, _, err := c.PostService.GetPost(ctx, postId)
+	if err != nil {
+		return statusCode, err
+	}
+	return http.StatusOK, nil
+}
+
+// canModify reports whether the caller wrote the comment or is an admin.
+func canModify(ctx context.Context, comment *comments.Comment) bool {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	return ok && (principal.Subject == comment.AuthorId || principal.IsAdmin())
+}
+
+func (c *commentService) getComment(ctx context.Context, postId string, uuid string) (*comments.Comment, int, error) {
+	if statusCode, err := c.checkPost(ctx, postId); err != nil {
+		return nil, statusCode, err
+	}
+
+	comment, err := c.CommentRepository.GetByUUID(postId, uuid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, fmt.Errorf("unexpected error fetching comment: %v", err)
+	}
+	return comment, http.StatusOK, nil
+}
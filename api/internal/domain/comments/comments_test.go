@@ -0,0 +1,231 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/comments"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	commentsdto "github.com/pedromspeixoto/posts-api/internal/dto/comments"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// fakeCommentRepository is an in memory CommentRepository. It records the pagination
+// it was last asked to list with and continues every page with a cursor.
+type fakeCommentRepository struct {
+	stored     []*comments.Comment
+	pagination *data.Pagination
+}
+
+func (f *fakeCommentRepository) List(postId string, pagination *data.Pagination) ([]comments.Comment, *data.Pagination, error) {
+	f.pagination = pagination
+	var list []comments.Comment
+	for _, comment := range f.stored {
+		if comment.PostId == postId {
+			list = append(list, *comment)
+		}
+	}
+	pagination.NextCursor = &data.Cursor{Sort: "created_at.asc", Values: []data.CursorValue{{Value: "2024-03-01T12:00:00Z", IsTime: true}}, ID: 2}
+	return list, pagination, nil
+}
+
+func (f *fakeCommentRepository) GetByUUID(postId string, uuid string) (*comments.Comment, error) {
+	for _, comment := range f.stored {
+		if comment.PostId == postId && comment.CommentId == uuid {
+			return comment, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeCommentRepository) Create(comment *comments.Comment) error {
+	f.stored = append(f.stored, comment)
+	return nil
+}
+
+func (f *fakeCommentRepository) Update(comment *comments.Comment) error {
+	return nil
+}
+
+func (f *fakeCommentRepository) Delete(comment *comments.Comment) error {
+	for i, stored := range f.stored {
+		if stored == comment {
+			f.stored = append(f.stored[:i], f.stored[i+1:]...)
+		}
+	}
+	return nil
+}
+
+// fakePostService finds the posts it's given. Other calls panic.
+type fakePostService struct {
+	posts.PostService
+
+	postIds []string
+}
+
+func (s *fakePostService) GetPost(ctx context.Context, uuid string) (int, *postsdto.PostResponse, error) {
+	for _, postId := range s.postIds {
+		if postId == uuid {
+			return http.StatusOK, &postsdto.PostResponse{PostId: postId}, nil
+		}
+	}
+	return http.StatusNotFound, nil, gorm.ErrRecordNotFound
+}
+
+func newTestService(repository *fakeCommentRepository) *commentService {
+	return &commentService{
+		CommentServiceDeps: CommentServiceDeps{
+			Config:            &config.Config{},
+			CommentRepository: repository,
+			PostService:       &fakePostService{postIds: []string{"p1", "p2"}},
+		},
+		Logger:       logger.NewStdoutLogger(logger.LoggingLevelNone),
+		cursorSecret: []byte("secret"),
+	}
+}
+
+func withPrincipal(principal *auth.Principal) context.Context {
+	return auth.WithPrincipal(context.Background(), principal)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestCreateComment(t *testing.T) {
+	author := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleViewer}})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		postId     string
+		parentId   *string
+		wantStatus int
+		wantErr    error
+	}{
+		{name: "top level comment", ctx: author, postId: "p1", wantStatus: http.StatusCreated},
+		{name: "reply", ctx: author, postId: "p1", parentId: stringPtr("c1"), wantStatus: http.StatusCreated},
+		{name: "reply to a reply", ctx: author, postId: "p1", parentId: stringPtr("c2"), wantStatus: http.StatusCreated},
+		{name: "reply to a comment on another post", ctx: author, postId: "p2", parentId: stringPtr("c1"), wantStatus: http.StatusBadRequest, wantErr: ErrParentNotFound},
+		{name: "reply to a missing comment", ctx: author, postId: "p1", parentId: stringPtr("c9"), wantStatus: http.StatusBadRequest, wantErr: ErrParentNotFound},
+		{name: "missing post", ctx: author, postId: "p9", wantStatus: http.StatusNotFound},
+		{name: "anonymous", ctx: context.Background(), postId: "p1", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeCommentRepository{stored: []*comments.Comment{
+				{CommentId: "c1", PostId: "p1", AuthorId: "u2"},
+				{CommentId: "c2", PostId: "p1", ParentId: stringPtr("c1"), AuthorId: "u2"},
+			}}
+
+			status, resp, err := newTestService(repository).CreateComment(tt.ctx, tt.postId, &commentsdto.CommentRequest{Content: "hi", ParentId: tt.parentId})
+			if status != tt.wantStatus || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("CreateComment() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if status != http.StatusCreated {
+				if len(repository.stored) != 2 {
+					t.Error("rejected comment was stored")
+				}
+				return
+			}
+			if resp.PostId != tt.postId || resp.Author == nil || resp.Author.Id != "u1" {
+				t.Errorf("CreateComment() = %+v, want a comment by the caller on %s", resp, tt.postId)
+			}
+			if (resp.ParentId == nil) != (tt.parentId == nil) || (resp.ParentId != nil && *resp.ParentId != *tt.parentId) {
+				t.Errorf("CreateComment() parent = %v, want %v", resp.ParentId, tt.parentId)
+			}
+		})
+	}
+}
+
+func TestModifyComment(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{name: "author", principal: &auth.Principal{Subject: "u1", Roles: []string{auth.RoleViewer}}, wantStatus: http.StatusOK},
+		{name: "admin", principal: &auth.Principal{Subject: "u2", Roles: []string{auth.RoleAdmin}}, wantStatus: http.StatusOK},
+		{name: "someone else", principal: &auth.Principal{Subject: "u2", Roles: []string{auth.RoleEditor}}, wantStatus: http.StatusForbidden},
+		{name: "api key of an admin without the admin scope", principal: &auth.Principal{Subject: "u2", KeyId: "k1", Scopes: []string{auth.ScopeCommentsWrite}}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeCommentRepository{stored: []*comments.Comment{{CommentId: "c1", PostId: "p1", AuthorId: "u1", Content: "hi"}}}
+			service := newTestService(repository)
+
+			status, _, err := service.UpdateComment(withPrincipal(tt.principal), "p1", "c1", &commentsdto.CommentUpdateRequest{Content: "hello"})
+			if status != tt.wantStatus {
+				t.Errorf("UpdateComment() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if edited := repository.stored[0].Content == "hello"; edited != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("comment edited = %v, want %v", edited, !edited)
+			}
+
+			status, _, err = service.DeleteComment(withPrincipal(tt.principal), "p1", "c1")
+			if status != tt.wantStatus {
+				t.Errorf("DeleteComment() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if deleted := len(repository.stored) == 0; deleted != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("comment deleted = %v, want %v", deleted, !deleted)
+			}
+		})
+	}
+}
+
+func TestListComments(t *testing.T) {
+	repository := &fakeCommentRepository{stored: []*comments.Comment{
+		{CommentId: "c1", PostId: "p1"},
+		{CommentId: "c2", PostId: "p1", ParentId: stringPtr("c1")},
+		{CommentId: "c3", PostId: "p2"},
+	}}
+	service := newTestService(repository)
+
+	status, resp, err := service.ListComments(context.Background(), "p1", &dto.PaginationRequest{Limit: 2})
+	if status != http.StatusOK {
+		t.Fatalf("ListComments() = %d, %v", status, err)
+	}
+	if list := resp.Data.(*commentsdto.CommentListResponse).Comments; len(list) != 2 || list[1].ParentId == nil || *list[1].ParentId != "c1" {
+		t.Errorf("ListComments() = %+v, want the thread of p1", list)
+	}
+	if resp.NextCursor == "" {
+		t.Fatal("ListComments() issued no cursor for the next page")
+	}
+
+	// the cursor continues the listing
+	status, _, err = service.ListComments(context.Background(), "p1", &dto.PaginationRequest{Limit: 2, Cursor: resp.NextCursor})
+	if status != http.StatusOK {
+		t.Fatalf("ListComments() with a cursor = %d, %v", status, err)
+	}
+	if repository.pagination.Cursor == nil || repository.pagination.Cursor.ID != 2 {
+		t.Errorf("listed from cursor %+v, want the one issued", repository.pagination.Cursor)
+	}
+
+	tests := []struct {
+		name   string
+		postId string
+		cursor string
+		want   int
+	}{
+		{name: "forged cursor", postId: "p1", cursor: resp.NextCursor + "x", want: http.StatusBadRequest},
+		{name: "missing post", postId: "p9", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, err := service.ListComments(context.Background(), tt.postId, &dto.PaginationRequest{Limit: 2, Cursor: tt.cursor})
+			if status != tt.want || err == nil {
+				t.Errorf("ListComments() = %d, %v, want %d", status, err, tt.want)
+			}
+		})
+	}
+}
@@ -3,13 +3,23 @@ package domain
 import (
 	"go.uber.org/fx"
 
+	"github.com/pedromspeixoto/posts-api/internal/domain/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/domain/comments"
 	"github.com/pedromspeixoto/posts-api/internal/domain/health"
+	"github.com/pedromspeixoto/posts-api/internal/domain/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/domain/media"
 	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/domain/users"
 )
 
 func ProvideDomains() fx.Option {
 	return fx.Provide(
 		health.NewHealthService,
 		posts.NewPostService,
+		users.NewUserService,
+		apikeys.NewAPIKeyService,
+		comments.NewCommentService,
+		media.NewMediaService,
+		jobs.NewJobService,
 	)
 }
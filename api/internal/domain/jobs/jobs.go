@@ -0,0 +1,323 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/users"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	jobsdto "github.com/pedromspeixoto/posts-api/internal/dto/jobs"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/storage"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// JobService provides methods pertaining to background jobs submitted through the API.
+type JobService interface {
+	// SubmitImport stores an import of posts and queues a job to run it
+	SubmitImport(ctx context.Context, request *postsdto.ImportRequest, content io.Reader) (int, *jobsdto.JobResponse, error)
+	// GetJob retrieves a job by uuid, only whoever submitted it and admins can see it
+	GetJob(ctx context.Context, uuid string) (int, *jobsdto.JobResponse, error)
+	// RunQueuedJobs runs queued jobs one at a time until none is left and returns how
+	// many it ran. Jobs left running by replicas that stopped are failed first
+	RunQueuedJobs(ctx context.Context) (int, error)
+}
+
+// jobLease is how long a running job can go without its lease being renewed before
+// it's considered abandoned by the replica running it. Leases are renewed a few times
+// per lease for as long as jobs run.
+const (
+	jobLease      = 15 * time.Minute
+	jobLeaseRenew = jobLease / 3
+)
+
+// staleJobsLimit bounds how many abandoned jobs are failed at a time.
+const staleJobsLimit = 100
+
+var (
+	ErrImportTooLarge  = errors.New("import is too large")
+	ErrJobInterrupted  = errors.New("job was interrupted")
+	ErrJobsUnavailable = errors.New("background jobs need a blob store shared by every replica")
+	ErrOwnerRevoked    = errors.New("the api key the job was submitted with was revoked or has expired")
+)
+
+type JobServiceDeps struct {
+	fx.In
+
+	Config        *config.Config
+	Logger        *logger.LoggingClient
+	JobRepository jobs.JobRepository
+	BlobStore     storage.BlobStore
+	PostService   posts.PostService
+	// jobs run on behalf of their owner as they are when the job runs
+	APIKeyRepository apikeys.APIKeyRepository
+	UserRepository   users.UserRepository
+}
+
+type jobService struct {
+	JobServiceDeps
+	logger.Logger
+}
+
+func NewJobService(deps JobServiceDeps) JobService {
+	return &jobService{
+		JobServiceDeps: deps,
+		Logger:         deps.Logger.GetLogger(),
+	}
+}
+
+func (j *jobService) SubmitImport(ctx context.Context, request *postsdto.ImportRequest, content io.Reader) (int, *jobsdto.JobResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+	// jobs run on whichever replica claims them first, which has to find their input
+	if !j.BlobStore.Shared() {
+		return http.StatusServiceUnavailable, nil, ErrJobsUnavailable
+	}
+
+	job := &jobs.Job{
+		JobId:  uuid.GenerateUUID(),
+		Kind:   jobs.KindPostsImport,
+		Status: jobs.StatusQueued,
+		Owner: jobs.Owner{
+			Subject: principal.Subject,
+			Roles:   principal.Roles,
+			Scopes:  principal.Scopes,
+			KeyId:   principal.KeyId,
+		},
+		Params: map[string]string{
+			"format":      request.Format,
+			"dry_run":     strconv.FormatBool(request.DryRun),
+			"on_conflict": request.OnConflict,
+		},
+	}
+	job.InputKey = "imports/" + job.JobId
+
+	// read one byte past the limit to tell imports at the limit from imports over it
+	limited := &io.LimitedReader{R: content, N: j.Config.ImportMaxSize + 1}
+	if err := j.BlobStore.Put(ctx, job.InputKey, limited); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error storing import: %v", err)
+	}
+	if limited.N == 0 {
+		j.deleteBlob(ctx, job.InputKey)
+		return http.StatusRequestEntityTooLarge, nil, fmt.Errorf("%w, the limit is %d bytes", ErrImportTooLarge, j.Config.ImportMaxSize)
+	}
+
+	if err := j.JobRepository.Create(job); err != nil {
+		j.deleteBlob(ctx, job.InputKey)
+		return http.StatusInternalServerError, nil, fmt.Errorf("error creating job: %v", err)
+	}
+
+	return http.StatusAccepted, jobsdto.NewJobResponse(job), nil
+}
+
+func (j *jobService) GetJob(ctx context.Context, uuid string) (int, *jobsdto.JobResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+
+	job, err := j.JobRepository.GetByUUID(uuid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusNotFound, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching job: %v", err)
+	}
+
+	// the jobs of others don't exist as far as callers are concerned
+	if principal.Subject != job.Owner.Subject && !principal.IsAdmin() {
+		return http.StatusNotFound, nil, gorm.ErrRecordNotFound
+	}
+
+	return http.StatusOK, jobsdto.NewJobResponse(job), nil
+}
+
+func (j *jobService) RunQueuedJobs(ctx context.Context) (int, error) {
+	before := time.Now().Add(-jobLease)
+	stale, err := j.JobRepository.ListStale(before, staleJobsLimit)
+	if err != nil {
+		return 0, fmt.Errorf("error listing stale jobs: %w", err)
+	}
+	for i := range stale {
+		// the lease may have been renewed since the job was listed
+		abandoned, err := j.JobRepository.Abandon(&stale[i], before, ErrJobInterrupted.Error())
+		if err != nil {
+			return 0, fmt.Errorf("error failing stale job: %w", err)
+		}
+		if abandoned {
+			j.Warningf("job %s wasn't renewed for %s, failed it", stale[i].JobId, jobLease)
+			j.deleteBlob(ctx, stale[i].InputKey)
+		}
+	}
+
+	ran := 0
+	for ctx.Err() == nil {
+		job, err := j.JobRepository.ClaimNext(time.Now())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return ran, fmt.Errorf("error claiming job: %w", err)
+		}
+
+		stop := j.renew(ctx, job.ID)
+		result, err := j.run(ctx, job)
+		stop()
+		j.finish(ctx, job, result, err)
+		ran++
+	}
+	return ran, nil
+}
+
+// renew renews the lease on a running job until the returned function is called.
+func (j *jobService) renew(ctx context.Context, id uint) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(jobLeaseRenew)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := j.JobRepository.Renew(id, now); err != nil {
+					j.Warningf("error renewing lease on job %d: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// run runs a job on behalf of its owner and returns its result.
+func (j *jobService) run(ctx context.Context, job *jobs.Job) (interface{}, error) {
+	principal, err := j.principalOf(job.Owner)
+	if err != nil {
+		return nil, err
+	}
+	ctx = auth.WithPrincipal(ctx, principal)
+
+	switch job.Kind {
+	case jobs.KindPostsImport:
+		return j.runImport(ctx, job)
+	}
+	return nil, fmt.Errorf("unknown job kind %s", job.Kind)
+}
+
+func (j *jobService) runImport(ctx context.Context, job *jobs.Job) (interface{}, error) {
+	content, err := j.BlobStore.Open(ctx, job.InputKey)
+	if err != nil {
+		return nil, fmt.Errorf("error opening import: %v", err)
+	}
+	defer content.Close()
+
+	dryRun, _ := strconv.ParseBool(job.Params["dry_run"])
+	request := &postsdto.ImportRequest{
+		Format:     job.Params["format"],
+		DryRun:     dryRun,
+		OnConflict: job.Params["on_conflict"],
+	}
+
+	// progress keeps the job from being taken for abandoned
+	progress := func(rows int) {
+		job.Processed = rows
+		if err := j.JobRepository.Update(job); err != nil {
+			j.Warningf("error recording progress of job %s: %v", job.JobId, err)
+		}
+	}
+
+	_, report, err := j.PostService.ImportPosts(ctx, request, content, progress)
+	if report != nil {
+		job.Processed = report.Rows
+	}
+	return report, err
+}
+
+// principalOf resolves the owner of a job to a principal as the owner stands now, so
+// jobs don't outlive revoked API keys or keep roles their owner lost. Owners that
+// aren't local users, authenticated by an external issuer, keep the roles they had.
+func (j *jobService) principalOf(owner jobs.Owner) (*auth.Principal, error) {
+	if owner.KeyId != "" {
+		key, err := j.APIKeyRepository.GetByUUID(owner.KeyId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOwnerRevoked
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error fetching api key: %v", err)
+		}
+		if !key.Active(time.Now()) {
+			return nil, ErrOwnerRevoked
+		}
+		return &auth.Principal{Subject: key.OwnerId, Scopes: key.ScopeList(), KeyId: key.KeyId}, nil
+	}
+
+	user, err := j.UserRepository.GetByUUID(owner.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &auth.Principal{Subject: owner.Subject, Roles: owner.Roles}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %v", err)
+	}
+	return &auth.Principal{Subject: user.UserId, Roles: []string{user.Role}}, nil
+}
+
+// finish records the outcome of a job and deletes its input, which is no longer needed,
+// unless the job was failed as abandoned in the meantime.
+func (j *jobService) finish(ctx context.Context, job *jobs.Job, result interface{}, err error) {
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = jobs.StatusSucceeded
+	if err != nil {
+		job.Status = jobs.StatusFailed
+		job.Error = err.Error()
+		j.Errorf("job %s failed: %v", job.JobId, err)
+	}
+	if result != nil {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			j.Errorf("error encoding result of job %s: %v", job.JobId, err)
+		} else {
+			job.Result = string(encoded)
+		}
+	}
+
+	if err := j.JobRepository.Update(job); err != nil {
+		// whoever failed the job as abandoned deleted its input already
+		if errors.Is(err, jobs.ErrNotRunning) {
+			j.Warningf("job %s was failed as abandoned before it finished, its outcome is dropped", job.JobId)
+			return
+		}
+		j.Errorf("error recording outcome of job %s: %v", job.JobId, err)
+	}
+	if job.InputKey != "" {
+		j.deleteBlob(ctx, job.InputKey)
+	}
+}
+
+// deleteBlob deletes the input of a job. Failures only leave an orphaned blob behind,
+// so they're logged rather than returned.
+func (j *jobService) deleteBlob(ctx context.Context, key string) {
+	if err := j.BlobStore.Delete(ctx, key); err != nil {
+		j.Errorf("error deleting blob %s: %v", key, err)
+	}
+}
@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/users"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/storage"
+	"gorm.io/gorm"
+)
+
+// fakeJobRepository is an in memory JobRepository. Calls it doesn't implement panic.
+type fakeJobRepository struct {
+	jobs.JobRepository
+
+	stale    []jobs.Job
+	queued   []*jobs.Job
+	staleErr error
+	claimErr error
+	// renewed lists the stale jobs renewed since they were listed
+	renewed   map[uint]bool
+	updated   []jobs.Job
+	updateErr error
+}
+
+func (f *fakeJobRepository) ListStale(before time.Time, limit int) ([]jobs.Job, error) {
+	return f.stale, f.staleErr
+}
+
+func (f *fakeJobRepository) ClaimNext(now time.Time) (*jobs.Job, error) {
+	if f.claimErr != nil {
+		return nil, f.claimErr
+	}
+	if len(f.queued) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	job := f.queued[0]
+	f.queued = f.queued[1:]
+	job.Status = jobs.StatusRunning
+	return job, nil
+}
+
+func (f *fakeJobRepository) Abandon(job *jobs.Job, before time.Time, reason string) (bool, error) {
+	if f.renewed[job.ID] {
+		return false, nil
+	}
+	job.Status, job.Error = jobs.StatusFailed, reason
+	return true, nil
+}
+
+func (f *fakeJobRepository) Update(job *jobs.Job) error {
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.updated = append(f.updated, *job)
+	return nil
+}
+
+// fakeAPIKeyRepository finds the keys it's given. Other calls panic.
+type fakeAPIKeyRepository struct {
+	apikeys.APIKeyRepository
+
+	keys []*apikeys.APIKey
+}
+
+func (f *fakeAPIKeyRepository) GetByUUID(uuid string) (*apikeys.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyId == uuid {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeUserRepository finds the users it's given. Other calls panic.
+type fakeUserRepository struct {
+	users.UserRepository
+
+	users []*users.User
+}
+
+func (f *fakeUserRepository) GetByUUID(uuid string) (*users.User, error) {
+	for _, user := range f.users {
+		if user.UserId == uuid {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeBlobStore records the blobs deleted. Other calls panic.
+type fakeBlobStore struct {
+	storage.BlobStore
+
+	deleted []string
+}
+
+func (f *fakeBlobStore) Delete(ctx context.Context, key string) error {
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func newTestService(repository *fakeJobRepository) *jobService {
+	earlier := time.Now().Add(-time.Hour)
+	revoked := &apikeys.APIKey{KeyId: "k-revoked", OwnerId: "u1", RevokedAt: &earlier}
+	expired := &apikeys.APIKey{KeyId: "k-expired", OwnerId: "u1", ExpiresAt: &earlier}
+	active := &apikeys.APIKey{KeyId: "k-active", OwnerId: "u1"}
+	active.SetScopes([]string{auth.ScopePostsWrite})
+
+	return &jobService{
+		JobServiceDeps: JobServiceDeps{
+			Config:           &config.Config{},
+			JobRepository:    repository,
+			BlobStore:        &fakeBlobStore{},
+			APIKeyRepository: &fakeAPIKeyRepository{keys: []*apikeys.APIKey{revoked, expired, active}},
+			UserRepository:   &fakeUserRepository{users: []*users.User{{UserId: "u1", Role: auth.RoleViewer}}},
+		},
+		Logger: logger.NewStdoutLogger(logger.LoggingLevelNone),
+	}
+}
+
+func TestRunQueuedJobsKeepsErrors(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	tests := []struct {
+		name       string
+		repository *fakeJobRepository
+	}{
+		{name: "listing stale jobs", repository: &fakeJobRepository{staleErr: deadlock}},
+		{name: "claiming a job", repository: &fakeJobRepository{claimErr: deadlock}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the scheduler retries deadlocks, it has to be able to tell them apart
+			_, err := newTestService(tt.repository).RunQueuedJobs(context.Background())
+			var mysqlErr *mysql.MySQLError
+			if !errors.As(err, &mysqlErr) || mysqlErr.Number != deadlock.Number {
+				t.Errorf("RunQueuedJobs() error = %v, want it to wrap %v", err, deadlock)
+			}
+		})
+	}
+}
+
+func TestPrincipalOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   jobs.Owner
+		want    *auth.Principal
+		wantErr error
+	}{
+		{name: "active api key", owner: jobs.Owner{Subject: "u1", KeyId: "k-active", Scopes: []string{auth.ScopeAdmin}}, want: &auth.Principal{Subject: "u1", Scopes: []string{auth.ScopePostsWrite}, KeyId: "k-active"}},
+		{name: "revoked api key", owner: jobs.Owner{Subject: "u1", KeyId: "k-revoked"}, wantErr: ErrOwnerRevoked},
+		{name: "expired api key", owner: jobs.Owner{Subject: "u1", KeyId: "k-expired"}, wantErr: ErrOwnerRevoked},
+		{name: "deleted api key", owner: jobs.Owner{Subject: "u1", KeyId: "k-deleted"}, wantErr: ErrOwnerRevoked},
+		{name: "local user with their current role", owner: jobs.Owner{Subject: "u1", Roles: []string{auth.RoleAdmin}}, want: &auth.Principal{Subject: "u1", Roles: []string{auth.RoleViewer}}},
+		{name: "externally authenticated user", owner: jobs.Owner{Subject: "ext-1", Roles: []string{auth.RoleEditor}}, want: &auth.Principal{Subject: "ext-1", Roles: []string{auth.RoleEditor}}},
+	}
+
+	service := newTestService(&fakeJobRepository{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.principalOf(tt.owner)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("principalOf() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("principalOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunQueuedJobs(t *testing.T) {
+	repository := &fakeJobRepository{
+		stale: []jobs.Job{
+			{ID: 1, JobId: "j-stale", Status: jobs.StatusRunning, InputKey: "imports/j-stale"},
+			{ID: 2, JobId: "j-renewed", Status: jobs.StatusRunning, InputKey: "imports/j-renewed"},
+		},
+		renewed: map[uint]bool{2: true},
+		queued: []*jobs.Job{
+			{ID: 3, JobId: "j-revoked", Kind: jobs.KindPostsImport, Status: jobs.StatusQueued, Owner: jobs.Owner{Subject: "u1", KeyId: "k-revoked"}, InputKey: "imports/j-revoked"},
+			{ID: 4, JobId: "j-unknown", Kind: "reindex", Status: jobs.StatusQueued, Owner: jobs.Owner{Subject: "u1"}},
+		},
+	}
+	service := newTestService(repository)
+
+	ran, err := service.RunQueuedJobs(context.Background())
+	if err != nil || ran != 2 {
+		t.Fatalf("RunQueuedJobs() = %d, %v, want 2 jobs run", ran, err)
+	}
+	if repository.stale[0].Status != jobs.StatusFailed || repository.stale[1].Status != jobs.StatusRunning {
+		t.Errorf("stale jobs = %s, %s, want only the one not renewed failed", repository.stale[0].Status, repository.stale[1].Status)
+	}
+	if len(repository.updated) != 2 {
+		t.Fatalf("recorded %d outcomes, want 2", len(repository.updated))
+	}
+	for _, job := range repository.updated {
+		if job.Status != jobs.StatusFailed || job.FinishedAt == nil {
+			t.Errorf("job %s = %s, want it failed", job.JobId, job.Status)
+		}
+	}
+	if repository.updated[0].Error != ErrOwnerRevoked.Error() {
+		t.Errorf("job of a revoked key failed with %q, want %q", repository.updated[0].Error, ErrOwnerRevoked)
+	}
+	if deleted := service.BlobStore.(*fakeBlobStore).deleted; !reflect.DeepEqual(deleted, []string{"imports/j-stale", "imports/j-revoked"}) {
+		t.Errorf("deleted blobs %v, want the inputs of the abandoned and finished jobs", deleted)
+	}
+}
+
+func TestFinishAbandonedJob(t *testing.T) {
+	// another replica failed the job as abandoned and deleted its input
+	repository := &fakeJobRepository{updateErr: jobs.ErrNotRunning}
+	service := newTestService(repository)
+
+	service.finish(context.Background(), &jobs.Job{ID: 3, JobId: "j1", Status: jobs.StatusRunning, InputKey: "imports/j1"}, nil, nil)
+	if deleted := service.BlobStore.(*fakeBlobStore).deleted; len(deleted) != 0 {
+		t.Errorf("deleted blobs %v of a job that was abandoned", deleted)
+	}
+}
@@ -0,0 +1,182 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/media"
+	mediadto "github.com/pedromspeixoto/posts-api/internal/dto/media"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/storage"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// MediaService provides methods pertaining to managing uploaded media.
+type MediaService interface {
+	// UploadMedia stores an uploaded file, whose type is sniffed from its content
+	UploadMedia(ctx context.Context, filename string, content io.Reader) (int, *mediadto.MediaResponse, error)
+	// OpenMedia opens the content of media by uuid for download
+	OpenMedia(ctx context.Context, uuid string) (int, *mediadto.MediaContent, error)
+}
+
+const (
+	// sniffLength is how much content is read to detect its type, all that
+	// http.DetectContentType looks at.
+	sniffLength = 512
+	// filenameMaxLength is the maximum length, in runes, of stored filenames.
+	filenameMaxLength = 255
+)
+
+var (
+	ErrEmptyMedia           = errors.New("media is empty")
+	ErrMediaTooLarge        = errors.New("media is too large")
+	ErrUnsupportedMediaType = errors.New("media type is not allowed")
+)
+
+type MediaServiceDeps struct {
+	fx.In
+
+	Config          *config.Config
+	Logger          *logger.LoggingClient
+	MediaRepository media.MediaRepository
+	BlobStore       storage.BlobStore
+}
+
+type mediaService struct {
+	MediaServiceDeps
+	logger.Logger
+	allowedTypes map[string]bool
+}
+
+func NewMediaService(deps MediaServiceDeps) MediaService {
+	allowedTypes := map[string]bool{}
+	for _, contentType := range deps.Config.MediaAllowedTypes {
+		allowedTypes[strings.ToLower(strings.TrimSpace(contentType))] = true
+	}
+	return &mediaService{
+		MediaServiceDeps: deps,
+		Logger:           deps.Logger.GetLogger(),
+		allowedTypes:     allowedTypes,
+	}
+}
+
+func (m *mediaService) UploadMedia(ctx context.Context, filename string, content io.Reader) (int, *mediadto.MediaResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+
+	// the declared type of uploads can't be trusted, the content decides
+	buffered := bufio.NewReaderSize(content, sniffLength)
+	head, err := buffered.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return http.StatusBadRequest, nil, fmt.Errorf("error reading media: %v", err)
+	}
+	if len(head) == 0 {
+		return http.StatusBadRequest, nil, ErrEmptyMedia
+	}
+	contentType := sniff(head)
+	if !m.allowedTypes[contentType] {
+		return http.StatusUnsupportedMediaType, nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+
+	model := &media.Media{
+		MediaId:     uuid.GenerateUUID(),
+		OwnerId:     principal.Subject,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+	}
+	model.StorageKey = storageKey(model.MediaId)
+
+	// read one byte past the limit to tell content at the limit from content over it
+	limited := &io.LimitedReader{R: buffered, N: m.Config.MediaMaxSize + 1}
+	hash := sha256.New()
+	if err := m.BlobStore.Put(ctx, model.StorageKey, io.TeeReader(limited, hash)); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error storing media: %v", err)
+	}
+	if limited.N == 0 {
+		m.deleteBlob(ctx, model.StorageKey)
+		return http.StatusRequestEntityTooLarge, nil, fmt.Errorf("%w, the limit is %d bytes", ErrMediaTooLarge, m.Config.MediaMaxSize)
+	}
+	model.Size = m.Config.MediaMaxSize + 1 - limited.N
+	model.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := m.MediaRepository.Create(model); err != nil {
+		m.deleteBlob(ctx, model.StorageKey)
+		return http.StatusInternalServerError, nil, fmt.Errorf("error creating media: %v", err)
+	}
+
+	return http.StatusCreated, mediadto.NewMediaResponse(model), nil
+}
+
+func (m *mediaService) OpenMedia(ctx context.Context, uuid string) (int, *mediadto.MediaContent, error) {
+	model, err := m.MediaRepository.GetByUUID(uuid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusNotFound, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching media: %v", err)
+	}
+
+	content, err := m.BlobStore.Open(ctx, model.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			m.Errorf("content of media %s is missing from the blob store", model.MediaId)
+			return http.StatusNotFound, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("error opening media: %v", err)
+	}
+
+	return http.StatusOK, &mediadto.MediaContent{
+		MediaResponse: *mediadto.NewMediaResponse(model),
+		Content:       content,
+	}, nil
+}
+
+// deleteBlob deletes the blob of an upload that didn't make it. Failures only leave
+// an orphaned blob behind, so they're logged rather than returned.
+func (m *mediaService) deleteBlob(ctx context.Context, key string) {
+	if err := m.BlobStore.Delete(ctx, key); err != nil {
+		m.Errorf("error deleting blob %s: %v", key, err)
+	}
+}
+
+// sniff detects the media type of content from its first bytes, without parameters.
+func sniff(head []byte) string {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// storageKey spreads blobs over directories named after the first characters of
+// their media id, so no directory grows too large.
+func storageKey(mediaId string) string {
+	return mediaId[:2] + "/" + mediaId
+}
+
+// cleanFilename keeps the base name of an uploaded file, cut to the maximum length.
+func cleanFilename(filename string) string {
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+	filename = strings.TrimSpace(filename)
+	if utf8.RuneCountInString(filename) > filenameMaxLength {
+		filename = string([]rune(filename)[:filenameMaxLength])
+	}
+	return filename
+}
@@ -0,0 +1,118 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/media"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/storage"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// fakeMediaRepository keeps media in memory. Other calls panic.
+type fakeMediaRepository struct {
+	media.MediaRepository
+
+	created []*media.Media
+}
+
+func (f *fakeMediaRepository) Create(model *media.Media) error {
+	f.created = append(f.created, model)
+	return nil
+}
+
+// fakeBlobStore keeps blobs in memory. Other calls panic.
+type fakeBlobStore struct {
+	storage.BlobStore
+
+	blobs map[string][]byte
+}
+
+func (f *fakeBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	f.blobs[key] = content
+	return nil
+}
+
+func (f *fakeBlobStore) Delete(ctx context.Context, key string) error {
+	delete(f.blobs, key)
+	return nil
+}
+
+func newTestService() *mediaService {
+	return &mediaService{
+		MediaServiceDeps: MediaServiceDeps{
+			Config:          &config.Config{MediaMaxSize: 64},
+			MediaRepository: &fakeMediaRepository{},
+			BlobStore:       &fakeBlobStore{blobs: map[string][]byte{}},
+		},
+		Logger:       logger.NewStdoutLogger(logger.LoggingLevelNone),
+		allowedTypes: map[string]bool{"image/png": true, "application/pdf": true},
+	}
+}
+
+func TestUploadMedia(t *testing.T) {
+	uploader := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+	png := func(size int) []byte {
+		return append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, size-len(pngHeader))...)
+	}
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		filename        string
+		content         []byte
+		wantStatus      int
+		wantErr         error
+		wantContentType string
+		wantFilename    string
+	}{
+		{name: "allowed type", ctx: uploader, filename: "cat.png", content: png(32), wantStatus: http.StatusCreated, wantContentType: "image/png", wantFilename: "cat.png"},
+		{name: "at the size limit", ctx: uploader, filename: "cat.png", content: png(64), wantStatus: http.StatusCreated, wantContentType: "image/png", wantFilename: "cat.png"},
+		{name: "type sniffed rather than declared", ctx: uploader, filename: "cat.gif", content: png(32), wantStatus: http.StatusCreated, wantContentType: "image/png", wantFilename: "cat.gif"},
+		{name: "path stripped from the filename", ctx: uploader, filename: `C:\Users\me\cat.png`, content: png(32), wantStatus: http.StatusCreated, wantContentType: "image/png", wantFilename: "cat.png"},
+		{name: "filename cut to the maximum length", ctx: uploader, filename: strings.Repeat("a", filenameMaxLength+5), content: png(32), wantStatus: http.StatusCreated, wantContentType: "image/png", wantFilename: strings.Repeat("a", filenameMaxLength)},
+		{name: "type not allowed", ctx: uploader, filename: "cat.png", content: []byte("<html><script>alert(1)</script>"), wantStatus: http.StatusUnsupportedMediaType, wantErr: ErrUnsupportedMediaType},
+		{name: "over the size limit", ctx: uploader, filename: "cat.png", content: png(65), wantStatus: http.StatusRequestEntityTooLarge, wantErr: ErrMediaTooLarge},
+		{name: "empty", ctx: uploader, filename: "cat.png", wantStatus: http.StatusBadRequest, wantErr: ErrEmptyMedia},
+		{name: "anonymous", ctx: context.Background(), filename: "cat.png", content: png(32), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService()
+			repository := service.MediaRepository.(*fakeMediaRepository)
+			blobs := service.BlobStore.(*fakeBlobStore).blobs
+
+			status, resp, err := service.UploadMedia(tt.ctx, tt.filename, bytes.NewReader(tt.content))
+			if status != tt.wantStatus || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("UploadMedia() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if status != http.StatusCreated {
+				if len(repository.created) != 0 || len(blobs) != 0 {
+					t.Errorf("rejected upload left %d media and %d blobs behind", len(repository.created), len(blobs))
+				}
+				return
+			}
+			if resp.ContentType != tt.wantContentType || resp.Filename != tt.wantFilename || resp.Size != int64(len(tt.content)) {
+				t.Errorf("UploadMedia() = %+v, want a %d bytes %s named %s", resp, len(tt.content), tt.wantContentType, tt.wantFilename)
+			}
+			stored := repository.created[0]
+			if !bytes.Equal(blobs[stored.StorageKey], tt.content) || stored.OwnerId != "u1" {
+				t.Errorf("stored %+v, want the content uploaded by u1", stored)
+			}
+		})
+	}
+}
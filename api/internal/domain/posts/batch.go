@@ -0,0 +1,155 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+)
+
+var (
+	ErrBatchTooLarge   = errors.New("batch has too many operations")
+	ErrBatchRolledBack = errors.New("batch rolled back")
+)
+
+func (p *postService) BatchPosts(ctx context.Context, request *postsdto.BatchRequest) (int, *postsdto.BatchResponse, error) {
+	if len(request.Operations) > p.Config.BatchMaxOperations {
+		return http.StatusRequestEntityTooLarge, nil, fmt.Errorf("%w: at most %d are allowed", ErrBatchTooLarge, p.Config.BatchMaxOperations)
+	}
+
+	results := make([]postsdto.BatchResult, len(request.Operations))
+	for i, operation := range request.Operations {
+		results[i] = postsdto.BatchResult{Index: i, Op: operation.Op}
+	}
+
+	if !request.Atomic {
+		p.runBatch(ctx, request.Operations, false, results)
+		return http.StatusOK, postsdto.NewBatchResponse(false, results), nil
+	}
+
+	failed := -1
+	err := p.PostRepository.Transaction(func(repository posts.PostRepository) error {
+		service := *p
+		service.PostRepository = repository
+		if failed = service.runBatch(ctx, request.Operations, true, results); failed >= 0 {
+			return ErrBatchRolledBack
+		}
+		return nil
+	})
+	if err == nil {
+		return http.StatusOK, postsdto.NewBatchResponse(true, results), nil
+	}
+
+	// nothing was applied, the operation that failed keeps its error and the batch
+	// answers with its status
+	statusCode, err := http.StatusInternalServerError, fmt.Errorf("error committing batch: %v", err)
+	if failed >= 0 {
+		statusCode, err = results[failed].Status, fmt.Errorf("%w: operation %d failed: %s", ErrBatchRolledBack, failed, results[failed].Error)
+	}
+	for i := range results {
+		if i != failed {
+			results[i] = postsdto.BatchResult{Index: i, Op: results[i].Op, Status: http.StatusFailedDependency, Error: ErrBatchRolledBack.Error()}
+		}
+	}
+	return statusCode, postsdto.NewBatchResponse(true, results), err
+}
+
+// runBatch applies the operations of a batch in order, filling in their results. Runs
+// of consecutive creates are inserted together; when that fails, other batches fall
+// back to inserting them one by one, so each create fails on its own. Atomic batches
+// stop at the first operation that fails, whose index is returned, or -1 if none did.
+func (p *postService) runBatch(ctx context.Context, operations []postsdto.BatchOperation, atomic bool, results []postsdto.BatchResult) int {
+	fail := func(i int, statusCode int, err error) bool {
+		results[i].Status, results[i].Error = statusCode, err.Error()
+		return atomic
+	}
+
+	var pending []int
+	var models []*posts.Post
+	// flush inserts the pending creates, returning the index of the first of them if
+	// that failed
+	flush := func() int {
+		defer func() { pending, models = nil, nil }()
+		if len(models) == 0 {
+			return -1
+		}
+		if err := p.PostRepository.CreateMany(models, p.Config.BatchInsertSize); err != nil {
+			if atomic {
+				for _, i := range pending {
+					fail(i, http.StatusInternalServerError, fmt.Errorf("error creating new post: %v", err))
+				}
+				return pending[0]
+			}
+			for n, i := range pending {
+				p.createOne(models[n], &results[i])
+			}
+			return -1
+		}
+		for n, i := range pending {
+			results[i].Status, results[i].Post = http.StatusCreated, postsdto.NewPostResponse(models[n])
+		}
+		return -1
+	}
+
+	for i, operation := range operations {
+		if err := p.Validator.Struct(operation); err != nil {
+			if fail(i, http.StatusBadRequest, err) {
+				return i
+			}
+			continue
+		}
+
+		if operation.Op == postsdto.BatchOpCreate {
+			model, statusCode, err := p.newPost(ctx, operation.Post)
+			if err != nil {
+				if fail(i, statusCode, err) {
+					return i
+				}
+				continue
+			}
+			pending, models = append(pending, i), append(models, model)
+			continue
+		}
+
+		if failed := flush(); failed >= 0 && atomic {
+			return failed
+		}
+
+		var statusCode int
+		var post *postsdto.PostResponse
+		var err error
+		switch operation.Op {
+		case postsdto.BatchOpUpdate:
+			statusCode, post, err = p.UpdatePost(ctx, operation.PostId, operation.Post, operation.IfMatch)
+		case postsdto.BatchOpDelete:
+			statusCode, post, err = p.DeletePost(ctx, operation.PostId, false, operation.IfMatch)
+		}
+		if err != nil {
+			if fail(i, statusCode, err) {
+				return i
+			}
+			continue
+		}
+		results[i].Status, results[i].Post = statusCode, post
+	}
+
+	if failed := flush(); failed >= 0 && atomic {
+		return failed
+	}
+	return -1
+}
+
+// createOne inserts a post of a batch on its own, after inserting it along with others
+// failed, filling in its result.
+func (p *postService) createOne(model *posts.Post, result *postsdto.BatchResult) {
+	// the failed insert was rolled back, along with the id and slug it handed out
+	model.ID, model.Slug = 0, ""
+	if err := p.PostRepository.Create(model); err != nil {
+		result.Status, result.Error = http.StatusInternalServerError, fmt.Sprintf("error creating new post: %v", err)
+		return
+	}
+	result.Status, result.Post = http.StatusCreated, postsdto.NewPostResponse(model)
+}
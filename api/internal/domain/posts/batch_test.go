@@ -0,0 +1,175 @@
+package posts
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+)
+
+// unstorable is the content of posts the fake repository fails to create on their own.
+const unstorable = "unstorable"
+
+func (f *fakePostRepository) Create(post *posts.Post) error {
+	if post.Content == unstorable {
+		return errors.New("data too long for column")
+	}
+	f.stored = append(f.stored, post)
+	return nil
+}
+
+// Transaction runs fn against the repository itself and undoes the posts it created
+// when fn fails.
+func (f *fakePostRepository) Transaction(fn func(repository posts.PostRepository) error) error {
+	stored := len(f.stored)
+	if err := fn(f); err != nil {
+		f.stored = f.stored[:stored]
+		f.transactions = append(f.transactions, "rollback")
+		return err
+	}
+	f.transactions = append(f.transactions, "commit")
+	return nil
+}
+
+func (f *fakePostRepository) CreateMany(created []*posts.Post, batchSize int) error {
+	if len(created) == 0 {
+		return nil
+	}
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.batches = append(f.batches, len(created))
+	f.stored = append(f.stored, created...)
+	return nil
+}
+
+func TestBatchPosts(t *testing.T) {
+	create := func(content string) postsdto.BatchOperation {
+		return postsdto.BatchOperation{Op: postsdto.BatchOpCreate, Post: &postsdto.PostRequest{Title: "New", Content: content}}
+	}
+	update := func(postId string) postsdto.BatchOperation {
+		return postsdto.BatchOperation{Op: postsdto.BatchOpUpdate, PostId: postId, Post: &postsdto.PostRequest{Title: "Edited", Content: "edited"}}
+	}
+	remove := func(postId string) postsdto.BatchOperation {
+		return postsdto.BatchOperation{Op: postsdto.BatchOpDelete, PostId: postId}
+	}
+	errCreate := errors.New("deadlock found when trying to get lock")
+
+	tests := []struct {
+		name             string
+		atomic           bool
+		operations       []postsdto.BatchOperation
+		createErr        error
+		wantStatus       int
+		wantErr          error
+		wantStatuses     []int
+		wantCreated      int
+		wantBatches      []int
+		wantTransactions []string
+	}{
+		{
+			name:             "atomic batch applied at once",
+			atomic:           true,
+			operations:       []postsdto.BatchOperation{create("a"), create("b"), update("p1"), remove("p2")},
+			wantStatus:       http.StatusOK,
+			wantStatuses:     []int{http.StatusCreated, http.StatusCreated, http.StatusOK, http.StatusOK},
+			wantCreated:      2,
+			wantBatches:      []int{2},
+			wantTransactions: []string{"commit"},
+		},
+		{
+			name:             "atomic batch rolled back by a failing operation in the middle",
+			atomic:           true,
+			operations:       []postsdto.BatchOperation{create("a"), update("p3"), create("b")},
+			wantStatus:       http.StatusForbidden,
+			wantErr:          ErrBatchRolledBack,
+			wantStatuses:     []int{http.StatusFailedDependency, http.StatusForbidden, http.StatusFailedDependency},
+			wantBatches:      []int{1},
+			wantTransactions: []string{"rollback"},
+		},
+		{
+			name:             "atomic batch rolled back by failing creates",
+			atomic:           true,
+			operations:       []postsdto.BatchOperation{create("a"), create("b")},
+			createErr:        errCreate,
+			wantStatus:       http.StatusInternalServerError,
+			wantErr:          ErrBatchRolledBack,
+			wantStatuses:     []int{http.StatusInternalServerError, http.StatusFailedDependency},
+			wantTransactions: []string{"rollback"},
+		},
+		{
+			name:         "best-effort batch keeps going past failures",
+			operations:   []postsdto.BatchOperation{create("a"), update("p9"), {Op: "move", PostId: "p1"}, remove("p2"), create("b")},
+			wantStatus:   http.StatusOK,
+			wantStatuses: []int{http.StatusCreated, http.StatusNotFound, http.StatusBadRequest, http.StatusOK, http.StatusCreated},
+			wantCreated:  2,
+			wantBatches:  []int{1, 1},
+		},
+		{
+			name:         "best-effort creates fall back to one by one",
+			operations:   []postsdto.BatchOperation{create("a"), create(unstorable), create("b")},
+			createErr:    errCreate,
+			wantStatus:   http.StatusOK,
+			wantStatuses: []int{http.StatusCreated, http.StatusInternalServerError, http.StatusCreated},
+			wantCreated:  2,
+		},
+		{
+			name:       "too many operations",
+			operations: []postsdto.BatchOperation{create("a"), create("b"), create("c"), create("d"), create("e"), create("f")},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantErr:    ErrBatchTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakePostRepository{
+				stored: []*posts.Post{
+					{PostId: "p1", AuthorId: "u1", Content: "first", Version: 1},
+					{PostId: "p2", AuthorId: "u1", Content: "second", Version: 1},
+					{PostId: "p3", AuthorId: "u2", Content: "third", Version: 1},
+				},
+				createErr: tt.createErr,
+			}
+			service := newTestService(repository)
+			service.Validator = validator.New()
+			service.Config.BatchMaxOperations = 5
+			service.Config.BatchInsertSize = 10
+			ctx := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+
+			status, resp, err := service.BatchPosts(ctx, &postsdto.BatchRequest{Atomic: tt.atomic, Operations: tt.operations})
+			if status != tt.wantStatus || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BatchPosts() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if tt.wantStatuses != nil {
+				var statuses []int
+				for i, result := range resp.Results {
+					statuses = append(statuses, result.Status)
+					if result.Index != i || result.Op != tt.operations[i].Op {
+						t.Errorf("result %d = %+v, want it for operation %d", i, result, i)
+					}
+					if result.Failed() != (result.Error != "") {
+						t.Errorf("result %d = %+v, want an error exactly when it failed", i, result)
+					}
+				}
+				if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+					t.Errorf("BatchPosts() statuses = %v, want %v", statuses, tt.wantStatuses)
+				}
+			}
+			if created := len(repository.stored) - 3; created != tt.wantCreated {
+				t.Errorf("created %d posts, want %d", created, tt.wantCreated)
+			}
+			if !reflect.DeepEqual(repository.batches, tt.wantBatches) {
+				t.Errorf("inserted batches of %v, want %v", repository.batches, tt.wantBatches)
+			}
+			if !reflect.DeepEqual(repository.transactions, tt.wantTransactions) {
+				t.Errorf("transactions = %v, want %v", repository.transactions, tt.wantTransactions)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+)
+
+// exportBatchSize is how many posts are read from the database and handed over at a
+// time when exporting.
+const exportBatchSize = 500
+
+func (p *postService) ExportPosts(ctx context.Context, request *postsdto.PostListRequest, q *query.Query, fn func(posts []*postsdto.PostResponse) error) (int, error) {
+	q = listQuery(ctx, request, q)
+	if q == nil {
+		q = &query.Query{}
+	}
+
+	responses := make([]*postsdto.PostResponse, 0, exportBatchSize)
+	err := p.PostRepository.Stream(q, exportBatchSize, func(batch []posts.Post) error {
+		// clients going away cancel the export
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		responses = responses[:0]
+		for i := range batch {
+			responses = append(responses, postsdto.NewPostResponse(&batch[i]))
+		}
+		return fn(responses)
+	})
+	if err != nil {
+		if errors.Is(err, posts.ErrReactionSorts) {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusInternalServerError, fmt.Errorf("error exporting posts: %v", err)
+	}
+	return http.StatusOK, nil
+}
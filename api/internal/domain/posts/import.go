@@ -0,0 +1,190 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/etag"
+)
+
+var ErrPostExists = errors.New("a post with this id already exists")
+
+// errDryRun rolls back the batches of dry runs once they've been applied.
+var errDryRun = errors.New("dry run")
+
+// What importing a row came to, when it didn't fail
+const (
+	importCreated = iota + 1
+	importUpdated
+	importSkipped
+)
+
+// importItem is a row of an import along with what importing it came to.
+type importItem struct {
+	row     *postsdto.ImportRow
+	outcome int
+	err     error
+}
+
+func (p *postService) ImportPosts(ctx context.Context, request *postsdto.ImportRequest, r io.Reader, progress func(rows int)) (int, *postsdto.ImportReport, error) {
+	reader, err := postsdto.NewImportReader(request.Format, r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+
+	report := postsdto.NewImportReport(request)
+	batch := make([]*importItem, 0, p.Config.BatchInsertSize)
+	batched := map[string]bool{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		p.importBatch(ctx, request, batch)
+		for _, item := range batch {
+			switch {
+			case item.err != nil:
+				report.Fail(item.row, item.err)
+			case item.outcome == importCreated:
+				report.Created++
+			case item.outcome == importUpdated:
+				report.Updated++
+			case item.outcome == importSkipped:
+				report.Skipped++
+			}
+		}
+		batch, batched = batch[:0], map[string]bool{}
+		if progress != nil {
+			progress(report.Rows)
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return http.StatusServiceUnavailable, report, fmt.Errorf("import interrupted: %v", err)
+		}
+
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// the batches applied so far stay applied
+			flush()
+			return http.StatusBadRequest, report, fmt.Errorf("error reading import: %v", err)
+		}
+
+		report.Rows++
+		item := &importItem{row: row, err: row.Err}
+		if item.err == nil {
+			item.err = p.Validator.Struct(row)
+		}
+		// a batch imports a post once, repeats of it wait for the next batch
+		if item.err == nil && row.PostId != "" {
+			if batched[row.PostId] {
+				flush()
+			}
+			batched[row.PostId] = true
+		}
+		batch = append(batch, item)
+		if len(batch) >= p.Config.BatchInsertSize {
+			flush()
+		}
+	}
+	flush()
+
+	return http.StatusOK, report, nil
+}
+
+// importBatch imports the rows of a batch that haven't failed yet in a single
+// transaction, filling in what came of each. Dry runs roll the transaction back.
+func (p *postService) importBatch(ctx context.Context, request *postsdto.ImportRequest, batch []*importItem) {
+	pending := false
+	var postIds []string
+	for _, item := range batch {
+		if item.err == nil {
+			pending = true
+			if item.row.PostId != "" {
+				postIds = append(postIds, item.row.PostId)
+			}
+		}
+	}
+	if !pending {
+		return
+	}
+
+	err := p.PostRepository.Transaction(func(repository posts.PostRepository) error {
+		service := *p
+		service.PostRepository = repository
+
+		found, err := repository.GetManyByUUID(postIds)
+		if err != nil {
+			return err
+		}
+		existing := map[string]*posts.Post{}
+		for i := range found {
+			existing[found[i].PostId] = &found[i]
+		}
+
+		var creates []*importItem
+		var models []*posts.Post
+		for _, item := range batch {
+			if item.err != nil {
+				continue
+			}
+
+			post, exists := existing[item.row.PostId]
+			switch {
+			case !exists:
+				model, _, err := service.newPost(ctx, &item.row.Post)
+				if err != nil {
+					item.err = err
+					continue
+				}
+				if item.row.PostId != "" {
+					model.PostId = item.row.PostId
+				}
+				creates, models = append(creates, item), append(models, model)
+			case request.OnConflict == postsdto.OnConflictSkip:
+				item.outcome = importSkipped
+			case request.OnConflict == postsdto.OnConflictFail:
+				item.err = ErrPostExists
+			case post.DeletedAt.Valid:
+				item.err = ErrPostTrashed
+			default:
+				// the version just read guards against concurrent updates
+				_, _, err := service.UpdatePost(ctx, post.PostId, &item.row.Post, etag.FromVersion(post.Version))
+				if err != nil {
+					item.err = err
+					continue
+				}
+				item.outcome = importUpdated
+			}
+		}
+
+		if err := repository.CreateMany(models, p.Config.BatchInsertSize); err != nil {
+			return err
+		}
+		for _, item := range creates {
+			item.outcome = importCreated
+		}
+
+		if request.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		// nothing of the batch was written
+		for _, item := range batch {
+			if item.err == nil {
+				item.outcome, item.err = 0, fmt.Errorf("error importing batch: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package posts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+)
+
+func (f *fakePostRepository) GetManyByUUID(uuids []string) ([]posts.Post, error) {
+	var found []posts.Post
+	for _, post := range f.stored {
+		for _, uuid := range uuids {
+			if post.PostId == uuid {
+				found = append(found, *post)
+			}
+		}
+	}
+	return found, nil
+}
+
+// ndjsonImport builds an NDJSON import of a post per post id, new posts for empty ids.
+func ndjsonImport(postIds ...string) string {
+	var lines []string
+	for i, postId := range postIds {
+		if postId == "" {
+			lines = append(lines, fmt.Sprintf(`{"content":"post %d"}`, i))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf(`{"post_id":%q,"content":"post %d"}`, postId, i))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestImportPosts(t *testing.T) {
+	const (
+		existingId = "9b2c9a4e-3f5d-4c1e-8a7b-6d5e4f3a2b1c"
+		newId      = "0d6f3e2a-7c1b-4b5e-9f8a-1a2b3c4d5e6f"
+	)
+	errBatch := errors.New("deadlock")
+
+	tests := []struct {
+		name             string
+		request          postsdto.ImportRequest
+		content          string
+		createErr        error
+		wantReport       postsdto.ImportReport
+		wantBatches      []int
+		wantTransactions []string
+		wantProgress     []int
+		wantStored       int
+	}{
+		{
+			name:             "creates posts in batches",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictFail},
+			content:          ndjsonImport("", "", "", "", ""),
+			wantReport:       postsdto.ImportReport{Rows: 5, Created: 5},
+			wantBatches:      []int{2, 2, 1},
+			wantTransactions: []string{"commit", "commit", "commit"},
+			wantProgress:     []int{2, 4, 5},
+			wantStored:       6,
+		},
+		{
+			name:             "repeated post ids wait for the next batch",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictOverwrite},
+			content:          ndjsonImport(newId, newId),
+			wantReport:       postsdto.ImportReport{Rows: 2, Created: 1, Updated: 1},
+			wantBatches:      []int{1},
+			wantTransactions: []string{"commit", "commit"},
+			wantProgress:     []int{2, 2},
+			wantStored:       2,
+		},
+		{
+			name:             "existing posts are overwritten",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictOverwrite},
+			content:          ndjsonImport(existingId, ""),
+			wantReport:       postsdto.ImportReport{Rows: 2, Created: 1, Updated: 1},
+			wantBatches:      []int{1},
+			wantTransactions: []string{"commit"},
+			wantProgress:     []int{2},
+			wantStored:       2,
+		},
+		{
+			name:             "existing posts are skipped",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictSkip},
+			content:          ndjsonImport(existingId, ""),
+			wantReport:       postsdto.ImportReport{Rows: 2, Created: 1, Skipped: 1},
+			wantBatches:      []int{1},
+			wantTransactions: []string{"commit"},
+			wantProgress:     []int{2},
+			wantStored:       2,
+		},
+		{
+			name:             "existing posts fail",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictFail},
+			content:          ndjsonImport(existingId, ""),
+			wantReport:       postsdto.ImportReport{Rows: 2, Created: 1, Failed: 1},
+			wantBatches:      []int{1},
+			wantTransactions: []string{"commit"},
+			wantProgress:     []int{2},
+			wantStored:       2,
+		},
+		{
+			name:             "dry runs roll every batch back",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictOverwrite, DryRun: true},
+			content:          ndjsonImport("", existingId, ""),
+			wantReport:       postsdto.ImportReport{DryRun: true, Rows: 3, Created: 2, Updated: 1},
+			wantBatches:      []int{1, 1},
+			wantTransactions: []string{"rollback", "rollback"},
+			wantProgress:     []int{2, 3},
+			wantStored:       1,
+		},
+		{
+			name:             "a failing batch fails all of its rows",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictFail},
+			content:          ndjsonImport("", ""),
+			createErr:        errBatch,
+			wantReport:       postsdto.ImportReport{Rows: 2, Failed: 2},
+			wantTransactions: []string{"rollback"},
+			wantProgress:     []int{2},
+			wantStored:       1,
+		},
+		{
+			name:             "invalid rows fail alone",
+			request:          postsdto.ImportRequest{Format: "ndjson", OnConflict: postsdto.OnConflictFail},
+			content:          ndjsonImport("not-a-uuid", ""),
+			wantReport:       postsdto.ImportReport{Rows: 2, Created: 1, Failed: 1},
+			wantBatches:      []int{1},
+			wantTransactions: []string{"commit"},
+			wantProgress:     []int{2},
+			wantStored:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakePostRepository{
+				stored:    []*posts.Post{{PostId: existingId, AuthorId: "u1", Content: "old", Format: "plain", Version: 2}},
+				createErr: tt.createErr,
+			}
+			service := newTestService(repository)
+			service.Validator = validator.New()
+			service.Config.BatchInsertSize = 2
+
+			var progress []int
+			ctx := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+			status, report, err := service.ImportPosts(ctx, &tt.request, strings.NewReader(tt.content), func(rows int) {
+				progress = append(progress, rows)
+			})
+			if status != http.StatusOK {
+				t.Fatalf("ImportPosts() = %d, %v", status, err)
+			}
+
+			got := *report
+			got.DryRun, got.OnConflict, got.Errors = tt.wantReport.DryRun, "", nil
+			if !reflect.DeepEqual(got, tt.wantReport) {
+				t.Errorf("ImportPosts() report = %+v, want %+v", got, tt.wantReport)
+			}
+			if len(report.Errors) != report.Failed {
+				t.Errorf("report lists %d errors for %d failed rows", len(report.Errors), report.Failed)
+			}
+			if !reflect.DeepEqual(repository.batches, tt.wantBatches) {
+				t.Errorf("created batches of %v, want %v", repository.batches, tt.wantBatches)
+			}
+			if !reflect.DeepEqual(repository.transactions, tt.wantTransactions) {
+				t.Errorf("transactions = %v, want %v", repository.transactions, tt.wantTransactions)
+			}
+			if !reflect.DeepEqual(progress, tt.wantProgress) {
+				t.Errorf("progress = %v, want %v", progress, tt.wantProgress)
+			}
+			if len(repository.stored) != tt.wantStored {
+				t.Errorf("stored %d posts, want %d", len(repository.stored), tt.wantStored)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package posts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+)
+
+// Action is a transition of the post lifecycle.
+type Action string
+
+const (
+	ActionSubmit    Action = "submit"
+	ActionPublish   Action = "publish"
+	ActionUnpublish Action = "unpublish"
+	ActionArchive   Action = "archive"
+)
+
+var (
+	ErrUnknownAction     = errors.New("unknown post action")
+	ErrInvalidTransition = errors.New("invalid post status transition")
+)
+
+type transition struct {
+	from []string
+	to   string
+}
+
+// transitions is the post lifecycle state machine. Posts start as drafts, can be
+// submitted for review, published, unpublished back to drafts and archived.
+var transitions = map[Action]transition{
+	ActionSubmit:    {from: []string{posts.StatusDraft}, to: posts.StatusInReview},
+	ActionPublish:   {from: []string{posts.StatusDraft, posts.StatusInReview}, to: posts.StatusPublished},
+	ActionUnpublish: {from: []string{posts.StatusPublished, posts.StatusArchived}, to: posts.StatusDraft},
+	ActionArchive:   {from: []string{posts.StatusDraft, posts.StatusInReview, posts.StatusPublished}, to: posts.StatusArchived},
+}
+
+// applyTransition moves the post to the status the action leads to, if the action is
+// allowed from its current status.
+func applyTransition(post *posts.Post, action Action, now time.Time) error {
+	t, ok := transitions[action]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+
+	allowed := false
+	for _, from := range t.from {
+		if post.Status == from {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: cannot %s a post that is %s", ErrInvalidTransition, action, post.Status)
+	}
+
+	post.Status = t.to
+	switch action {
+	case ActionPublish:
+		post.PublishedAt = &now
+		post.PublishAt = nil
+	case ActionUnpublish:
+		post.PublishedAt = nil
+	case ActionArchive:
+		post.PublishAt = nil
+	}
+	return nil
+}
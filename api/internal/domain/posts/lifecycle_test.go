@@ -0,0 +1,85 @@
+package posts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+)
+
+func TestApplyTransition(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name            string
+		status          string
+		action          Action
+		want            string
+		wantErr         error
+		wantPublishedAt *time.Time
+		keepsPublishAt  bool
+	}{
+		{name: "submit draft", status: posts.StatusDraft, action: ActionSubmit, want: posts.StatusInReview, wantPublishedAt: &earlier, keepsPublishAt: true},
+		{name: "submit in review", status: posts.StatusInReview, action: ActionSubmit, wantErr: ErrInvalidTransition},
+		{name: "submit published", status: posts.StatusPublished, action: ActionSubmit, wantErr: ErrInvalidTransition},
+		{name: "submit archived", status: posts.StatusArchived, action: ActionSubmit, wantErr: ErrInvalidTransition},
+
+		{name: "publish draft", status: posts.StatusDraft, action: ActionPublish, want: posts.StatusPublished, wantPublishedAt: &now},
+		{name: "publish in review", status: posts.StatusInReview, action: ActionPublish, want: posts.StatusPublished, wantPublishedAt: &now},
+		{name: "publish published", status: posts.StatusPublished, action: ActionPublish, wantErr: ErrInvalidTransition},
+		{name: "publish archived", status: posts.StatusArchived, action: ActionPublish, wantErr: ErrInvalidTransition},
+
+		{name: "unpublish draft", status: posts.StatusDraft, action: ActionUnpublish, wantErr: ErrInvalidTransition},
+		{name: "unpublish in review", status: posts.StatusInReview, action: ActionUnpublish, wantErr: ErrInvalidTransition},
+		{name: "unpublish published", status: posts.StatusPublished, action: ActionUnpublish, want: posts.StatusDraft, keepsPublishAt: true},
+		{name: "unpublish archived", status: posts.StatusArchived, action: ActionUnpublish, want: posts.StatusDraft, keepsPublishAt: true},
+
+		{name: "archive draft", status: posts.StatusDraft, action: ActionArchive, want: posts.StatusArchived, wantPublishedAt: &earlier},
+		{name: "archive in review", status: posts.StatusInReview, action: ActionArchive, want: posts.StatusArchived, wantPublishedAt: &earlier},
+		{name: "archive published", status: posts.StatusPublished, action: ActionArchive, want: posts.StatusArchived, wantPublishedAt: &earlier},
+		{name: "archive archived", status: posts.StatusArchived, action: ActionArchive, wantErr: ErrInvalidTransition},
+
+		{name: "unknown action", status: posts.StatusDraft, action: Action("delete"), wantErr: ErrUnknownAction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publishedAt, publishAt := earlier, later
+			post := &posts.Post{Status: tt.status, PublishedAt: &publishedAt, PublishAt: &publishAt}
+
+			err := applyTransition(post, tt.action, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("applyTransition() error = %v, want %v", err, tt.wantErr)
+				}
+				if post.Status != tt.status {
+					t.Errorf("failed applyTransition() changed the status to %s", post.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyTransition() error = %v", err)
+			}
+
+			if post.Status != tt.want {
+				t.Errorf("status = %s, want %s", post.Status, tt.want)
+			}
+			if !equalTimes(post.PublishedAt, tt.wantPublishedAt) {
+				t.Errorf("published at = %v, want %v", post.PublishedAt, tt.wantPublishedAt)
+			}
+			if got := post.PublishAt != nil; got != tt.keepsPublishAt {
+				t.Errorf("publish at kept = %v, want %v", got, tt.keepsPublishAt)
+			}
+		})
+	}
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,36 @@
+package posts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrUnknownMedia = errors.New("unknown media")
+
+// checkMedia dedupes the media ids a post references, keeping their order, and
+// checks they all exist. A nil slice stays nil, leaving the media of a post untouched.
+func (p *postService) checkMedia(mediaIds []string) ([]string, int, error) {
+	if mediaIds == nil {
+		return nil, http.StatusOK, nil
+	}
+
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(mediaIds))
+	for _, mediaId := range mediaIds {
+		if !seen[mediaId] {
+			seen[mediaId] = true
+			unique = append(unique, mediaId)
+		}
+	}
+
+	missing, err := p.MediaRepository.FindMissing(unique)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("error checking media: %v", err)
+	}
+	if len(missing) > 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("%w: %s", ErrUnknownMedia, strings.Join(missing, ", "))
+	}
+	return unique, http.StatusOK, nil
+}
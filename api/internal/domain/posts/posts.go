@@ -2,17 +2,30 @@ package posts
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 
 	"net/http"
 
 	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/media"
 	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
 	"github.com/pedromspeixoto/posts-api/internal/dto"
 	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/etag"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/render"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
 	"go.uber.org/fx"
 )
 
@@ -20,41 +33,134 @@ import (
 type PostService interface {
 	// CreatePost creates a post entry
 	CreatePost(ctx context.Context, Post *postsdto.PostRequest) (int, *postsdto.PostResponse, error)
-	// ListPosts retrieves all posts with pagination.
-	ListPosts(ctx context.Context, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
-	// UpdatePost updates a post entry by uuid
-	UpdatePost(ctx context.Context, uuid string, request *postsdto.PostRequest) (int, *postsdto.PostResponse, error)
-	// UpsertPost updates or creates a post entry by uuid
-	UpsertPost(ctx context.Context, uuid string, request *postsdto.PostRequest) (int, *postsdto.PostResponse, error)
+	// ListPosts retrieves all posts, optionally in a given status, with pagination.
+	ListPosts(ctx context.Context, request *postsdto.PostListRequest, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// ExportPosts streams every post ListPosts would list, across all pages, to fn a
+	// chunk at a time
+	ExportPosts(ctx context.Context, request *postsdto.PostListRequest, q *query.Query, fn func(posts []*postsdto.PostResponse) error) (int, error)
+	// ListAuthorPosts retrieves the posts written by an author with pagination.
+	ListAuthorPosts(ctx context.Context, authorId string, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// SearchPosts runs a full-text search over posts with pagination.
+	SearchPosts(ctx context.Context, request *postsdto.PostSearchRequest, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// UpdatePost updates a post entry by uuid if the If-Match precondition holds
+	UpdatePost(ctx context.Context, uuid string, request *postsdto.PostRequest, ifMatch string) (int, *postsdto.PostResponse, error)
+	// UpsertPost creates a post entry at a client chosen uuid, answering with
+	// http.StatusCreated, or replaces the post already there like UpdatePost does
+	UpsertPost(ctx context.Context, uuid string, request *postsdto.PostRequest, ifMatch string) (int, *postsdto.PostResponse, error)
 	// GetPost retrieves a post entry by uuid
 	GetPost(ctx context.Context, uuid string) (int, *postsdto.PostResponse, error)
-	// DeletePost hard deletes a post entry by uuid
-	DeletePost(ctx context.Context, uuid string) (int, *postsdto.PostResponse, error)
+	// GetPostBySlug retrieves a post entry by slug. Slugs the post went by before being
+	// renamed answer with http.StatusMovedPermanently and the post, whose slug is the
+	// current one
+	GetPostBySlug(ctx context.Context, slug string) (int, *postsdto.PostResponse, error)
+	// DeletePost moves a post entry to the trash, or hard deletes it when purging, by
+	// uuid if the If-Match precondition holds
+	DeletePost(ctx context.Context, uuid string, purge bool, ifMatch string) (int, *postsdto.PostResponse, error)
+	// RestorePost restores a post entry from the trash by uuid if the If-Match
+	// precondition holds
+	RestorePost(ctx context.Context, uuid string, ifMatch string) (int, *postsdto.PostResponse, error)
+	// ListTrashedPosts retrieves the trashed posts the caller can restore with pagination.
+	ListTrashedPosts(ctx context.Context, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// PurgeTrashedPosts hard deletes up to limit posts trashed for longer than the
+	// retention period and returns how many were deleted
+	PurgeTrashedPosts(ctx context.Context, retention time.Duration, limit int) (int, error)
+	// TransitionPost moves a post through its lifecycle if the If-Match precondition holds
+	TransitionPost(ctx context.Context, uuid string, action Action, ifMatch string) (int, *postsdto.PostResponse, error)
+	// ListRevisions retrieves the revisions of a post with pagination.
+	ListRevisions(ctx context.Context, uuid string, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// GetRevision retrieves a revision of a post by number
+	GetRevision(ctx context.Context, uuid string, revision uint) (int, *postsdto.RevisionResponse, error)
+	// DiffRevisions computes the unified diff between two revisions of a post
+	DiffRevisions(ctx context.Context, uuid string, from, to uint) (int, *postsdto.RevisionDiffResponse, error)
+	// RestoreRevision makes the content of an old revision current again, as a new
+	// revision, if the If-Match precondition holds
+	RestoreRevision(ctx context.Context, uuid string, revision uint, ifMatch string) (int, *postsdto.PostResponse, error)
+	// ListTags retrieves the tags in use along with their post counts with pagination.
+	ListTags(ctx context.Context, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// ListTagPosts retrieves the posts filed under a tag with pagination.
+	ListTagPosts(ctx context.Context, tag string, pagination *dto.PaginationRequest) (int, *dto.PaginationResponse, error)
+	// ReactToPost records the reaction of the caller to a post, once per kind
+	ReactToPost(ctx context.Context, uuid string, kind string) (int, *postsdto.PostResponse, error)
+	// UnreactToPost removes the reaction of the caller to a post
+	UnreactToPost(ctx context.Context, uuid string, kind string) (int, *postsdto.PostResponse, error)
+	// BatchPosts applies a batch of create, update and delete operations, all or
+	// nothing when atomic, reporting the outcome of each of them
+	BatchPosts(ctx context.Context, request *postsdto.BatchRequest) (int, *postsdto.BatchResponse, error)
+	// ImportPosts imports posts from NDJSON or CSV a batch at a time, creating the ones
+	// whose post id is free and handling the others as asked, and reports what came of
+	// each row. progress, if set, is called with the number of rows read after each batch
+	ImportPosts(ctx context.Context, request *postsdto.ImportRequest, r io.Reader, progress func(rows int)) (int, *postsdto.ImportReport, error)
+	// PublishScheduledPosts publishes up to limit posts whose publish_at is due and
+	// returns how many were published
+	PublishScheduledPosts(ctx context.Context, limit int) (int, error)
 }
 
+// mysqlErrSyntax is returned by MySQL for malformed boolean mode search expressions.
+const mysqlErrSyntax = 1064
+
+var (
+	ErrPreconditionFailed   = errors.New("precondition failed: post has been modified")
+	ErrPreconditionRequired = errors.New("precondition required: If-Match header is missing")
+	ErrNotAuthor            = errors.New("only the author of a post or an admin can modify it")
+	ErrPublishAtInPast      = errors.New("publish_at must be in the future")
+	ErrScheduleNotAllowed   = errors.New("only draft and in review posts can be scheduled")
+	ErrPurgeNotAllowed      = errors.New("only admins can purge posts")
+	ErrInvalidPostId        = errors.New("post id must be a UUID")
+	ErrPostTrashed          = errors.New("post is in the trash, restore it before replacing it")
+)
+
+// StatusScheduled lists the unpublished posts that have a publish_at set. It isn't a
+// status of its own, scheduled posts stay drafts or in review until published.
+const StatusScheduled = "scheduled"
+
 type PostServiceDeps struct {
 	fx.In
 
-	Config         *config.Config
-	Logger         *logger.LoggingClient
-	PostRepository posts.PostRepository
+	Config                 *config.Config
+	Logger                 *logger.LoggingClient
+	Validator              *validator.Validate
+	PostRepository         posts.PostRepository
+	PostRevisionRepository posts.PostRevisionRepository
+	TagRepository          posts.TagRepository
+	PostReactionRepository posts.PostReactionRepository
+	MediaRepository        media.MediaRepository
 }
 
 type postService struct {
 	PostServiceDeps
 	logger.Logger
+	cursorSecret []byte
+	// querySchema is the query schema of post lists, along with the reaction kinds
+	querySchema query.Schema
 }
 
-func NewPostService(deps PostServiceDeps) PostService {
-	return &postService{
+func NewPostService(deps PostServiceDeps) (PostService, error) {
+	service := &postService{
 		PostServiceDeps: deps,
 		Logger:          deps.Logger.GetLogger(),
+		cursorSecret:    []byte(deps.Config.PaginationCursorSecret),
+		querySchema:     posts.QuerySchemaWithReactions(deps.Config.ReactionKinds),
+	}
+
+	// fall back to an ephemeral secret, cursors will not survive restarts or be
+	// shared between replicas
+	if len(service.cursorSecret) == 0 {
+		service.Warning("PAGINATION_CURSOR_SECRET is not set, using an ephemeral cursor secret")
+		service.cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(service.cursorSecret); err != nil {
+			return nil, fmt.Errorf("error generating cursor secret: %v", err)
+		}
 	}
+
+	return service, nil
 }
 
 func (p *postService) CreatePost(ctx context.Context, request *postsdto.PostRequest) (int, *postsdto.PostResponse, error) {
-	model := postsdto.ModelFromPostRequest(request)
-	err := p.PostRepository.Create(model)
+	model, statusCode, err := p.newPost(ctx, request)
+	if err != nil {
+		return statusCode, nil, err
+	}
+	err = p.PostRepository.Create(model)
 	if err != nil {
 		return http.StatusInternalServerError, nil, fmt.Errorf("error creating new post: %v", err)
 	}
@@ -62,41 +168,201 @@ func (p *postService) CreatePost(ctx context.Context, request *postsdto.PostRequ
 	return http.StatusCreated, postsdto.NewPostResponse(model), nil
 }
 
-func (p *postService) ListPosts(ctx context.Context, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
-	posts, pageEnv, err := p.PostRepository.List(paginationRequest.Limit, paginationRequest.Page)
+// newPost builds a post written by the caller out of a request, ready to be stored.
+func (p *postService) newPost(ctx context.Context, request *postsdto.PostRequest) (*posts.Post, int, error) {
+	model := postsdto.ModelFromPostRequest(request)
+	if err := checkSchedule(model, nil, time.Now()); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		model.AuthorId = principal.Subject
+	}
+	mediaIds, statusCode, err := p.checkMedia(model.Media)
+	if err != nil {
+		return nil, statusCode, err
+	}
+	model.Media = mediaIds
+	if err := renderContent(model); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return model, http.StatusOK, nil
+}
+
+func (p *postService) ListPosts(ctx context.Context, request *postsdto.PostListRequest, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	paginationRequest.Query = listQuery(ctx, request, paginationRequest.Query)
+
+	pagination := dto.ModelFromPaginationRequest(paginationRequest)
+	if paginationRequest.Cursor != "" {
+		cursor, err := data.DecodeCursor(paginationRequest.Cursor, p.cursorSecret)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if err := pagination.SetCursor(cursor, p.querySchema); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+	}
+
+	list, pageEnv, err := p.PostRepository.List(pagination)
 	if err != nil {
+		if errors.Is(err, posts.ErrReactionSorts) {
+			return http.StatusBadRequest, nil, err
+		}
 		return http.StatusNotFound, nil, fmt.Errorf("error fetching posts: %v", err)
 	}
 
-	pageEnv.Data = postsdto.NewPostListResponse(posts)
+	pageEnv.Data = postsdto.NewPostListResponse(list)
+	response := dto.NewPaginationResponse(pageEnv)
+	if err := response.SetCursors(pageEnv, p.cursorSecret); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error encoding cursors: %v", err)
+	}
+	return http.StatusOK, response, nil
+}
+
+func (p *postService) ListAuthorPosts(ctx context.Context, authorId string, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	paginationRequest.Query = withFilter(paginationRequest.Query, "author_id", query.Eq, authorId)
+	return p.ListPosts(ctx, &postsdto.PostListRequest{}, paginationRequest)
+}
+
+func (p *postService) SearchPosts(ctx context.Context, request *postsdto.PostSearchRequest, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	if paginationRequest.Cursor != "" {
+		return http.StatusBadRequest, nil, fmt.Errorf("search results are ranked by relevance and don't support cursors")
+	}
+	if paginationRequest.Query != nil && len(paginationRequest.Query.Sorts) > 0 {
+		return http.StatusBadRequest, nil, fmt.Errorf("search results are ranked by relevance and can't be sorted")
+	}
+
+	if !canSeeUnpublished(ctx) {
+		paginationRequest.Query = onlyPublished(paginationRequest.Query)
+	}
+
+	mode := posts.SearchModeNatural
+	if request.Mode != "" {
+		mode = posts.SearchMode(request.Mode)
+	}
+
+	results, pageEnv, err := p.PostRepository.Search(request.Query, mode, dto.ModelFromPaginationRequest(paginationRequest))
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrSyntax {
+			return http.StatusBadRequest, nil, fmt.Errorf("malformed search query: %v", err)
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("error searching posts: %v", err)
+	}
+
+	pageEnv.Data = postsdto.NewPostSearchListResponse(results, request.Query)
 	return http.StatusOK, dto.NewPaginationResponse(pageEnv), nil
 }
 
-func (p *postService) UpdatePost(ctx context.Context, uuid string, request *postsdto.PostRequest) (int, *postsdto.PostResponse, error) {
+func (p *postService) UpdatePost(ctx context.Context, uuid string, request *postsdto.PostRequest, ifMatch string) (int, *postsdto.PostResponse, error) {
 	post, err := p.PostRepository.GetByUUID(uuid)
 	if err != nil {
 		return http.StatusNotFound, nil, err
 	}
 
+	statusCode, err := p.checkModify(ctx, post, ifMatch)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	// posts keep their format unless asked to change it
+	format := post.Format
+	if request.Format != "" {
+		format = request.Format
+	}
+	previous, contentChanged := post.PublishAt, post.Content != request.Content || post.Format != format
+	if post.Title != request.Title {
+		// renamed posts get a new slug, the old one keeps redirecting to the post
+		post.Title = request.Title
+		post.Slug = ""
+	}
 	post.Content = request.Content
-	err = p.PostRepository.Update(post)
+	post.Format = format
+	post.PublishAt = request.PublishAt
+	post.Tags = posts.NormalizeTags(request.Tags)
+	post.Media, statusCode, err = p.checkMedia(request.Media)
+	if err != nil {
+		return statusCode, nil, err
+	}
+	if err := checkSchedule(post, previous, time.Now()); err != nil {
+		if errors.Is(err, ErrScheduleNotAllowed) {
+			return http.StatusConflict, nil, err
+		}
+		return http.StatusBadRequest, nil, err
+	}
+	if err := renderContent(post); err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	// only content changes are worth a revision
+	if contentChanged {
+		_, err = p.PostRepository.Revise(post, editorOf(ctx))
+	} else {
+		err = p.PostRepository.Update(post)
+	}
 	if err != nil {
+		if errors.Is(err, posts.ErrVersionConflict) {
+			return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+		}
 		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error updating post: %v", err)
 	}
 
 	return http.StatusOK, postsdto.NewPostResponse(post), nil
 }
 
-func (p *postService) UpsertPost(ctx context.Context, uuid string, request *postsdto.PostRequest) (int, *postsdto.PostResponse, error) {
-	_, err := p.PostRepository.GetByUUID(uuid)
+func (p *postService) UpsertPost(ctx context.Context, postId string, request *postsdto.PostRequest, ifMatch string) (int, *postsdto.PostResponse, error) {
+	if !uuid.IsValid(postId) {
+		return http.StatusBadRequest, nil, ErrInvalidPostId
+	}
+
+	_, err := p.PostRepository.GetByUUID(postId)
+	if err == nil {
+		return p.UpdatePost(ctx, postId, request, ifMatch)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching post: %v", err)
+	}
+
+	// there's no version to match yet
+	if ifMatch != "" {
+		return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+	}
+	if _, err := p.PostRepository.GetTrashedByUUID(postId); err == nil {
+		return http.StatusConflict, nil, ErrPostTrashed
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching post: %v", err)
+	}
+
+	model, statusCode, err := p.newPost(ctx, request)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return p.CreatePost(ctx, request)
+		return statusCode, nil, err
+	}
+	model.PostId = postId
+	// a concurrent request may create the post in the meantime, which this one then
+	// replaces, as long as the caller could have replaced it in the first place
+	replaceStatus := http.StatusOK
+	created, err := p.PostRepository.Upsert(model, func(existing *posts.Post) error {
+		if existing.DeletedAt.Valid {
+			replaceStatus = http.StatusConflict
+			return ErrPostTrashed
 		}
-		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching post: %v", err)
+		statusCode, err := p.checkModify(ctx, existing, "")
+		replaceStatus = statusCode
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, posts.ErrSlugTaken):
+			return http.StatusConflict, nil, err
+		case replaceStatus != http.StatusOK:
+			return replaceStatus, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("error creating new post: %v", err)
+	}
+	if !created {
+		return http.StatusOK, postsdto.NewPostResponse(model), nil
 	}
 
-	return p.UpdatePost(ctx, uuid, request)
+	return http.StatusCreated, postsdto.NewPostResponse(model), nil
 }
 
 func (p *postService) GetPost(ctx context.Context, uuid string) (int, *postsdto.PostResponse, error) {
@@ -105,19 +371,316 @@ func (p *postService) GetPost(ctx context.Context, uuid string) (int, *postsdto.
 		return http.StatusNotFound, nil, err
 	}
 
+	// unpublished posts don't exist as far as readers are concerned
+	if post.Status != posts.StatusPublished && !canSeeUnpublished(ctx) {
+		return http.StatusNotFound, nil, gorm.ErrRecordNotFound
+	}
+
 	return http.StatusOK, postsdto.NewPostResponse(post), nil
 }
 
-func (p *postService) DeletePost(ctx context.Context, uuid string) (int, *postsdto.PostResponse, error) {
+func (p *postService) GetPostBySlug(ctx context.Context, slug string) (int, *postsdto.PostResponse, error) {
+	post, err := p.PostRepository.GetBySlug(slug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusNotFound, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching post: %v", err)
+	}
+
+	if post.Status != posts.StatusPublished && !canSeeUnpublished(ctx) {
+		return http.StatusNotFound, nil, gorm.ErrRecordNotFound
+	}
+
+	if post.Slug != slug {
+		return http.StatusMovedPermanently, postsdto.NewPostResponse(post), nil
+	}
+	return http.StatusOK, postsdto.NewPostResponse(post), nil
+}
+
+func (p *postService) DeletePost(ctx context.Context, uuid string, purge bool, ifMatch string) (int, *postsdto.PostResponse, error) {
+	if purge {
+		return p.purgePost(ctx, uuid, ifMatch)
+	}
+
 	post, err := p.PostRepository.GetByUUID(uuid)
 	if err != nil {
 		return http.StatusNotFound, nil, err
 	}
 
-	err = p.PostRepository.HardDelete(post)
+	statusCode, err := p.checkModify(ctx, post, ifMatch)
 	if err != nil {
+		return statusCode, nil, err
+	}
+
+	err = p.PostRepository.SoftDelete(post)
+	if err != nil {
+		if errors.Is(err, posts.ErrVersionConflict) {
+			return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+		}
 		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error deleting post: %v", err)
 	}
 
 	return http.StatusOK, nil, nil
 }
+
+// purgePost hard deletes a post, trashed or not. It's reserved to admins as there is
+// no coming back from it.
+func (p *postService) purgePost(ctx context.Context, uuid string, ifMatch string) (int, *postsdto.PostResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || !principal.IsAdmin() {
+		return http.StatusForbidden, nil, ErrPurgeNotAllowed
+	}
+
+	post, err := p.PostRepository.GetByUUID(uuid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		post, err = p.PostRepository.GetTrashedByUUID(uuid)
+	}
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+
+	statusCode, err := p.checkIfMatch(post, ifMatch)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	err = p.PostRepository.HardDelete(post)
+	if err != nil {
+		if errors.Is(err, posts.ErrVersionConflict) {
+			return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error purging post: %v", err)
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+func (p *postService) RestorePost(ctx context.Context, uuid string, ifMatch string) (int, *postsdto.PostResponse, error) {
+	post, err := p.PostRepository.GetTrashedByUUID(uuid)
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+
+	statusCode, err := p.checkModify(ctx, post, ifMatch)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	err = p.PostRepository.Restore(post)
+	if err != nil {
+		if errors.Is(err, posts.ErrVersionConflict) {
+			return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error restoring post: %v", err)
+	}
+
+	return http.StatusOK, postsdto.NewPostResponse(post), nil
+}
+
+func (p *postService) ListTrashedPosts(ctx context.Context, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	// everyone but admins only sees the posts they wrote
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+	if !principal.IsAdmin() {
+		paginationRequest.Query = withFilter(paginationRequest.Query, "author_id", query.Eq, principal.Subject)
+	}
+
+	pagination := dto.ModelFromPaginationRequest(paginationRequest)
+	if paginationRequest.Cursor != "" {
+		cursor, err := data.DecodeCursor(paginationRequest.Cursor, p.cursorSecret)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if err := pagination.SetCursor(cursor, p.querySchema); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+	}
+
+	trashed, pageEnv, err := p.PostRepository.ListTrashed(pagination)
+	if err != nil {
+		if errors.Is(err, posts.ErrReactionSorts) {
+			return http.StatusBadRequest, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("error fetching trashed posts: %v", err)
+	}
+
+	pageEnv.Data = postsdto.NewPostListResponse(trashed)
+	response := dto.NewPaginationResponse(pageEnv)
+	if err := response.SetCursors(pageEnv, p.cursorSecret); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error encoding cursors: %v", err)
+	}
+	return http.StatusOK, response, nil
+}
+
+func (p *postService) PurgeTrashedPosts(ctx context.Context, retention time.Duration, limit int) (int, error) {
+	purged, err := p.PostRepository.PurgeTrashed(ctx, time.Now().Add(-retention), limit)
+	if err != nil {
+		return 0, fmt.Errorf("error purging trashed posts: %w", err)
+	}
+	if purged > 0 {
+		p.Infof("purged %d trashed posts", purged)
+	}
+	return purged, nil
+}
+
+func (p *postService) TransitionPost(ctx context.Context, uuid string, action Action, ifMatch string) (int, *postsdto.PostResponse, error) {
+	post, err := p.PostRepository.GetByUUID(uuid)
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+
+	statusCode, err := p.checkModify(ctx, post, ifMatch)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	err = applyTransition(post, action, time.Now())
+	if err != nil {
+		if errors.Is(err, ErrUnknownAction) {
+			return http.StatusNotFound, nil, err
+		}
+		return http.StatusConflict, nil, err
+	}
+
+	err = p.PostRepository.Update(post)
+	if err != nil {
+		if errors.Is(err, posts.ErrVersionConflict) {
+			return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error updating post status: %v", err)
+	}
+
+	return http.StatusOK, postsdto.NewPostResponse(post), nil
+}
+
+func (p *postService) PublishScheduledPosts(ctx context.Context, limit int) (int, error) {
+	now := time.Now()
+	published, err := p.PostRepository.PublishDue(ctx, now, limit, func(post *posts.Post) error {
+		return applyTransition(post, ActionPublish, now)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error publishing scheduled posts: %w", err)
+	}
+	for _, post := range published {
+		p.Infof("published scheduled post %s", post.PostId)
+	}
+	return len(published), nil
+}
+
+// checkSchedule validates the publish_at of a post against the one it had before, if
+// any. Only unpublished posts can be scheduled, and only in the future, unless the
+// schedule is left untouched.
+func checkSchedule(post *posts.Post, previous *time.Time, now time.Time) error {
+	if post.PublishAt == nil {
+		return nil
+	}
+	if post.Status != posts.StatusDraft && post.Status != posts.StatusInReview {
+		return ErrScheduleNotAllowed
+	}
+	if previous != nil && previous.Equal(*post.PublishAt) {
+		return nil
+	}
+	if !post.PublishAt.After(now) {
+		return ErrPublishAtInPast
+	}
+	return nil
+}
+
+// renderContent renders the content of a post to sanitized HTML, along with the
+// excerpt and reading time shown in list views.
+func renderContent(post *posts.Post) error {
+	document, err := render.Render(post.Content, post.Format)
+	if err != nil {
+		return fmt.Errorf("error rendering post content: %v", err)
+	}
+	post.ContentHTML = document.HTML
+	post.Excerpt = document.Excerpt
+	post.ReadingTime = document.ReadingTime
+	return nil
+}
+
+// editorOf returns the id of the user a change is made by.
+func editorOf(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Subject
+	}
+	return ""
+}
+
+// canSeeUnpublished reports whether the caller may see posts that aren't published,
+// which is anyone allowed to write posts.
+func canSeeUnpublished(ctx context.Context) bool {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	return ok && principal.Can(auth.ScopePostsWrite)
+}
+
+// listQuery restricts a query of posts to the status asked for and to the posts the
+// caller can see.
+func listQuery(ctx context.Context, request *postsdto.PostListRequest, q *query.Query) *query.Query {
+	switch request.Status {
+	case "":
+	case StatusScheduled:
+		q = onlyScheduled(q)
+	default:
+		q = withFilter(q, "status", query.Eq, request.Status)
+	}
+	if !canSeeUnpublished(ctx) {
+		q = onlyPublished(q)
+	}
+	return q
+}
+
+// onlyPublished restricts a query to published posts, on top of any status filter
+// the caller asked for.
+func onlyPublished(q *query.Query) *query.Query {
+	return withFilter(q, "status", query.Eq, posts.StatusPublished)
+}
+
+// onlyScheduled restricts a query to unpublished posts with a publish_at, soonest
+// first unless the caller asked for another order.
+func onlyScheduled(q *query.Query) *query.Query {
+	q = withFilter(q, "status", query.In, []interface{}{posts.StatusDraft, posts.StatusInReview})
+	q = withFilter(q, "publish_at", query.NotNull, nil)
+	if len(q.Sorts) == 0 {
+		q.Sorts = []query.Sort{{Field: "publish_at", Column: posts.QuerySchema["publish_at"].Column}}
+	}
+	return q
+}
+
+// withFilter returns the query with an extra filter on a field.
+func withFilter(q *query.Query, field string, op query.Operator, value interface{}) *query.Query {
+	if q == nil {
+		q = &query.Query{}
+	}
+	q.Filters = append(q.Filters, posts.QuerySchema.Filter(field, op, value))
+	return q
+}
+
+// checkModify checks the caller is allowed to modify the post and that the If-Match
+// precondition holds. Posts written before authors were recorded have none, only
+// admins can modify those.
+func (p *postService) checkModify(ctx context.Context, post *posts.Post, ifMatch string) (int, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || (principal.Subject != post.AuthorId && !principal.IsAdmin()) {
+		return http.StatusForbidden, ErrNotAuthor
+	}
+	return p.checkIfMatch(post, ifMatch)
+}
+
+// checkIfMatch evaluates the If-Match precondition against the current post version.
+// Counters changing don't make the tags clients hold stale for writes.
+func (p *postService) checkIfMatch(post *posts.Post, ifMatch string) (int, error) {
+	if ifMatch == "" {
+		if p.Config.RequireIfMatch {
+			return http.StatusPreconditionRequired, ErrPreconditionRequired
+		}
+		return http.StatusOK, nil
+	}
+	if !etag.MatchVersion(ifMatch, post.Version) {
+		return http.StatusPreconditionFailed, ErrPreconditionFailed
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,488 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// fakePostRepository keeps posts in memory and records the pagination it was last
+// asked to list or search with. Other calls panic.
+type fakePostRepository struct {
+	posts.PostRepository
+
+	stored     []*posts.Post
+	pagination *data.Pagination
+	mode       posts.SearchMode
+	concurrent *posts.Post
+	revisions  int
+	err        error
+	// batches and transactions record the sizes of the batches created and how
+	// each transaction ended
+	batches      []int
+	transactions []string
+	createErr    error
+}
+
+func (f *fakePostRepository) Search(q string, mode posts.SearchMode, pagination *data.Pagination) ([]posts.SearchResult, *data.Pagination, error) {
+	f.mode, f.pagination = mode, pagination
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	var results []posts.SearchResult
+	for _, post := range f.stored {
+		results = append(results, posts.SearchResult{Post: *post, Score: 1})
+	}
+	return results, pagination, nil
+}
+
+func newTestService(repository *fakePostRepository) *postService {
+	return &postService{
+		PostServiceDeps: PostServiceDeps{Config: &config.Config{}, PostRepository: repository},
+		Logger:          logger.NewStdoutLogger(logger.LoggingLevelNone),
+		cursorSecret:    []byte("secret"),
+		querySchema:     posts.QuerySchema,
+	}
+}
+
+func withPrincipal(principal *auth.Principal) context.Context {
+	return auth.WithPrincipal(context.Background(), principal)
+}
+
+// filtersOn returns the values a query is filtered to on a field.
+func filtersOn(q *query.Query, field string) []interface{} {
+	var values []interface{}
+	if q == nil {
+		return values
+	}
+	for _, filter := range q.Filters {
+		if filter.Field == field {
+			values = append(values, filter.Value)
+		}
+	}
+	return values
+}
+
+func TestSearchPosts(t *testing.T) {
+	editor := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		request       *postsdto.PostSearchRequest
+		pagination    *dto.PaginationRequest
+		err           error
+		wantStatus    int
+		wantMode      posts.SearchMode
+		wantPublished bool
+	}{
+		{
+			name:          "natural language by default",
+			ctx:           context.Background(),
+			request:       &postsdto.PostSearchRequest{Query: "go"},
+			pagination:    &dto.PaginationRequest{Limit: 10},
+			wantStatus:    http.StatusOK,
+			wantMode:      posts.SearchModeNatural,
+			wantPublished: true,
+		},
+		{
+			name:       "boolean mode",
+			ctx:        editor,
+			request:    &postsdto.PostSearchRequest{Query: "+go -java", Mode: "boolean"},
+			pagination: &dto.PaginationRequest{Limit: 10},
+			wantStatus: http.StatusOK,
+			wantMode:   posts.SearchModeBoolean,
+		},
+		{
+			name:       "cursors are rejected",
+			ctx:        context.Background(),
+			request:    &postsdto.PostSearchRequest{Query: "go"},
+			pagination: &dto.PaginationRequest{Limit: 10, Cursor: "abc"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "sorts are rejected",
+			ctx:        context.Background(),
+			request:    &postsdto.PostSearchRequest{Query: "go"},
+			pagination: &dto.PaginationRequest{Limit: 10, Query: &query.Query{Sorts: []query.Sort{{Field: "created_at", Column: "created_at"}}}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed boolean query",
+			ctx:        context.Background(),
+			request:    &postsdto.PostSearchRequest{Query: "+(go", Mode: "boolean"},
+			pagination: &dto.PaginationRequest{Limit: 10},
+			err:        &mysql.MySQLError{Number: mysqlErrSyntax, Message: "syntax error"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "database failure",
+			ctx:        context.Background(),
+			request:    &postsdto.PostSearchRequest{Query: "go"},
+			pagination: &dto.PaginationRequest{Limit: 10},
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakePostRepository{stored: []*posts.Post{{PostId: "p1", Content: "go"}}, err: tt.err}
+
+			status, resp, err := newTestService(repository).SearchPosts(tt.ctx, tt.request, tt.pagination)
+			if status != tt.wantStatus {
+				t.Fatalf("SearchPosts() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				if err == nil {
+					t.Error("SearchPosts() failed without an error")
+				}
+				if tt.err == nil && repository.pagination != nil {
+					t.Error("rejected search reached the database")
+				}
+				return
+			}
+			if repository.mode != tt.wantMode {
+				t.Errorf("searched in %s mode, want %s", repository.mode, tt.wantMode)
+			}
+			published := len(filtersOn(repository.pagination.Query, "status")) > 0
+			if published != tt.wantPublished {
+				t.Errorf("search restricted to published posts = %v, want %v", published, tt.wantPublished)
+			}
+			if results := resp.Data.(*postsdto.PostSearchListResponse).Results; len(results) != 1 || results[0].PostId != "p1" {
+				t.Errorf("SearchPosts() = %+v, want the post found", results)
+			}
+		})
+	}
+}
+
+func (f *fakePostRepository) ListTrashed(pagination *data.Pagination) ([]posts.Post, *data.Pagination, error) {
+	f.pagination = pagination
+	var trashed []posts.Post
+	for _, post := range f.stored {
+		if post.DeletedAt.Valid {
+			trashed = append(trashed, *post)
+		}
+	}
+	return trashed, pagination, nil
+}
+
+func TestCheckModify(t *testing.T) {
+	adminKey := &auth.Principal{Subject: "u2", KeyId: "k1", Scopes: []string{auth.ScopePostsWrite, auth.ScopeAdmin}}
+	writeKey := &auth.Principal{Subject: "u2", KeyId: "k2", Scopes: []string{auth.ScopePostsWrite}}
+
+	tests := []struct {
+		name           string
+		principal      *auth.Principal
+		authorId       string
+		ifMatch        string
+		requireIfMatch bool
+		want           int
+		wantErr        error
+	}{
+		{name: "author", principal: &auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}}, authorId: "u1", want: http.StatusOK},
+		{name: "someone else", principal: &auth.Principal{Subject: "u2", Roles: []string{auth.RoleEditor}}, authorId: "u1", want: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "admin", principal: &auth.Principal{Subject: "u2", Roles: []string{auth.RoleAdmin}}, authorId: "u1", want: http.StatusOK},
+		{name: "api key with the admin scope", principal: adminKey, authorId: "u1", want: http.StatusOK},
+		{name: "api key of an admin without the admin scope", principal: writeKey, authorId: "u1", want: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "anonymous", authorId: "u1", want: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "unowned post", principal: &auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}}, want: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "unowned post by an admin", principal: &auth.Principal{Subject: "u2", Roles: []string{auth.RoleAdmin}}, want: http.StatusOK},
+		{name: "author with a current If-Match", principal: &auth.Principal{Subject: "u1"}, authorId: "u1", ifMatch: `"3"`, want: http.StatusOK},
+		{name: "author with a stale If-Match", principal: &auth.Principal{Subject: "u1"}, authorId: "u1", ifMatch: `"2"`, want: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed},
+		{name: "someone else with a current If-Match", principal: &auth.Principal{Subject: "u2"}, authorId: "u1", ifMatch: `"3"`, want: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "required If-Match", principal: &auth.Principal{Subject: "u1"}, authorId: "u1", requireIfMatch: true, want: http.StatusPreconditionRequired, wantErr: ErrPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(&fakePostRepository{})
+			service.Config.RequireIfMatch = tt.requireIfMatch
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = withPrincipal(tt.principal)
+			}
+
+			got, err := service.checkModify(ctx, &posts.Post{AuthorId: tt.authorId, Version: 3}, tt.ifMatch)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkModify() = %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListTrashedPosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		wantStatus  int
+		wantAuthors []interface{}
+	}{
+		{name: "author sees their own posts", ctx: withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}}), wantStatus: http.StatusOK, wantAuthors: []interface{}{"u1"}},
+		{name: "admin sees every post", ctx: withPrincipal(&auth.Principal{Subject: "u2", Roles: []string{auth.RoleAdmin}}), wantStatus: http.StatusOK},
+		{name: "anonymous", ctx: context.Background(), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakePostRepository{}
+			status, _, err := newTestService(repository).ListTrashedPosts(tt.ctx, &dto.PaginationRequest{Limit: 10})
+			if status != tt.wantStatus {
+				t.Fatalf("ListTrashedPosts() = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				if repository.pagination != nil {
+					t.Error("trashed posts were listed")
+				}
+				return
+			}
+			if got := filtersOn(repository.pagination.Query, "author_id"); !reflect.DeepEqual(got, tt.wantAuthors) {
+				t.Errorf("trashed posts filtered to authors %v, want %v", got, tt.wantAuthors)
+			}
+		})
+	}
+}
+
+func (f *fakePostRepository) SoftDelete(post *posts.Post) error {
+	if f.err != nil {
+		return f.err
+	}
+	post.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (f *fakePostRepository) Restore(post *posts.Post) error {
+	if f.err != nil {
+		return f.err
+	}
+	post.Version++
+	post.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func (f *fakePostRepository) HardDelete(post *posts.Post) error {
+	if f.err != nil {
+		return f.err
+	}
+	for i, stored := range f.stored {
+		if stored == post {
+			f.stored = append(f.stored[:i], f.stored[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func TestDeletePost(t *testing.T) {
+	author := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+	admin := withPrincipal(&auth.Principal{Subject: "u2", Roles: []string{auth.RoleAdmin}})
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		trashed     bool
+		purge       bool
+		ifMatch     string
+		err         error
+		wantStatus  int
+		wantErr     error
+		wantTrashed bool
+		wantPurged  bool
+	}{
+		{name: "author trashes their post", ctx: author, wantStatus: http.StatusOK, wantTrashed: true},
+		{name: "someone else", ctx: withPrincipal(&auth.Principal{Subject: "u3", Roles: []string{auth.RoleEditor}}), wantStatus: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "stale If-Match", ctx: author, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed},
+		{name: "changed in the meantime", ctx: author, err: posts.ErrVersionConflict, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed},
+		{name: "already trashed", ctx: author, trashed: true, wantStatus: http.StatusNotFound, wantErr: gorm.ErrRecordNotFound, wantTrashed: true},
+		{name: "admin purges a post", ctx: admin, purge: true, wantStatus: http.StatusOK, wantPurged: true},
+		{name: "admin purges a trashed post", ctx: admin, trashed: true, purge: true, wantStatus: http.StatusOK, wantPurged: true},
+		{name: "authors can't purge", ctx: author, trashed: true, purge: true, wantStatus: http.StatusForbidden, wantErr: ErrPurgeNotAllowed, wantTrashed: true},
+		{name: "api keys without the admin scope can't purge", ctx: withPrincipal(&auth.Principal{Subject: "u1", KeyId: "k1", Scopes: []string{auth.ScopePostsWrite}}), purge: true, wantStatus: http.StatusForbidden, wantErr: ErrPurgeNotAllowed},
+		{name: "stale If-Match on purge", ctx: admin, purge: true, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &posts.Post{PostId: "p1", AuthorId: "u1", Version: 2}
+			if tt.trashed {
+				post.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			}
+			repository := &fakePostRepository{stored: []*posts.Post{post}, err: tt.err}
+
+			status, _, err := newTestService(repository).DeletePost(tt.ctx, "p1", tt.purge, tt.ifMatch)
+			if status != tt.wantStatus || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeletePost() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if purged := len(repository.stored) == 0; purged != tt.wantPurged {
+				t.Errorf("post purged = %v, want %v", purged, tt.wantPurged)
+			}
+			if !tt.wantPurged && post.DeletedAt.Valid != tt.wantTrashed {
+				t.Errorf("post trashed = %v, want %v", post.DeletedAt.Valid, tt.wantTrashed)
+			}
+		})
+	}
+}
+
+func TestRestorePost(t *testing.T) {
+	author := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		trashed     bool
+		ifMatch     string
+		err         error
+		wantStatus  int
+		wantErr     error
+		wantTrashed bool
+	}{
+		{name: "author restores their post", ctx: author, trashed: true, ifMatch: `"2"`, wantStatus: http.StatusOK},
+		{name: "admin restores someone else's post", ctx: withPrincipal(&auth.Principal{Subject: "u2", Roles: []string{auth.RoleAdmin}}), trashed: true, wantStatus: http.StatusOK},
+		{name: "someone else", ctx: withPrincipal(&auth.Principal{Subject: "u3", Roles: []string{auth.RoleEditor}}), trashed: true, wantStatus: http.StatusForbidden, wantErr: ErrNotAuthor, wantTrashed: true},
+		{name: "stale If-Match", ctx: author, trashed: true, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed, wantTrashed: true},
+		{name: "changed in the meantime", ctx: author, trashed: true, err: posts.ErrVersionConflict, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed, wantTrashed: true},
+		{name: "not trashed", ctx: author, wantStatus: http.StatusNotFound, wantErr: gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &posts.Post{PostId: "p1", AuthorId: "u1", Version: 2}
+			if tt.trashed {
+				post.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			}
+			repository := &fakePostRepository{stored: []*posts.Post{post}, err: tt.err}
+
+			status, resp, err := newTestService(repository).RestorePost(tt.ctx, "p1", tt.ifMatch)
+			if status != tt.wantStatus || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RestorePost() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if post.DeletedAt.Valid != tt.wantTrashed {
+				t.Errorf("post trashed = %v, want %v", post.DeletedAt.Valid, tt.wantTrashed)
+			}
+			if status == http.StatusOK && resp.Version != 3 {
+				t.Errorf("RestorePost() version = %d, want it bumped", resp.Version)
+			}
+		})
+	}
+}
+
+func (f *fakePostRepository) GetByUUID(uuid string) (*posts.Post, error) {
+	for _, post := range f.stored {
+		if post.PostId == uuid && !post.DeletedAt.Valid {
+			return post, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakePostRepository) GetTrashedByUUID(uuid string) (*posts.Post, error) {
+	for _, post := range f.stored {
+		if post.PostId == uuid && post.DeletedAt.Valid {
+			return post, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Upsert creates the post, unless a concurrent post was set, in which case it's
+// replaced the way it would be if it had been created in the meantime.
+func (f *fakePostRepository) Upsert(post *posts.Post, canReplace func(existing *posts.Post) error) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	if f.concurrent != nil {
+		if err := canReplace(f.concurrent); err != nil {
+			return false, err
+		}
+		post.AuthorId = f.concurrent.AuthorId
+		return false, nil
+	}
+	f.stored = append(f.stored, post)
+	return true, nil
+}
+
+func (f *fakePostRepository) Update(post *posts.Post) error {
+	post.Version++
+	return nil
+}
+
+func (f *fakePostRepository) Revise(post *posts.Post, editorId string) (*posts.PostRevision, error) {
+	post.Version++
+	f.revisions++
+	return &posts.PostRevision{PostId: post.PostId, EditorId: editorId, Content: post.Content}, nil
+}
+
+func TestUpsertPost(t *testing.T) {
+	const postId = "9b2c9a4e-3f5d-4c1e-8a7b-6d5e4f3a2b1c"
+	author := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}})
+	other := withPrincipal(&auth.Principal{Subject: "u2", Roles: []string{auth.RoleEditor}})
+	trashed := func() *posts.Post {
+		post := &posts.Post{PostId: postId, AuthorId: "u1", Version: 2}
+		post.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		return post
+	}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		postId        string
+		stored        *posts.Post
+		concurrent    *posts.Post
+		err           error
+		ifMatch       string
+		wantStatus    int
+		wantErr       error
+		wantRevisions int
+	}{
+		{name: "creates at the post id", ctx: author, postId: postId, wantStatus: http.StatusCreated},
+		{name: "replaces the post at the post id", ctx: author, postId: postId, stored: &posts.Post{PostId: postId, AuthorId: "u1", Content: "old", Format: "plain", Version: 2}, wantStatus: http.StatusOK, wantRevisions: 1},
+		{name: "replaces with a current If-Match", ctx: author, postId: postId, stored: &posts.Post{PostId: postId, AuthorId: "u1", Content: "old", Format: "plain", Version: 2}, ifMatch: `"2"`, wantStatus: http.StatusOK, wantRevisions: 1},
+		{name: "replacing with the same content makes no revision", ctx: author, postId: postId, stored: &posts.Post{PostId: postId, AuthorId: "u1", Content: "hi", Format: "plain", Version: 2}, wantStatus: http.StatusOK},
+		{name: "stale If-Match", ctx: author, postId: postId, stored: &posts.Post{PostId: postId, AuthorId: "u1", Version: 2}, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed},
+		{name: "If-Match on a post that doesn't exist", ctx: author, postId: postId, ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantErr: ErrPreconditionFailed},
+		{name: "someone else's post", ctx: other, postId: postId, stored: &posts.Post{PostId: postId, AuthorId: "u1", Version: 2}, wantStatus: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "trashed post", ctx: author, postId: postId, stored: trashed(), wantStatus: http.StatusConflict, wantErr: ErrPostTrashed},
+		{name: "slug taken", ctx: author, postId: postId, err: posts.ErrSlugTaken, wantStatus: http.StatusConflict, wantErr: posts.ErrSlugTaken},
+		{name: "created in the meantime by the caller", ctx: author, postId: postId, concurrent: &posts.Post{PostId: postId, AuthorId: "u1", Version: 1}, wantStatus: http.StatusOK},
+		{name: "created in the meantime by someone else", ctx: other, postId: postId, concurrent: &posts.Post{PostId: postId, AuthorId: "u1", Version: 1}, wantStatus: http.StatusForbidden, wantErr: ErrNotAuthor},
+		{name: "trashed in the meantime", ctx: author, postId: postId, concurrent: trashed(), wantStatus: http.StatusConflict, wantErr: ErrPostTrashed},
+		{name: "invalid post id", ctx: author, postId: "p1", wantStatus: http.StatusBadRequest, wantErr: ErrInvalidPostId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakePostRepository{concurrent: tt.concurrent, err: tt.err}
+			if tt.stored != nil {
+				repository.stored = append(repository.stored, tt.stored)
+			}
+
+			status, resp, err := newTestService(repository).UpsertPost(tt.ctx, tt.postId, &postsdto.PostRequest{Title: "Hello", Content: "hi"}, tt.ifMatch)
+			if status != tt.wantStatus || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpsertPost() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if repository.revisions != tt.wantRevisions {
+				t.Errorf("made %d revisions, want %d", repository.revisions, tt.wantRevisions)
+			}
+			if err != nil {
+				return
+			}
+			if resp.PostId != tt.postId || resp.Content != "hi" || resp.Author == nil || resp.Author.Id != "u1" {
+				t.Errorf("UpsertPost() = %+v, want the post at %s by u1", resp, tt.postId)
+			}
+		})
+	}
+}
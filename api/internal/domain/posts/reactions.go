@@ -0,0 +1,63 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"gorm.io/gorm"
+)
+
+var ErrUnknownReaction = errors.New("unknown reaction kind")
+
+func (p *postService) ReactToPost(ctx context.Context, uuid string, kind string) (int, *postsdto.PostResponse, error) {
+	return p.react(ctx, uuid, kind, p.PostReactionRepository.React)
+}
+
+func (p *postService) UnreactToPost(ctx context.Context, uuid string, kind string) (int, *postsdto.PostResponse, error) {
+	return p.react(ctx, uuid, kind, p.PostReactionRepository.Unreact)
+}
+
+// react applies a reaction change of the caller to a post and returns the post with
+// its updated reaction counts. Changes are idempotent, reacting twice counts once.
+func (p *postService) react(ctx context.Context, uuid string, kind string, apply func(postId, kind, principalId string) (bool, error)) (int, *postsdto.PostResponse, error) {
+	if !p.isReactionKind(kind) {
+		return http.StatusNotFound, nil, ErrUnknownReaction
+	}
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, nil, fmt.Errorf("authentication required")
+	}
+
+	post, err := p.PostRepository.GetByUUID(uuid)
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+	if post.Status != posts.StatusPublished && !canSeeUnpublished(ctx) {
+		return http.StatusNotFound, nil, gorm.ErrRecordNotFound
+	}
+
+	if _, err := apply(post.PostId, kind, principal.Subject); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error reacting to post: %v", err)
+	}
+
+	post, err = p.PostRepository.GetByUUID(uuid)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching post: %v", err)
+	}
+	return http.StatusOK, postsdto.NewPostResponse(post), nil
+}
+
+func (p *postService) isReactionKind(kind string) bool {
+	for _, k := range p.Config.ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"gorm.io/gorm"
+)
+
+// fakePostReactionRepository keeps the reactions of the posts of a fakePostRepository
+// in step with their counts, the way the database does.
+type fakePostReactionRepository struct {
+	posts     *fakePostRepository
+	reactions map[string]bool
+}
+
+func (f *fakePostReactionRepository) React(postId, kind, principalId string) (bool, error) {
+	key := postId + "/" + kind + "/" + principalId
+	if f.reactions[key] {
+		return false, nil
+	}
+	f.reactions[key] = true
+	return true, f.count(postId, kind, 1)
+}
+
+func (f *fakePostReactionRepository) Unreact(postId, kind, principalId string) (bool, error) {
+	key := postId + "/" + kind + "/" + principalId
+	if !f.reactions[key] {
+		return false, nil
+	}
+	delete(f.reactions, key)
+	return true, f.count(postId, kind, -1)
+}
+
+func (f *fakePostReactionRepository) count(postId, kind string, delta int) error {
+	post, err := f.posts.GetByUUID(postId)
+	if err != nil {
+		return err
+	}
+	if post.Reactions == nil {
+		post.Reactions = map[string]uint{}
+	}
+	post.Reactions[kind] = uint(int(post.Reactions[kind]) + delta)
+	return nil
+}
+
+func TestReactToPost(t *testing.T) {
+	reader := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleViewer}})
+	repository := &fakePostRepository{stored: []*posts.Post{
+		{PostId: "p1", Status: posts.StatusPublished, Version: 1},
+		{PostId: "p2", Status: posts.StatusDraft, Version: 1},
+	}}
+	service := newTestService(repository)
+	service.Config.ReactionKinds = []string{"like", "love"}
+	service.PostReactionRepository = &fakePostReactionRepository{posts: repository, reactions: map[string]bool{}}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		postId     string
+		kind       string
+		unreact    bool
+		wantStatus int
+		wantErr    error
+		wantCount  uint
+	}{
+		{name: "reacting counts", ctx: reader, postId: "p1", kind: "like", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "reacting again counts once", ctx: reader, postId: "p1", kind: "like", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "unreacting uncounts", ctx: reader, postId: "p1", kind: "like", unreact: true, wantStatus: http.StatusOK},
+		{name: "unreacting again changes nothing", ctx: reader, postId: "p1", kind: "like", unreact: true, wantStatus: http.StatusOK},
+		{name: "unknown kind", ctx: reader, postId: "p1", kind: "hate", wantStatus: http.StatusNotFound, wantErr: ErrUnknownReaction},
+		{name: "anonymous", ctx: context.Background(), postId: "p1", kind: "like", wantStatus: http.StatusUnauthorized},
+		{name: "post readers can't see", ctx: reader, postId: "p2", kind: "like", wantStatus: http.StatusNotFound, wantErr: gorm.ErrRecordNotFound},
+		{name: "missing post", ctx: reader, postId: "p3", kind: "like", wantStatus: http.StatusNotFound, wantErr: gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			react := service.ReactToPost
+			if tt.unreact {
+				react = service.UnreactToPost
+			}
+			status, resp, err := react(tt.ctx, tt.postId, tt.kind)
+			if status != tt.wantStatus || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("react() = %d, %v, want %d, %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if resp.Reactions[tt.kind] != tt.wantCount {
+				t.Errorf("react() %s count = %d, want %d", tt.kind, resp.Reactions[tt.kind], tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestReactToPostChangesTheETag(t *testing.T) {
+	repository := &fakePostRepository{stored: []*posts.Post{{PostId: "p1", Status: posts.StatusPublished, Version: 1}}}
+	service := newTestService(repository)
+	service.Config.ReactionKinds = []string{"like"}
+	service.PostReactionRepository = &fakePostReactionRepository{posts: repository, reactions: map[string]bool{}}
+	ctx := withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleViewer}})
+
+	_, before, _ := service.GetPost(ctx, "p1")
+	_, after, err := service.ReactToPost(ctx, "p1", "like")
+	if err != nil {
+		t.Fatalf("ReactToPost() error = %v", err)
+	}
+	if before.ETag() == after.ETag() {
+		t.Error("reacting kept the ETag, caches would serve stale counts")
+	}
+	if after.Version != before.Version {
+		t.Errorf("reacting bumped the version to %d, If-Match on edits would fail", after.Version)
+	}
+}
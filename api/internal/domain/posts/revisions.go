@@ -0,0 +1,123 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+)
+
+// diffContext is the number of unchanged lines shown around each change of a diff.
+const diffContext = 3
+
+func (p *postService) ListRevisions(ctx context.Context, uuid string, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	if _, err := p.PostRepository.GetByUUID(uuid); err != nil {
+		return http.StatusNotFound, nil, err
+	}
+
+	pagination := dto.ModelFromPaginationRequest(paginationRequest)
+	if paginationRequest.Cursor != "" {
+		cursor, err := data.DecodeCursor(paginationRequest.Cursor, p.cursorSecret)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if err := pagination.SetCursor(cursor, posts.RevisionQuerySchema); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+	}
+
+	revisions, pageEnv, err := p.PostRevisionRepository.List(uuid, pagination)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error fetching post revisions: %v", err)
+	}
+
+	pageEnv.Data = postsdto.NewRevisionListResponse(revisions)
+	response := dto.NewPaginationResponse(pageEnv)
+	if err := response.SetCursors(pageEnv, p.cursorSecret); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error encoding cursors: %v", err)
+	}
+	return http.StatusOK, response, nil
+}
+
+func (p *postService) GetRevision(ctx context.Context, uuid string, revision uint) (int, *postsdto.RevisionResponse, error) {
+	rev, statusCode, err := p.getRevision(uuid, revision)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	return http.StatusOK, postsdto.NewRevisionResponse(rev), nil
+}
+
+func (p *postService) DiffRevisions(ctx context.Context, uuid string, from, to uint) (int, *postsdto.RevisionDiffResponse, error) {
+	fromRev, statusCode, err := p.getRevision(uuid, from)
+	if err != nil {
+		return statusCode, nil, err
+	}
+	toRev, statusCode, err := p.getRevision(uuid, to)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromRev.Content),
+		B:        difflib.SplitLines(toRev.Content),
+		FromFile: fmt.Sprintf("revision %d", from),
+		ToFile:   fmt.Sprintf("revision %d", to),
+		Context:  diffContext,
+	})
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error computing diff: %v", err)
+	}
+
+	return http.StatusOK, postsdto.NewRevisionDiffResponse(from, to, diff), nil
+}
+
+func (p *postService) RestoreRevision(ctx context.Context, uuid string, revision uint, ifMatch string) (int, *postsdto.PostResponse, error) {
+	post, err := p.PostRepository.GetByUUID(uuid)
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+
+	statusCode, err := p.checkModify(ctx, post, ifMatch)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	rev, statusCode, err := p.getRevision(uuid, revision)
+	if err != nil {
+		return statusCode, nil, err
+	}
+
+	post.Content = rev.Content
+	post.Format = rev.Format
+	if err := renderContent(post); err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	_, err = p.PostRepository.Revise(post, editorOf(ctx))
+	if err != nil {
+		if errors.Is(err, posts.ErrVersionConflict) {
+			return http.StatusPreconditionFailed, nil, ErrPreconditionFailed
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error restoring revision: %v", err)
+	}
+
+	return http.StatusOK, postsdto.NewPostResponse(post), nil
+}
+
+func (p *postService) getRevision(uuid string, revision uint) (*posts.PostRevision, int, error) {
+	rev, err := p.PostRevisionRepository.Get(uuid, revision)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, http.StatusNotFound, fmt.Errorf("revision %d of post %s not found", revision, uuid)
+		}
+		return nil, http.StatusInternalServerError, fmt.Errorf("unexpected error fetching revision: %v", err)
+	}
+	return rev, http.StatusOK, nil
+}
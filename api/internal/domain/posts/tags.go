@@ -0,0 +1,57 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+)
+
+// defaultTagSort lists the most used tags first.
+var defaultTagSort = []query.Sort{
+	{Field: "post_count", Column: "post_count", Desc: true},
+	{Field: "name", Column: "name"},
+}
+
+func (p *postService) ListTags(ctx context.Context, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	if paginationRequest.Query == nil {
+		paginationRequest.Query = &query.Query{}
+	}
+	if len(paginationRequest.Query.Sorts) == 0 && paginationRequest.Cursor == "" {
+		paginationRequest.Query.Sorts = defaultTagSort
+	}
+
+	pagination := dto.ModelFromPaginationRequest(paginationRequest)
+	if paginationRequest.Cursor != "" {
+		cursor, err := data.DecodeCursor(paginationRequest.Cursor, p.cursorSecret)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if err := pagination.SetCursor(cursor, posts.TagQuerySchema); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+	}
+
+	// readers only get to know about tags of published posts
+	tags, pageEnv, err := p.TagRepository.List(!canSeeUnpublished(ctx), pagination)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error fetching tags: %v", err)
+	}
+
+	pageEnv.Data = postsdto.NewTagListResponse(tags)
+	response := dto.NewPaginationResponse(pageEnv)
+	if err := response.SetCursors(pageEnv, p.cursorSecret); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error encoding cursors: %v", err)
+	}
+	return http.StatusOK, response, nil
+}
+
+func (p *postService) ListTagPosts(ctx context.Context, tag string, paginationRequest *dto.PaginationRequest) (int, *dto.PaginationResponse, error) {
+	paginationRequest.Query = withFilter(paginationRequest.Query, "tag", query.Eq, tag)
+	return p.ListPosts(ctx, &postsdto.PostListRequest{}, paginationRequest)
+}
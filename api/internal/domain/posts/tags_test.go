@@ -0,0 +1,90 @@
+package posts
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+)
+
+// fakeTagRepository answers with the tags it's given and records how it was asked
+// to list them.
+type fakeTagRepository struct {
+	tags          []posts.TagCount
+	publishedOnly bool
+	pagination    *data.Pagination
+}
+
+func (f *fakeTagRepository) List(publishedOnly bool, pagination *data.Pagination) ([]posts.TagCount, *data.Pagination, error) {
+	f.publishedOnly, f.pagination = publishedOnly, pagination
+	return f.tags, pagination, nil
+}
+
+func (f *fakePostRepository) List(pagination *data.Pagination) ([]posts.Post, *data.Pagination, error) {
+	f.pagination = pagination
+	var list []posts.Post
+	for _, post := range f.stored {
+		list = append(list, *post)
+	}
+	return list, pagination, nil
+}
+
+func TestListTags(t *testing.T) {
+	byName := &query.Query{Sorts: []query.Sort{{Field: "name", Column: "name"}}}
+
+	tests := []struct {
+		name              string
+		ctx               context.Context
+		query             *query.Query
+		wantPublishedOnly bool
+		wantSorts         []query.Sort
+	}{
+		{name: "readers count published posts", ctx: context.Background(), wantPublishedOnly: true, wantSorts: defaultTagSort},
+		{name: "writers count every post", ctx: withPrincipal(&auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}}), wantSorts: defaultTagSort},
+		{name: "sorts asked for are kept", ctx: context.Background(), query: byName, wantPublishedOnly: true, wantSorts: byName.Sorts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := &fakeTagRepository{tags: []posts.TagCount{{Tag: posts.Tag{ID: 1, Name: "go"}, PostCount: 3}}}
+			service := newTestService(&fakePostRepository{})
+			service.TagRepository = tags
+
+			status, resp, err := service.ListTags(tt.ctx, &dto.PaginationRequest{Limit: 10, Query: tt.query})
+			if status != http.StatusOK {
+				t.Fatalf("ListTags() = %d, %v", status, err)
+			}
+			if tags.publishedOnly != tt.wantPublishedOnly {
+				t.Errorf("counted published posts only = %v, want %v", tags.publishedOnly, tt.wantPublishedOnly)
+			}
+			if !reflect.DeepEqual(tags.pagination.Query.Sorts, tt.wantSorts) {
+				t.Errorf("tags sorted by %v, want %v", tags.pagination.Query.Sorts, tt.wantSorts)
+			}
+			if got := resp.Data.(*postsdto.TagListResponse).Tags; len(got) != 1 || got[0].Name != "go" || got[0].PostCount != 3 {
+				t.Errorf("ListTags() = %+v, want the tag with its post count", got)
+			}
+		})
+	}
+}
+
+func TestListTagPosts(t *testing.T) {
+	repository := &fakePostRepository{stored: []*posts.Post{{PostId: "p1", Tags: []string{"go"}}}}
+
+	status, _, err := newTestService(repository).ListTagPosts(context.Background(), "Go", &dto.PaginationRequest{Limit: 10})
+	if status != http.StatusOK {
+		t.Fatalf("ListTagPosts() = %d, %v", status, err)
+	}
+	if got := filtersOn(repository.pagination.Query, "tag"); !reflect.DeepEqual(got, []interface{}{"Go"}) {
+		t.Errorf("posts filtered to tags %v, want the tag asked for", got)
+	}
+	if got := filtersOn(repository.pagination.Query, "status"); len(got) == 0 {
+		t.Error("readers were listed unpublished posts")
+	}
+}
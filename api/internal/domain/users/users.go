@@ -0,0 +1,236 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/users"
+	usersdto "github.com/pedromspeixoto/posts-api/internal/dto/users"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidRefresh     = errors.New("invalid, expired or revoked refresh token")
+	ErrPasswordTooLong    = fmt.Errorf("password must be at most %d bytes long", usersdto.MaxPasswordBytes)
+)
+
+// dummyHash is compared against when a login email is unknown, so that unknown and
+// known emails take as long to answer.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("posts-api-dummy-password"), bcrypt.DefaultCost)
+
+// UserService provides methods pertaining to managing users and their sessions.
+type UserService interface {
+	// Register creates a new local user account
+	Register(ctx context.Context, request *usersdto.RegisterRequest) (int, *usersdto.UserResponse, error)
+	// Login exchanges user credentials for an access and a refresh token
+	Login(ctx context.Context, request *usersdto.LoginRequest) (int, *usersdto.TokenResponse, error)
+	// Refresh exchanges a refresh token for a new access and refresh token, revoking the old one
+	Refresh(ctx context.Context, request *usersdto.RefreshRequest) (int, *usersdto.TokenResponse, error)
+	// Logout revokes a refresh token
+	Logout(ctx context.Context, request *usersdto.RefreshRequest) (int, *usersdto.TokenResponse, error)
+}
+
+type UserServiceDeps struct {
+	fx.In
+
+	LifeCycle              fx.Lifecycle
+	Config                 *config.Config
+	Logger                 *logger.LoggingClient
+	TokenIssuer            *auth.TokenIssuer
+	UserRepository         users.UserRepository
+	RefreshTokenRepository users.RefreshTokenRepository
+}
+
+type userService struct {
+	UserServiceDeps
+	logger.Logger
+}
+
+func NewUserService(deps UserServiceDeps) UserService {
+	service := &userService{
+		UserServiceDeps: deps,
+		Logger:          deps.Logger.GetLogger(),
+	}
+
+	deps.LifeCycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return service.bootstrapAdmin()
+		},
+	})
+
+	return service
+}
+
+func (u *userService) Register(ctx context.Context, request *usersdto.RegisterRequest) (int, *usersdto.UserResponse, error) {
+	if len(request.Password) > usersdto.MaxPasswordBytes {
+		return http.StatusBadRequest, nil, ErrPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error hashing password: %v", err)
+	}
+
+	model := usersdto.ModelFromRegisterRequest(request, string(hash), u.Config.DefaultUserRole)
+	err = u.UserRepository.Create(model)
+	if err != nil {
+		if errors.Is(err, users.ErrDuplicateEmail) {
+			return http.StatusConflict, nil, err
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("error creating new user: %v", err)
+	}
+
+	return http.StatusCreated, usersdto.NewUserResponse(model), nil
+}
+
+func (u *userService) Login(ctx context.Context, request *usersdto.LoginRequest) (int, *usersdto.TokenResponse, error) {
+	user, err := u.UserRepository.GetByEmail(usersdto.NormalizeEmail(request.Email))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(request.Password))
+			return http.StatusUnauthorized, nil, ErrInvalidCredentials
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching user: %v", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(request.Password)); err != nil {
+		return http.StatusUnauthorized, nil, ErrInvalidCredentials
+	}
+
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error generating refresh token: %v", err)
+	}
+	err = u.RefreshTokenRepository.Create(u.newRefreshToken(user.UserId, refreshHash))
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error storing refresh token: %v", err)
+	}
+
+	return u.issue(user, refreshToken)
+}
+
+func (u *userService) Refresh(ctx context.Context, request *usersdto.RefreshRequest) (int, *usersdto.TokenResponse, error) {
+	token, err := u.RefreshTokenRepository.GetByHash(auth.HashToken(request.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusUnauthorized, nil, ErrInvalidRefresh
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching refresh token: %v", err)
+	}
+
+	// a revoked token being presented again means it leaked, end every session of the user
+	if token.RevokedAt != nil {
+		if err := u.RefreshTokenRepository.RevokeAllForUser(token.UserId); err != nil {
+			return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error revoking refresh tokens: %v", err)
+		}
+		return http.StatusUnauthorized, nil, ErrInvalidRefresh
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return http.StatusUnauthorized, nil, ErrInvalidRefresh
+	}
+
+	user, err := u.UserRepository.GetByUUID(token.UserId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusUnauthorized, nil, ErrInvalidRefresh
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching user: %v", err)
+	}
+
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error generating refresh token: %v", err)
+	}
+	err = u.RefreshTokenRepository.Rotate(token, u.newRefreshToken(user.UserId, refreshHash))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusUnauthorized, nil, ErrInvalidRefresh
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("error rotating refresh token: %v", err)
+	}
+
+	return u.issue(user, refreshToken)
+}
+
+func (u *userService) Logout(ctx context.Context, request *usersdto.RefreshRequest) (int, *usersdto.TokenResponse, error) {
+	token, err := u.RefreshTokenRepository.GetByHash(auth.HashToken(request.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusUnauthorized, nil, ErrInvalidRefresh
+		}
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error fetching refresh token: %v", err)
+	}
+
+	err = u.RefreshTokenRepository.Revoke(token)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("unexpected error revoking refresh token: %v", err)
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+func (u *userService) issue(user *users.User, refreshToken string) (int, *usersdto.TokenResponse, error) {
+	accessToken, expiresAt, err := u.TokenIssuer.Issue(&auth.Principal{
+		Subject: user.UserId,
+		Roles:   []string{user.Role},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("error issuing access token: %v", err)
+	}
+
+	return http.StatusOK, usersdto.NewTokenResponse(accessToken, expiresAt, refreshToken), nil
+}
+
+func (u *userService) newRefreshToken(userId, hash string) *users.RefreshToken {
+	return &users.RefreshToken{
+		UserId:    userId,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(u.Config.JWTRefreshTokenTTL),
+	}
+}
+
+// bootstrapAdmin creates the admin account from the ADMIN_* settings, if configured
+// and not registered yet.
+func (u *userService) bootstrapAdmin() error {
+	admin := u.Config.Admin
+	if admin.Email == "" || admin.Password == "" {
+		return nil
+	}
+
+	_, err := u.UserRepository.GetByEmail(usersdto.NormalizeEmail(admin.Email))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("unexpected error fetching admin user: %v", err)
+	}
+
+	if len(admin.Password) > usersdto.MaxPasswordBytes {
+		return fmt.Errorf("error creating admin user: %v", ErrPasswordTooLong)
+	}
+
+	role := admin.Role
+	if role == "" {
+		role = auth.RoleAdmin
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hashing admin password: %v", err)
+	}
+
+	model := usersdto.ModelFromRegisterRequest(&usersdto.RegisterRequest{Email: admin.Email}, string(hash), role)
+	if err := u.UserRepository.Create(model); err != nil && !errors.Is(err, users.ErrDuplicateEmail) {
+		return fmt.Errorf("error creating admin user: %v", err)
+	}
+	u.Infof("created admin user %s", model.Email)
+	return nil
+}
@@ -0,0 +1,408 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/users"
+	usersdto "github.com/pedromspeixoto/posts-api/internal/dto/users"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// fakeUsers is an in memory UserRepository.
+type fakeUsers struct {
+	users []*users.User
+}
+
+func (f *fakeUsers) GetByUUID(uuid string) (*users.User, error) {
+	for _, user := range f.users {
+		if user.UserId == uuid {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUsers) GetByEmail(email string) (*users.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUsers) Create(user *users.User) error {
+	if _, err := f.GetByEmail(user.Email); err == nil {
+		return users.ErrDuplicateEmail
+	}
+	f.users = append(f.users, user)
+	return nil
+}
+
+// fakeRefreshTokens is an in memory RefreshTokenRepository.
+type fakeRefreshTokens struct {
+	tokens []*users.RefreshToken
+}
+
+func (f *fakeRefreshTokens) GetByHash(hash string) (*users.RefreshToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeRefreshTokens) Create(token *users.RefreshToken) error {
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+func (f *fakeRefreshTokens) Rotate(old *users.RefreshToken, replacement *users.RefreshToken) error {
+	stored := f.stored(old.TokenHash)
+	if stored == nil || stored.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	stored.RevokedAt = &now
+	return f.Create(replacement)
+}
+
+func (f *fakeRefreshTokens) Revoke(token *users.RefreshToken) error {
+	if stored := f.stored(token.TokenHash); stored != nil && stored.RevokedAt == nil {
+		now := time.Now()
+		stored.RevokedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeRefreshTokens) RevokeAllForUser(userId string) error {
+	for _, token := range f.tokens {
+		if token.UserId == userId {
+			f.Revoke(token)
+		}
+	}
+	return nil
+}
+
+func (f *fakeRefreshTokens) stored(hash string) *users.RefreshToken {
+	for _, token := range f.tokens {
+		if token.TokenHash == hash {
+			return token
+		}
+	}
+	return nil
+}
+
+func (f *fakeRefreshTokens) active(userId string) int {
+	count := 0
+	for _, token := range f.tokens {
+		if token.UserId == userId && token.RevokedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// newTestService returns a user service backed by in memory repositories, along with
+// a verifier for the access tokens it issues.
+func newTestService(t *testing.T, cfg *config.Config) (*userService, *fakeUsers, *fakeRefreshTokens, *auth.TokenVerifier) {
+	t.Helper()
+	cfg.JWTSecret = "secret"
+	cfg.JWTAccessTokenTTL = time.Minute
+	cfg.JWTRefreshTokenTTL = time.Hour
+	cfg.DefaultUserRole = auth.RoleViewer
+	cfg.LoggerLevel = logger.LoggingLevelNone
+
+	var loggingClient *logger.LoggingClient
+	var issuer *auth.TokenIssuer
+	var verifier *auth.TokenVerifier
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(cfg),
+		logger.ProvideLogger(),
+		auth.ProvideAuth(),
+		fx.Populate(&loggingClient, &issuer, &verifier),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("fx.New() error = %v", err)
+	}
+
+	userRepository, tokenRepository := &fakeUsers{}, &fakeRefreshTokens{}
+	service := &userService{
+		UserServiceDeps: UserServiceDeps{
+			Config:                 cfg,
+			TokenIssuer:            issuer,
+			UserRepository:         userRepository,
+			RefreshTokenRepository: tokenRepository,
+		},
+		Logger: loggingClient.GetLogger(),
+	}
+	return service, userRepository, tokenRepository, verifier
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		password   string
+		wantStatus int
+		wantErr    error
+	}{
+		{name: "registered", email: " Bob@Example.com", password: "correct horse", wantStatus: http.StatusCreated},
+		{name: "email taken in another case", email: "ADA@example.com", password: "correct horse", wantStatus: http.StatusConflict, wantErr: users.ErrDuplicateEmail},
+		{name: "72 bytes", email: "b@example.com", password: strings.Repeat("é", 36), wantStatus: http.StatusCreated},
+		{name: "over 72 bytes in fewer runes", email: "c@example.com", password: strings.Repeat("é", 37), wantStatus: http.StatusBadRequest, wantErr: ErrPasswordTooLong},
+	}
+
+	service, userRepository, _, _ := newTestService(t, &config.Config{})
+	userRepository.users = []*users.User{{UserId: "u0", Email: "ada@example.com"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp, err := service.Register(context.Background(), &usersdto.RegisterRequest{Email: tt.email, Password: tt.password})
+			if status != tt.wantStatus {
+				t.Fatalf("Register() status = %d, want %d (error %v)", status, tt.wantStatus, err)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Register() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if resp.Email != usersdto.NormalizeEmail(tt.email) || resp.Role != auth.RoleViewer {
+				t.Errorf("Register() = %+v, want the normalized email and the default role", resp)
+			}
+			user, err := userRepository.GetByEmail(resp.Email)
+			if err != nil {
+				t.Fatalf("registered user not stored: %v", err)
+			}
+			if user.PasswordHash == "" || user.PasswordHash == tt.password {
+				t.Error("password stored without being hashed")
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	service, _, tokenRepository, verifier := newTestService(t, &config.Config{})
+	if _, _, err := service.Register(context.Background(), &usersdto.RegisterRequest{Email: "ada@example.com", Password: "correct horse"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		email      string
+		password   string
+		wantStatus int
+	}{
+		{name: "logged in", email: "ada@example.com", password: "correct horse", wantStatus: http.StatusOK},
+		{name: "email in another case", email: " ADA@example.com ", password: "correct horse", wantStatus: http.StatusOK},
+		{name: "wrong password", email: "ada@example.com", password: "battery staple", wantStatus: http.StatusUnauthorized},
+		{name: "unknown email", email: "bob@example.com", password: "correct horse", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(tokenRepository.tokens)
+			status, resp, err := service.Login(context.Background(), &usersdto.LoginRequest{Email: tt.email, Password: tt.password})
+			if status != tt.wantStatus {
+				t.Fatalf("Login() status = %d, want %d (error %v)", status, tt.wantStatus, err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+				}
+				if len(tokenRepository.tokens) != before {
+					t.Error("failed Login() stored a refresh token")
+				}
+				return
+			}
+
+			principal, err := verifier.Verify(resp.AccessToken)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if principal.Subject == "" || !principal.HasRole(auth.RoleViewer) {
+				t.Errorf("access token principal = %+v, want the user with their role", principal)
+			}
+			if _, err := tokenRepository.GetByHash(auth.HashToken(resp.RefreshToken)); err != nil {
+				t.Error("refresh token isn't stored by its hash")
+			}
+			if resp.TokenType != "Bearer" || resp.ExpiresIn <= 0 {
+				t.Errorf("Login() = %+v, want a bearer token that expires", resp)
+			}
+		})
+	}
+}
+
+// login registers a user, logs them in and returns their refresh token.
+func login(t *testing.T, service *userService) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	_, user, err := service.Register(ctx, &usersdto.RegisterRequest{Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	_, tokens, err := service.Login(ctx, &usersdto.LoginRequest{Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return user.UserId, tokens.RefreshToken
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	ctx := context.Background()
+	service, _, tokenRepository, _ := newTestService(t, &config.Config{})
+	userId, first := login(t, service)
+
+	status, resp, err := service.Refresh(ctx, &usersdto.RefreshRequest{RefreshToken: first})
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Refresh() = %d, %v", status, err)
+	}
+	second := resp.RefreshToken
+	if second == first {
+		t.Fatal("Refresh() handed out the same refresh token")
+	}
+	if revoked, _ := tokenRepository.GetByHash(auth.HashToken(first)); revoked.RevokedAt == nil {
+		t.Error("Refresh() didn't revoke the refresh token it rotated")
+	}
+	if got := tokenRepository.active(userId); got != 1 {
+		t.Errorf("active refresh tokens = %d, want 1", got)
+	}
+
+	if status, resp, err = service.Refresh(ctx, &usersdto.RefreshRequest{RefreshToken: second}); err != nil {
+		t.Fatalf("Refresh() with the rotated token = %d, %v", status, err)
+	}
+	if resp.RefreshToken == second {
+		t.Error("Refresh() handed out the same refresh token")
+	}
+}
+
+func TestRefreshReuseRevokesEverySession(t *testing.T) {
+	ctx := context.Background()
+	service, _, tokenRepository, _ := newTestService(t, &config.Config{})
+	userId, first := login(t, service)
+	_, resp, err := service.Refresh(ctx, &usersdto.RefreshRequest{RefreshToken: first})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	// another session of the same user
+	if _, _, err := service.Login(ctx, &usersdto.LoginRequest{Email: "ada@example.com", Password: "correct horse"}); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	status, _, err := service.Refresh(ctx, &usersdto.RefreshRequest{RefreshToken: first})
+	if status != http.StatusUnauthorized || !errors.Is(err, ErrInvalidRefresh) {
+		t.Fatalf("Refresh() with a rotated token = %d, %v, want %d, %v", status, err, http.StatusUnauthorized, ErrInvalidRefresh)
+	}
+	if got := tokenRepository.active(userId); got != 0 {
+		t.Errorf("active refresh tokens after reuse = %d, want 0", got)
+	}
+	if status, _, _ := service.Refresh(ctx, &usersdto.RefreshRequest{RefreshToken: resp.RefreshToken}); status != http.StatusUnauthorized {
+		t.Errorf("Refresh() with the token rotated to before the reuse = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(tokens *fakeRefreshTokens, token string) string
+	}{
+		{name: "unknown token", prepare: func(tokens *fakeRefreshTokens, token string) string { return "unknown" }},
+		{name: "expired token", prepare: func(tokens *fakeRefreshTokens, token string) string {
+			tokens.stored(auth.HashToken(token)).ExpiresAt = time.Now().Add(-time.Second)
+			return token
+		}},
+		{name: "token of a deleted user", prepare: func(tokens *fakeRefreshTokens, token string) string {
+			tokens.stored(auth.HashToken(token)).UserId = "deleted"
+			return token
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, tokenRepository, _ := newTestService(t, &config.Config{})
+			_, token := login(t, service)
+			token = tt.prepare(tokenRepository, token)
+
+			status, _, err := service.Refresh(context.Background(), &usersdto.RefreshRequest{RefreshToken: token})
+			if status != http.StatusUnauthorized || !errors.Is(err, ErrInvalidRefresh) {
+				t.Errorf("Refresh() = %d, %v, want %d, %v", status, err, http.StatusUnauthorized, ErrInvalidRefresh)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	service, _, tokenRepository, _ := newTestService(t, &config.Config{})
+	userId, token := login(t, service)
+
+	if status, _, err := service.Logout(ctx, &usersdto.RefreshRequest{RefreshToken: token}); err != nil || status != http.StatusOK {
+		t.Fatalf("Logout() = %d, %v", status, err)
+	}
+	if got := tokenRepository.active(userId); got != 0 {
+		t.Errorf("active refresh tokens after logout = %d, want 0", got)
+	}
+	if status, _, _ := service.Refresh(ctx, &usersdto.RefreshRequest{RefreshToken: token}); status != http.StatusUnauthorized {
+		t.Errorf("Refresh() after logout = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _, err := service.Logout(ctx, &usersdto.RefreshRequest{RefreshToken: "unknown"}); status != http.StatusUnauthorized || !errors.Is(err, ErrInvalidRefresh) {
+		t.Errorf("Logout() with an unknown token = %d, %v, want %d, %v", status, err, http.StatusUnauthorized, ErrInvalidRefresh)
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	tests := []struct {
+		name     string
+		admin    config.UserDetails
+		existing []*users.User
+		wantRole string
+		wantErr  bool
+	}{
+		{name: "not configured"},
+		{name: "created", admin: config.UserDetails{Email: "Root@example.com", Password: "correct horse"}, wantRole: auth.RoleAdmin},
+		{name: "created with a role", admin: config.UserDetails{Email: "root@example.com", Password: "correct horse", Role: auth.RoleEditor}, wantRole: auth.RoleEditor},
+		{name: "already registered", admin: config.UserDetails{Email: "root@example.com", Password: "correct horse"}, existing: []*users.User{{UserId: "u0", Email: "root@example.com", Role: auth.RoleViewer}}, wantRole: auth.RoleViewer},
+		{name: "password too long", admin: config.UserDetails{Email: "root@example.com", Password: strings.Repeat("a", 73)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, userRepository, _, _ := newTestService(t, &config.Config{Admin: tt.admin})
+			userRepository.users = tt.existing
+
+			err := service.bootstrapAdmin()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bootstrapAdmin() error = %v, want error %v", err, tt.wantErr)
+			}
+			user, err := userRepository.GetByEmail("root@example.com")
+			if tt.wantRole == "" {
+				if err == nil {
+					t.Errorf("bootstrapAdmin() created %+v", user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("admin not created: %v", err)
+			}
+			if user.Role != tt.wantRole {
+				t.Errorf("admin role = %s, want %s", user.Role, tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package apikeys
+
+import (
+	"time"
+
+	apikeymodel "github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
+)
+
+// displayPrefixLength is how much of a key is kept in clear to tell keys apart.
+const displayPrefixLength = 12
+
+// request
+type APIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=posts:read posts:write comments:write reactions:write admin"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func ModelFromAPIKeyRequest(request *APIKeyRequest, key, hash, ownerId string) *apikeymodel.APIKey {
+	model := &apikeymodel.APIKey{
+		KeyId:     uuid.GenerateUUID(),
+		Name:      request.Name,
+		Prefix:    key[:displayPrefixLength],
+		KeyHash:   hash,
+		OwnerId:   ownerId,
+		ExpiresAt: request.ExpiresAt,
+	}
+	model.SetScopes(request.Scopes)
+	return model
+}
+
+// response
+type APIKeyResponse struct {
+	KeyId      string     `json:"key_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	OwnerId    string     `json:"owner_id"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+}
+
+func NewAPIKeyResponse(key *apikeymodel.APIKey) *APIKeyResponse {
+	resp := &APIKeyResponse{
+		KeyId:      key.KeyId,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		OwnerId:    key.OwnerId,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+	return resp
+}
+
+// CreatedAPIKeyResponse is only returned on creation, it's the one time the key is
+// shown in full.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func NewCreatedAPIKeyResponse(model *apikeymodel.APIKey, key string) *CreatedAPIKeyResponse {
+	resp := &CreatedAPIKeyResponse{
+		APIKeyResponse: *NewAPIKeyResponse(model),
+		Key:            key,
+	}
+	return resp
+}
+
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys,omitempty"`
+}
+
+func NewAPIKeyListResponse(models []apikeymodel.APIKey) *APIKeyListResponse {
+	var keys []APIKeyResponse
+	for i := range models {
+		keys = append(keys, *NewAPIKeyResponse(&models[i]))
+	}
+	resp := &APIKeyListResponse{
+		APIKeys: keys,
+	}
+	return resp
+}
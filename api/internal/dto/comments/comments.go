@@ -0,0 +1,72 @@
+package comments
+
+import (
+	"time"
+
+	commentmodel "github.com/pedromspeixoto/posts-api/internal/data/models/comments"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
+)
+
+// request
+type CommentRequest struct {
+	Content  string  `json:"content" validate:"required,max=10000"`
+	ParentId *string `json:"parent_id,omitempty" validate:"omitempty,max=45"`
+}
+
+type CommentUpdateRequest struct {
+	Content string `json:"content" validate:"required,max=10000"`
+}
+
+func ModelFromCommentRequest(comment *CommentRequest, postId, authorId string) *commentmodel.Comment {
+	model := &commentmodel.Comment{
+		CommentId: uuid.GenerateUUID(),
+		PostId:    postId,
+		ParentId:  comment.ParentId,
+		AuthorId:  authorId,
+		Content:   comment.Content,
+	}
+	return model
+}
+
+// response
+type AuthorResponse struct {
+	Id string `json:"id"`
+}
+
+type CommentResponse struct {
+	CommentId string          `json:"comment_id"`
+	PostId    string          `json:"post_id"`
+	ParentId  *string         `json:"parent_id,omitempty"`
+	Author    *AuthorResponse `json:"author,omitempty"`
+	Content   string          `json:"content"`
+	CreatedAt time.Time       `json:"created_at,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at,omitempty"`
+}
+
+func NewCommentResponse(comment *commentmodel.Comment) *CommentResponse {
+	resp := &CommentResponse{
+		CommentId: comment.CommentId,
+		PostId:    comment.PostId,
+		ParentId:  comment.ParentId,
+		Author:    &AuthorResponse{Id: comment.AuthorId},
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+	return resp
+}
+
+type CommentListResponse struct {
+	Comments []CommentResponse `json:"comments,omitempty"`
+}
+
+func NewCommentListResponse(models []commentmodel.Comment) *CommentListResponse {
+	var comments []CommentResponse
+	for i := range models {
+		comments = append(comments, *NewCommentResponse(&models[i]))
+	}
+	resp := &CommentListResponse{
+		Comments: comments,
+	}
+	return resp
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	jobmodel "github.com/pedromspeixoto/posts-api/internal/data/models/jobs"
+)
+
+// response
+type JobResponse struct {
+	JobId     string            `json:"job_id"`
+	Kind      string            `json:"kind"`
+	Status    string            `json:"status"`
+	Params    map[string]string `json:"params,omitempty"`
+	Processed int               `json:"processed"`
+	// Result is the outcome of the job, whose shape depends on its kind.
+	Result     json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error      string          `json:"error,omitempty"`
+	URL        string          `json:"url"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+func NewJobResponse(job *jobmodel.Job) *JobResponse {
+	resp := &JobResponse{
+		JobId:      job.JobId,
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Params:     job.Params,
+		Processed:  job.Processed,
+		Error:      job.Error,
+		URL:        "/v1/jobs/" + job.JobId,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Result != "" {
+		resp.Result = json.RawMessage(job.Result)
+	}
+	return resp
+}
@@ -0,0 +1,44 @@
+package media
+
+import (
+	"io"
+	"time"
+
+	mediamodel "github.com/pedromspeixoto/posts-api/internal/data/models/media"
+)
+
+// response
+type OwnerResponse struct {
+	Id string `json:"id"`
+}
+
+type MediaResponse struct {
+	MediaId     string         `json:"media_id"`
+	Owner       *OwnerResponse `json:"owner,omitempty"`
+	Filename    string         `json:"filename"`
+	ContentType string         `json:"content_type"`
+	Size        int64          `json:"size"`
+	Checksum    string         `json:"checksum"`
+	URL         string         `json:"url"`
+	CreatedAt   time.Time      `json:"created_at,omitempty"`
+}
+
+func NewMediaResponse(media *mediamodel.Media) *MediaResponse {
+	resp := &MediaResponse{
+		MediaId:     media.MediaId,
+		Owner:       &OwnerResponse{Id: media.OwnerId},
+		Filename:    media.Filename,
+		ContentType: media.ContentType,
+		Size:        media.Size,
+		Checksum:    media.Checksum,
+		URL:         "/v1/media/" + media.MediaId,
+		CreatedAt:   media.CreatedAt,
+	}
+	return resp
+}
+
+// MediaContent is media opened for download. Content has to be closed once served.
+type MediaContent struct {
+	MediaResponse
+	Content io.ReadSeekCloser
+}
@@ -2,50 +2,78 @@ package dto
 
 import (
 	"github.com/pedromspeixoto/posts-api/internal/data"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
 )
 
 type PaginationRequest struct {
-	Limit  int               `json:"limit,omitempty"`
-	Page   int               `json:"page,omitempty"`
-	Sort   string            `json:"sort,omitempty"`
-	Filter map[string]string `json:"filter,omitempty"`
-	Search map[string]string `json:"search,omitempty"`
+	Limit  int          `json:"limit,omitempty"`
+	Page   int          `json:"page,omitempty"`
+	Query  *query.Query `json:"-"`
+	Cursor string       `json:"cursor,omitempty"`
+	Count  bool         `json:"count,omitempty"`
 }
 
-func NewPaginationRequest(limit, page int, sort string, filter, search map[string]string) (*PaginationRequest, error) {
+func NewPaginationRequest(limit, page int, q *query.Query, cursor string, count bool) (*PaginationRequest, error) {
 	res := &PaginationRequest{
 		Limit:  limit,
 		Page:   page,
-		Sort:   sort,
-		Filter: filter,
-		Search: search,
+		Query:  q,
+		Cursor: cursor,
+		Count:  count,
 	}
 	return res, nil
 }
 
+// ModelFromPaginationRequest builds the pagination model. The cursor is left unset as
+// it has to be verified by the caller with DecodeCursor first.
 func ModelFromPaginationRequest(p *PaginationRequest) *data.Pagination {
 	model := &data.Pagination{
-		Limit:  p.Limit,
-		Page:   p.Page,
-		Sort:   p.Sort,
-		Filter: p.Filter,
+		Limit:     p.Limit,
+		Page:      p.Page,
+		Query:     p.Query,
+		SkipCount: !p.Count,
 	}
 	return model
 }
 
 type PaginationResponse struct {
 	CurrentPage int         `json:"current_page,omitempty"`
-	TotalRows   int64       `json:"total_rows"`
-	TotalPages  int         `json:"total_pages"`
+	TotalRows   *int64      `json:"total_rows,omitempty"`
+	TotalPages  *int        `json:"total_pages,omitempty"`
+	NextCursor  string      `json:"next_cursor,omitempty"`
+	PrevCursor  string      `json:"prev_cursor,omitempty"`
 	Data        interface{} `json:"data"`
 }
 
 func NewPaginationResponse(p *data.Pagination) *PaginationResponse {
 	res := &PaginationResponse{
-		CurrentPage: p.GetPage(),
-		TotalRows:   p.TotalRows,
-		TotalPages:  p.TotalPages,
-		Data:        p.Data,
+		Data: p.Data,
+	}
+	if p.Cursor == nil {
+		res.CurrentPage = p.GetPage()
+	}
+	if !p.SkipCount {
+		res.TotalRows = &p.TotalRows
+		res.TotalPages = &p.TotalPages
 	}
 	return res
 }
+
+// SetCursors signs the next and previous cursors of the page into opaque tokens.
+func (r *PaginationResponse) SetCursors(p *data.Pagination, secret []byte) error {
+	if p.NextCursor != nil {
+		next, err := data.EncodeCursor(p.NextCursor, secret)
+		if err != nil {
+			return err
+		}
+		r.NextCursor = next
+	}
+	if p.PrevCursor != nil {
+		prev, err := data.EncodeCursor(p.PrevCursor, secret)
+		if err != nil {
+			return err
+		}
+		r.PrevCursor = prev
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package posts
+
+import "net/http"
+
+// Batch operations
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// request
+type BatchOperation struct {
+	Op      string       `json:"op" validate:"required,oneof=create update delete"`
+	PostId  string       `json:"post_id,omitempty" validate:"required_unless=Op create"`
+	IfMatch string       `json:"if_match,omitempty"`
+	Post    *PostRequest `json:"post,omitempty" validate:"required_unless=Op delete"`
+}
+
+type BatchRequest struct {
+	// Atomic batches are applied all or nothing
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations" validate:"required,min=1"`
+}
+
+// response
+type BatchResult struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	Status int           `json:"status"`
+	Error  string        `json:"error,omitempty"`
+	Post   *PostResponse `json:"post,omitempty"`
+}
+
+// Failed reports whether the operation of the result failed.
+func (r *BatchResult) Failed() bool {
+	return r.Status >= http.StatusBadRequest
+}
+
+type BatchResponse struct {
+	Atomic    bool          `json:"atomic"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+func NewBatchResponse(atomic bool, results []BatchResult) *BatchResponse {
+	resp := &BatchResponse{
+		Atomic:  atomic,
+		Results: results,
+	}
+	for i := range results {
+		if results[i].Failed() {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+	}
+	return resp
+}
@@ -0,0 +1,90 @@
+package posts
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvListSeparator separates the items of list columns, tags and media ids never
+// contain it.
+const csvListSeparator = "|"
+
+// csvFormulaTriggers are the characters that make spreadsheets evaluate a cell they
+// start as a formula.
+const csvFormulaTriggers = "=+-@\t\r"
+
+// csvTextPrefix makes spreadsheets take a cell as text.
+const csvTextPrefix = "'"
+
+// CSVHeader lists the columns of posts in CSV, in order.
+var CSVHeader = []string{
+	"post_id",
+	"author_id",
+	"title",
+	"slug",
+	"status",
+	"format",
+	"content",
+	"tags",
+	"media",
+	"publish_at",
+	"published_at",
+	"version",
+	"created_at",
+}
+
+// CSVRecord returns the post as a CSV record with the columns of CSVHeader. Lists are
+// joined with "|" and times are formatted as RFC 3339. Cells that spreadsheets would
+// evaluate as formulas are escaped.
+func (p *PostResponse) CSVRecord() []string {
+	authorId := ""
+	if p.Author != nil {
+		authorId = p.Author.Id
+	}
+	record := []string{
+		p.PostId,
+		authorId,
+		p.Title,
+		p.Slug,
+		p.Status,
+		p.Format,
+		p.Content,
+		strings.Join(p.Tags, csvListSeparator),
+		strings.Join(p.Media, csvListSeparator),
+		csvTime(p.PublishAt),
+		csvTime(p.PublishedAt),
+		strconv.FormatUint(uint64(p.Version), 10),
+		csvTime(&p.CreatedAt),
+	}
+	for i, cell := range record {
+		record[i] = csvEscape(cell)
+	}
+	return record
+}
+
+// csvEscape prefixes cells starting with a formula trigger with a quote, so they are
+// taken as text. Cells already starting with a quote get another one, so csvUnescape
+// can tell the prefix apart from their content.
+func csvEscape(cell string) string {
+	if cell != "" && strings.ContainsAny(cell[:1], csvFormulaTriggers+csvTextPrefix) {
+		return csvTextPrefix + cell
+	}
+	return cell
+}
+
+// csvUnescape strips the prefix csvEscape adds. Quotes that don't precede a formula
+// trigger or another quote are content, and kept.
+func csvUnescape(cell string) string {
+	if len(cell) > 1 && strings.HasPrefix(cell, csvTextPrefix) && strings.ContainsAny(cell[1:2], csvFormulaTriggers+csvTextPrefix) {
+		return cell[1:]
+	}
+	return cell
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
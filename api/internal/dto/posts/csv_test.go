@@ -0,0 +1,86 @@
+package posts
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestCSVEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		cell string
+		want string
+	}{
+		{name: "text", cell: "hello", want: "hello"},
+		{name: "empty", cell: "", want: ""},
+		{name: "formula", cell: "=HYPERLINK(\"http://evil\")", want: "'=HYPERLINK(\"http://evil\")"},
+		{name: "plus", cell: "+1", want: "'+1"},
+		{name: "minus", cell: "-1", want: "'-1"},
+		{name: "at", cell: "@SUM(A1)", want: "'@SUM(A1)"},
+		{name: "tab", cell: "\t=1", want: "'\t=1"},
+		{name: "carriage return", cell: "\r=1", want: "'\r=1"},
+		{name: "quote", cell: "'quoted", want: "''quoted"},
+		{name: "trigger past the start", cell: "a=b", want: "a=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := csvEscape(tt.cell)
+			if got != tt.want {
+				t.Errorf("csvEscape(%q) = %q, want %q", tt.cell, got, tt.want)
+			}
+			if unescaped := csvUnescape(got); unescaped != tt.cell {
+				t.Errorf("csvUnescape(%q) = %q, want %q", got, unescaped, tt.cell)
+			}
+		})
+	}
+}
+
+func TestCSVUnescapeKeepsContentQuotes(t *testing.T) {
+	tests := []string{"'hello", "'", "it's"}
+	for _, cell := range tests {
+		t.Run(cell, func(t *testing.T) {
+			if got := csvUnescape(cell); got != cell {
+				t.Errorf("csvUnescape(%q) = %q, want it unchanged", cell, got)
+			}
+		})
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	post := &PostResponse{
+		PostId:  testPostId,
+		Title:   "=cmd|' /C calc'!A0",
+		Content: "-starts with a dash",
+		Format:  "plain",
+		Tags:    []string{"go", "api"},
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(CSVHeader); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	record := post.CSVRecord()
+	if err := writer.Write(record); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	writer.Flush()
+
+	if record[2] != "'"+post.Title || record[6] != "'"+post.Content {
+		t.Errorf("CSVRecord() title = %q, content = %q, want them escaped", record[2], record[6])
+	}
+
+	reader, err := NewImportReader(ImportFormatCSV, &buf)
+	if err != nil {
+		t.Fatalf("NewImportReader() error = %v", err)
+	}
+	rows := readRows(t, reader)
+	if len(rows) != 1 {
+		t.Fatalf("read %d rows, want 1", len(rows))
+	}
+	if rows[0].Post.Title != post.Title || rows[0].Post.Content != post.Content {
+		t.Errorf("imported title = %q, content = %q, want %q and %q", rows[0].Post.Title, rows[0].Post.Content, post.Title, post.Content)
+	}
+}
@@ -0,0 +1,208 @@
+package posts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Import formats, the same posts are exported in
+const (
+	ImportFormatNDJSON = "ndjson"
+	ImportFormatCSV    = "csv"
+)
+
+// What imports do with rows whose post_id is taken
+const (
+	OnConflictSkip      = "skip"
+	OnConflictOverwrite = "overwrite"
+	OnConflictFail      = "fail"
+)
+
+// maxImportErrors bounds how many row errors an import report lists, the rest are
+// only counted.
+const maxImportErrors = 1000
+
+var ErrMissingContentColumn = errors.New("csv header has no content column")
+
+// request
+type ImportRequest struct {
+	Format     string `json:"format" validate:"required,oneof=ndjson csv"`
+	DryRun     bool   `json:"dry_run"`
+	OnConflict string `json:"on_conflict" validate:"required,oneof=skip overwrite fail"`
+}
+
+// ImportRow is a post read from an import, along with the line it starts on. Rows
+// without a post id get a new one.
+type ImportRow struct {
+	Line   int         `json:"-"`
+	PostId string      `json:"post_id,omitempty" validate:"omitempty,uuid"`
+	Post   PostRequest `json:"-"`
+	// Err is set when the row couldn't be read.
+	Err error `json:"-"`
+}
+
+// ImportReader reads the rows of an import one at a time. Next returns io.EOF once
+// there are no rows left; any other error means the rest of the import can't be read.
+type ImportReader interface {
+	Next() (*ImportRow, error)
+}
+
+// NewImportReader returns the reader of an import format.
+func NewImportReader(format string, r io.Reader) (ImportReader, error) {
+	switch format {
+	case ImportFormatNDJSON:
+		return &ndjsonReader{reader: bufio.NewReader(r)}, nil
+	case ImportFormatCSV:
+		return newCSVReader(r)
+	}
+	return nil, fmt.Errorf("unsupported import format %q, expected %s or %s", format, ImportFormatNDJSON, ImportFormatCSV)
+}
+
+// ndjsonReader reads posts as one JSON document per line, blank lines are skipped.
+// Fields that aren't part of a post request, like the ones of exports, are ignored.
+type ndjsonReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (n *ndjsonReader) Next() (*ImportRow, error) {
+	for {
+		data, err := n.reader.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		n.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &ImportRow{Line: n.line}
+		if err := json.Unmarshal(data, row); err != nil {
+			row.Err = err
+		} else if err := json.Unmarshal(data, &row.Post); err != nil {
+			row.Err = err
+		}
+		return row, nil
+	}
+}
+
+// csvReader reads posts as CSV records with the columns named in a header, those of
+// CSVHeader. Only the content column is required, unknown ones are ignored. Cells
+// escaped by exports are unescaped.
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("csv header is missing")
+		}
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns["content"]; !ok {
+		return nil, ErrMissingContentColumn
+	}
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (*ImportRow, error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &ImportRow{Line: parseErr.StartLine, Err: err}, nil
+		}
+		return nil, err
+	}
+	line, _ := c.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return csvUnescape(record[i])
+		}
+		return ""
+	}
+	list := func(name string) []string {
+		if value := field(name); value != "" {
+			return strings.Split(value, csvListSeparator)
+		}
+		return nil
+	}
+
+	row := &ImportRow{
+		Line:   line,
+		PostId: field("post_id"),
+		Post: PostRequest{
+			Title:   field("title"),
+			Content: field("content"),
+			Format:  field("format"),
+			Tags:    list("tags"),
+			Media:   list("media"),
+		},
+	}
+	if value := field("publish_at"); value != "" {
+		publishAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			row.Err = fmt.Errorf("invalid publish_at: %v", err)
+		}
+		row.Post.PublishAt = &publishAt
+	}
+	return row, nil
+}
+
+// response
+type ImportError struct {
+	Line   int    `json:"line"`
+	PostId string `json:"post_id,omitempty"`
+	Error  string `json:"error"`
+}
+
+type ImportReport struct {
+	DryRun     bool   `json:"dry_run"`
+	OnConflict string `json:"on_conflict"`
+	Rows       int    `json:"rows"`
+	Created    int    `json:"created"`
+	Updated    int    `json:"updated"`
+	Skipped    int    `json:"skipped"`
+	Failed     int    `json:"failed"`
+	// Errors lists the rows that failed, up to a limit, in line order.
+	Errors          []ImportError `json:"errors"`
+	ErrorsTruncated bool          `json:"errors_truncated,omitempty"`
+}
+
+func NewImportReport(request *ImportRequest) *ImportReport {
+	return &ImportReport{
+		DryRun:     request.DryRun,
+		OnConflict: request.OnConflict,
+		Errors:     []ImportError{},
+	}
+}
+
+// Fail records a row that failed.
+func (r *ImportReport) Fail(row *ImportRow, err error) {
+	r.Failed++
+	if len(r.Errors) >= maxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, ImportError{Line: row.Line, PostId: row.PostId, Error: err.Error()})
+}
@@ -0,0 +1,185 @@
+package posts
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPostId = "9b2c9a4e-3f5d-4c1e-8a7b-6d5e4f3a2b1c"
+
+// readRows reads every row of an import, failing the test on errors that end it.
+func readRows(t *testing.T, reader ImportReader) []*ImportRow {
+	t.Helper()
+	var rows []*ImportRow
+	for {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// assertRows compares the rows read with the wanted ones, only checking that rows
+// that failed to read have an error.
+func assertRows(t *testing.T, got, want []*ImportRow) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if (got[i].Err != nil) != (want[i].Err != nil) {
+			t.Errorf("row %d error = %v, want %v", i, got[i].Err, want[i].Err)
+		}
+		gotRow, wantRow := *got[i], *want[i]
+		gotRow.Err, wantRow.Err = nil, nil
+		if want[i].Err != nil {
+			gotRow.Post, wantRow.Post = PostRequest{}, PostRequest{}
+		}
+		if !reflect.DeepEqual(gotRow, wantRow) {
+			t.Errorf("row %d = %+v, want %+v", i, gotRow, wantRow)
+		}
+	}
+}
+
+var errRow = errors.New("row error")
+
+func TestNDJSONReader(t *testing.T) {
+	publishAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []*ImportRow
+	}{
+		{name: "empty", input: ""},
+		{
+			name:  "post",
+			input: `{"post_id":"` + testPostId + `","title":"Hi","content":"World","format":"markdown","tags":["go"],"media":["m1"],"publish_at":"2024-03-01T12:00:00Z"}`,
+			want: []*ImportRow{{Line: 1, PostId: testPostId, Post: PostRequest{
+				Title: "Hi", Content: "World", Format: "markdown", Tags: []string{"go"}, Media: []string{"m1"}, PublishAt: &publishAt,
+			}}},
+		},
+		{
+			name:  "blank lines skipped and counted",
+			input: "\n{\"content\":\"a\"}\n  \n\r\n{\"content\":\"b\"}",
+			want:  []*ImportRow{{Line: 2, Post: PostRequest{Content: "a"}}, {Line: 5, Post: PostRequest{Content: "b"}}},
+		},
+		{
+			name:  "unknown fields ignored",
+			input: `{"content":"a","version":3,"author_id":"x"}`,
+			want:  []*ImportRow{{Line: 1, Post: PostRequest{Content: "a"}}},
+		},
+		{
+			name:  "malformed lines fail on their own",
+			input: "{\"content\":\"a\"}\n{\"content\":\n{\"content\":\"c\"}\n",
+			want:  []*ImportRow{{Line: 1, Post: PostRequest{Content: "a"}}, {Line: 2, Err: errRow}, {Line: 3, Post: PostRequest{Content: "c"}}},
+		},
+		{
+			name:  "wrong types",
+			input: `{"content":1}`,
+			want:  []*ImportRow{{Line: 1, Err: errRow}},
+		},
+		{
+			name:  "wrong post id type",
+			input: `{"post_id":1,"content":"a"}`,
+			want:  []*ImportRow{{Line: 1, Err: errRow}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewImportReader(ImportFormatNDJSON, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("NewImportReader() error = %v", err)
+			}
+			assertRows(t, readRows(t, reader), tt.want)
+		})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	publishAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []*ImportRow
+	}{
+		{name: "header only", input: "content\n"},
+		{
+			name:  "every column",
+			input: "post_id,title,content,format,tags,media,publish_at\n" + testPostId + ",Hi,World,markdown,go|api,m1,2024-03-01T12:00:00Z\n",
+			want: []*ImportRow{{Line: 2, PostId: testPostId, Post: PostRequest{
+				Title: "Hi", Content: "World", Format: "markdown", Tags: []string{"go", "api"}, Media: []string{"m1"}, PublishAt: &publishAt,
+			}}},
+		},
+		{
+			name:  "columns in any order with unknown ones and a byte order mark",
+			input: "\ufeffversion, content ,title\n3,World,Hi\n",
+			want:  []*ImportRow{{Line: 2, Post: PostRequest{Title: "Hi", Content: "World"}}},
+		},
+		{
+			name:  "multiline fields",
+			input: "content,title\n\"one\ntwo\",a\nthree,b\n",
+			want:  []*ImportRow{{Line: 2, Post: PostRequest{Content: "one\ntwo", Title: "a"}}, {Line: 4, Post: PostRequest{Content: "three", Title: "b"}}},
+		},
+		{
+			name:  "short records",
+			input: "content,title\nonly\n",
+			want:  []*ImportRow{{Line: 2, Post: PostRequest{Content: "only"}}},
+		},
+		{
+			name:  "invalid publish_at",
+			input: "content,publish_at\na,tomorrow\n",
+			want:  []*ImportRow{{Line: 2, Err: errRow}},
+		},
+		{
+			name:  "malformed records fail on their own",
+			input: "content\na\n\"b\"c\nd\n",
+			want:  []*ImportRow{{Line: 2, Post: PostRequest{Content: "a"}}, {Line: 3, Err: errRow}, {Line: 4, Post: PostRequest{Content: "d"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewImportReader(ImportFormatCSV, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("NewImportReader() error = %v", err)
+			}
+			assertRows(t, readRows(t, reader), tt.want)
+		})
+	}
+}
+
+func TestNewImportReaderRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   error
+	}{
+		{name: "missing header", format: ImportFormatCSV, input: ""},
+		{name: "missing content column", format: ImportFormatCSV, input: "title,tags\n", want: ErrMissingContentColumn},
+		{name: "unknown format", format: "xml", input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewImportReader(tt.format, strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("NewImportReader() error = nil, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("NewImportReader() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
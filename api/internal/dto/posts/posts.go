@@ -1,59 +1,269 @@
 package posts
 
 import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
 	"time"
 
 	postmodel "github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/etag"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/highlight"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/render"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
 )
 
+// snippetWidth is the approximate length, in characters, of search result snippets.
+const snippetWidth = 160
+
 type Post struct {
 }
 
 // request
 type PostRequest struct {
-	Content string `json:"content" validate:"required"`
+	Title     string     `json:"title,omitempty" validate:"max=255"`
+	Content   string     `json:"content" validate:"required"`
+	Format    string     `json:"format,omitempty" validate:"omitempty,oneof=plain markdown html"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Tags      []string   `json:"tags,omitempty" validate:"max=20,dive,required,max=100"`
+	Media     []string   `json:"media,omitempty" validate:"max=20,dive,required,max=45"`
+}
+
+type PostListRequest struct {
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=draft in_review published archived scheduled"`
 }
 
 func ModelFromPostRequest(post *PostRequest) *postmodel.Post {
+	format := post.Format
+	if format == "" {
+		format = render.FormatPlain
+	}
 	model := &postmodel.Post{
-		PostId:  uuid.GenerateUUID(),
-		Content: post.Content,
+		PostId:    uuid.GenerateUUID(),
+		Title:     post.Title,
+		Content:   post.Content,
+		Format:    format,
+		Status:    postmodel.StatusDraft,
+		PublishAt: post.PublishAt,
+		Tags:      postmodel.NormalizeTags(post.Tags),
+		Media:     post.Media,
+		Version:   1,
 	}
 	return model
 }
 
+// NewPostRequestFromResponse builds the request representation of an existing post,
+// which is the document partial updates are applied to.
+func NewPostRequestFromResponse(post *PostResponse) *PostRequest {
+	request := &PostRequest{
+		Title:     post.Title,
+		Content:   post.Content,
+		Format:    post.Format,
+		PublishAt: post.PublishAt,
+		Tags:      post.Tags,
+		Media:     post.Media,
+	}
+	return request
+}
+
 // response
+type AuthorResponse struct {
+	Id string `json:"id"`
+}
+
 type PostResponse struct {
-	PostId    string    `json:"post_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
+	PostId       string          `json:"post_id"`
+	Author       *AuthorResponse `json:"author,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Slug         string          `json:"slug"`
+	Content      string          `json:"content"`
+	Format       string          `json:"format"`
+	ContentHTML  string          `json:"content_html"`
+	Excerpt      string          `json:"excerpt"`
+	ReadingTime  uint            `json:"reading_time_minutes"`
+	Status       string          `json:"status"`
+	PublishedAt  *time.Time      `json:"published_at,omitempty"`
+	PublishAt    *time.Time      `json:"publish_at,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	CommentCount uint            `json:"comment_count"`
+	Reactions    map[string]uint `json:"reactions,omitempty"`
+	Media        []string        `json:"media,omitempty"`
+	Version      uint            `json:"version"`
+	CreatedAt    time.Time       `json:"created_at,omitempty"`
+	DeletedAt    *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// ETag returns the strong entity tag of the post representation. Comment and reaction
+// counts change without bumping the version, so they're folded into the tag too.
+func (p *PostResponse) ETag() string {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "comments=%d", p.CommentCount)
+	kinds := make([]string, 0, len(p.Reactions))
+	for kind := range p.Reactions {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(hash, ";%s=%d", kind, p.Reactions[kind])
+	}
+	return etag.FromVersionAndState(p.Version, strconv.FormatUint(uint64(hash.Sum32()), 36))
 }
 
 func NewPostResponse(post *postmodel.Post) *PostResponse {
 	resp := &PostResponse{
-		PostId:    post.PostId,
-		Content:   post.Content,
-		CreatedAt: post.CreatedAt,
+		PostId:       post.PostId,
+		Author:       newAuthorResponse(post.AuthorId),
+		Title:        post.Title,
+		Slug:         post.Slug,
+		Content:      post.Content,
+		Format:       post.Format,
+		ContentHTML:  post.ContentHTML,
+		Excerpt:      post.Excerpt,
+		ReadingTime:  post.ReadingTime,
+		Status:       post.Status,
+		PublishedAt:  post.PublishedAt,
+		PublishAt:    post.PublishAt,
+		Tags:         post.Tags,
+		CommentCount: post.CommentCount,
+		Reactions:    post.Reactions,
+		Media:        post.Media,
+		Version:      post.Version,
+		CreatedAt:    post.CreatedAt,
+	}
+	if post.DeletedAt.Valid {
+		resp.DeletedAt = &post.DeletedAt.Time
 	}
 	return resp
 }
 
+func newAuthorResponse(authorId string) *AuthorResponse {
+	if authorId == "" {
+		return nil
+	}
+	return &AuthorResponse{
+		Id: authorId,
+	}
+}
+
 type PostListResponse struct {
 	Posts []PostResponse `json:"posts,omitempty"`
 }
 
 func NewPostListResponse(models []postmodel.Post) *PostListResponse {
 	var posts []PostResponse
-	for _, m := range models {
-		posts = append(posts, PostResponse{
-			PostId:    m.PostId,
-			Content:   m.Content,
-			CreatedAt: m.CreatedAt,
-		})
+	for i := range models {
+		posts = append(posts, *NewPostResponse(&models[i]))
 	}
 	resp := &PostListResponse{
 		Posts: posts,
 	}
 	return resp
 }
+
+// search request
+type PostSearchRequest struct {
+	Query string `json:"q" validate:"required"`
+	Mode  string `json:"mode,omitempty" validate:"omitempty,oneof=natural boolean"`
+}
+
+// search response
+type PostSearchResponse struct {
+	PostResponse
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type PostSearchListResponse struct {
+	Results []PostSearchResponse `json:"results,omitempty"`
+}
+
+func NewPostSearchListResponse(models []postmodel.SearchResult, q string) *PostSearchListResponse {
+	terms := highlight.Terms(q)
+	var results []PostSearchResponse
+	for _, m := range models {
+		results = append(results, PostSearchResponse{
+			PostResponse: *NewPostResponse(&m.Post),
+			Score:        m.Score,
+			Snippet:      highlight.Snippet(render.Text(m.ContentHTML), terms, snippetWidth),
+		})
+	}
+	resp := &PostSearchListResponse{
+		Results: results,
+	}
+	return resp
+}
+
+// revisions response
+type RevisionResponse struct {
+	Revision  uint            `json:"revision"`
+	Editor    *AuthorResponse `json:"editor,omitempty"`
+	Content   string          `json:"content"`
+	Format    string          `json:"format"`
+	CreatedAt time.Time       `json:"created_at,omitempty"`
+}
+
+func NewRevisionResponse(revision *postmodel.PostRevision) *RevisionResponse {
+	resp := &RevisionResponse{
+		Revision:  revision.Revision,
+		Editor:    newAuthorResponse(revision.EditorId),
+		Content:   revision.Content,
+		Format:    revision.Format,
+		CreatedAt: revision.CreatedAt,
+	}
+	return resp
+}
+
+type RevisionListResponse struct {
+	Revisions []RevisionResponse `json:"revisions,omitempty"`
+}
+
+func NewRevisionListResponse(models []postmodel.PostRevision) *RevisionListResponse {
+	var revisions []RevisionResponse
+	for i := range models {
+		revisions = append(revisions, *NewRevisionResponse(&models[i]))
+	}
+	resp := &RevisionListResponse{
+		Revisions: revisions,
+	}
+	return resp
+}
+
+type RevisionDiffResponse struct {
+	From uint   `json:"from"`
+	To   uint   `json:"to"`
+	Diff string `json:"diff"`
+}
+
+func NewRevisionDiffResponse(from, to uint, diff string) *RevisionDiffResponse {
+	resp := &RevisionDiffResponse{
+		From: from,
+		To:   to,
+		Diff: diff,
+	}
+	return resp
+}
+
+// tags response
+type TagResponse struct {
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
+
+type TagListResponse struct {
+	Tags []TagResponse `json:"tags,omitempty"`
+}
+
+func NewTagListResponse(models []postmodel.TagCount) *TagListResponse {
+	var tags []TagResponse
+	for _, m := range models {
+		tags = append(tags, TagResponse{
+			Name:      m.Name,
+			PostCount: m.PostCount,
+		})
+	}
+	resp := &TagListResponse{
+		Tags: tags,
+	}
+	return resp
+}
@@ -0,0 +1,30 @@
+package posts
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestPostSearchRequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		request PostSearchRequest
+		wantErr bool
+	}{
+		{name: "query", request: PostSearchRequest{Query: "go"}},
+		{name: "natural mode", request: PostSearchRequest{Query: "go", Mode: "natural"}},
+		{name: "boolean mode", request: PostSearchRequest{Query: "+go -java", Mode: "boolean"}},
+		{name: "no query", request: PostSearchRequest{}, wantErr: true},
+		{name: "unknown mode", request: PostSearchRequest{Query: "go", Mode: "regexp"}, wantErr: true},
+	}
+
+	validate := validator.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("Struct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
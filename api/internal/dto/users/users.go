@@ -0,0 +1,79 @@
+package users
+
+import (
+	"strings"
+	"time"
+
+	usermodel "github.com/pedromspeixoto/posts-api/internal/data/models/users"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
+)
+
+// MaxPasswordBytes is the most bcrypt hashes, longer passwords can't be told apart
+// past it. Validation tags count runes, so passwords are checked against it in bytes.
+const MaxPasswordBytes = 72
+
+// request
+type RegisterRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	// Password is at most 72 bytes long once UTF-8 encoded
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func ModelFromRegisterRequest(user *RegisterRequest, passwordHash, role string) *usermodel.User {
+	model := &usermodel.User{
+		UserId:       uuid.GenerateUUID(),
+		Email:        NormalizeEmail(user.Email),
+		PasswordHash: passwordHash,
+		Role:         role,
+	}
+	return model
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// response
+type UserResponse struct {
+	UserId    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+func NewUserResponse(user *usermodel.User) *UserResponse {
+	resp := &UserResponse{
+		UserId:    user.UserId,
+		Email:     user.Email,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+	return resp
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func NewTokenResponse(accessToken string, expiresAt time.Time, refreshToken string) *TokenResponse {
+	resp := &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(expiresAt).Seconds()),
+		RefreshToken: refreshToken,
+	}
+	return resp
+}
@@ -0,0 +1,145 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	apikeymodel "github.com/pedromspeixoto/posts-api/internal/data/models/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/domain/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	apikeysdto "github.com/pedromspeixoto/posts-api/internal/dto/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+type APIKeyServiceHandler interface {
+	Routes() chi.Router
+}
+
+type apiKeyServiceDeps struct {
+	fx.In
+
+	Config        *config.Config
+	Logger        *logger.LoggingClient
+	Validator     *validator.Validate
+	APIKeyService apikeys.APIKeyService
+}
+
+type apiKeyServiceHandler struct {
+	apiKeyServiceDeps
+	logger.Logger
+}
+
+func NewAPIKeyServiceHandler(deps apiKeyServiceDeps) APIKeyServiceHandler {
+	return &apiKeyServiceHandler{
+		apiKeyServiceDeps: deps,
+		Logger:            deps.Logger.GetLogger(),
+	}
+}
+
+func (h apiKeyServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// api keys (admin)
+	r.Use(middlewares.RequireRoles(auth.RoleAdmin))
+	r.Post("/", h.CreateAPIKey)
+	r.With(middlewares.Paginate(apikeymodel.QuerySchema)).Get("/", h.ListAPIKeys)
+	r.Delete("/{keyId}", h.RevokeAPIKey)
+
+	return r
+}
+
+// CreateAPIKey - Handles API key creation
+// @Summary Create a new API key.
+// @Description This API is used to create an API key for headless clients, the key is only returned once
+// @Param request body apikeysdto.APIKeyRequest true "API Key Payload"
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Router /v1/admin/api-keys [post]
+func (h apiKeyServiceHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	request := apikeysdto.APIKeyRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(request)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, keyResponse, err := h.APIKeyService.CreateAPIKey(r.Context(), &request)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "new api key created", keyResponse)
+}
+
+// ListAPIKeys - Handles listing API keys
+// @Summary Gets all API keys.
+// @Description This API is used to list all API keys, without the keys themselves
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. created_at.desc"
+// @Param filter query string false "Filters as field:operator:value separated by commas, e.g. owner_id:eq:some-uuid"
+// @Param count query bool false "Whether to count total rows"
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Router /v1/admin/api-keys [get]
+func (h apiKeyServiceHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.APIKeyService.ListAPIKeys(r.Context(), pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "api keys retrieved", env)
+}
+
+// RevokeAPIKey - Handles API key revocation
+// @Summary Revoke an API key.
+// @Description This API is used to revoke an API key, requests using it are rejected from then on
+// @Param key_id path string true "API Key Id"
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Router /v1/admin/api-keys/{key_id} [delete]
+func (h apiKeyServiceHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyId := chi.URLParam(r, "keyId")
+
+	statusCode, keyResponse, err := h.APIKeyService.RevokeAPIKey(r.Context(), keyId)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "api key revoked", keyResponse)
+}
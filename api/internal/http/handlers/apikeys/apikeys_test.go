@@ -0,0 +1,66 @@
+package apikeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/domain/apikeys"
+	apikeysdto "github.com/pedromspeixoto/posts-api/internal/dto/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+)
+
+// fakeAPIKeyService records the keys it's asked to create. Other calls panic.
+type fakeAPIKeyService struct {
+	apikeys.APIKeyService
+
+	created []*apikeysdto.APIKeyRequest
+}
+
+func (s *fakeAPIKeyService) CreateAPIKey(ctx context.Context, request *apikeysdto.APIKeyRequest) (int, *apikeysdto.CreatedAPIKeyResponse, error) {
+	s.created = append(s.created, request)
+	return http.StatusCreated, &apikeysdto.CreatedAPIKeyResponse{Key: "pak_new"}, nil
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	admin := &auth.Principal{Subject: "admin-1", Roles: []string{auth.RoleAdmin}}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		body       string
+		wantStatus int
+	}{
+		{name: "created", principal: admin, body: `{"name":"ci","scopes":["posts:read","admin"]}`, wantStatus: http.StatusCreated},
+		{name: "unknown scope", principal: admin, body: `{"name":"ci","scopes":["posts:delete"]}`, wantStatus: http.StatusBadRequest},
+		{name: "no scopes", principal: admin, body: `{"name":"ci","scopes":[]}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed", principal: admin, body: `{"name":`, wantStatus: http.StatusBadRequest},
+		{name: "anonymous", body: `{"name":"ci","scopes":["posts:read"]}`, wantStatus: http.StatusUnauthorized},
+		{name: "not an admin", principal: &auth.Principal{Subject: "u1", Roles: []string{auth.RoleEditor}}, body: `{"name":"ci","scopes":["posts:read"]}`, wantStatus: http.StatusForbidden},
+		{name: "api keys can't mint keys", principal: &auth.Principal{Subject: "admin-1", KeyId: "k1", Scopes: []string{auth.ScopeAdmin}}, body: `{"name":"ci","scopes":["admin"]}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeAPIKeyService{}
+			h := apiKeyServiceHandler{apiKeyServiceDeps: apiKeyServiceDeps{Validator: validator.New(), APIKeyService: service}}
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.principal != nil {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+			h.Routes().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if created := len(service.created) == 1; created != (tt.wantStatus == http.StatusCreated) {
+				t.Errorf("key created = %v, want %v", created, !created)
+			}
+		})
+	}
+}
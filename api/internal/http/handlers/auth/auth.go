@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/domain/users"
+	usersdto "github.com/pedromspeixoto/posts-api/internal/dto/users"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+type AuthServiceHandler interface {
+	Routes() chi.Router
+}
+
+type authServiceDeps struct {
+	fx.In
+
+	Config      *config.Config
+	Logger      *logger.LoggingClient
+	Validator   *validator.Validate
+	UserService users.UserService
+}
+
+type authServiceHandler struct {
+	authServiceDeps
+	logger.Logger
+}
+
+func NewAuthServiceHandler(deps authServiceDeps) AuthServiceHandler {
+	return &authServiceHandler{
+		authServiceDeps: deps,
+		Logger:          deps.Logger.GetLogger(),
+	}
+}
+
+func (h authServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// auth
+	r.Post("/register", h.Register)
+	r.Post("/login", h.Login)
+	r.Post("/refresh", h.Refresh)
+	r.Post("/logout", h.Logout)
+
+	return r
+}
+
+// Register - Handles user registrations
+// @Summary Register a new user.
+// @Description This API is used to create a new local user account
+// @Param request body usersdto.RegisterRequest true "Register Payload"
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Router /v1/auth/register [post]
+func (h authServiceHandler) Register(w http.ResponseWriter, r *http.Request) {
+	register := usersdto.RegisterRequest{}
+	err := json.NewDecoder(r.Body).Decode(&register)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(register)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, userResponse, err := h.UserService.Register(r.Context(), &register)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "user registered", userResponse)
+}
+
+// Login - Handles user logins
+// @Summary Log a user in.
+// @Description This API is used to exchange user credentials for an access and a refresh token
+// @Param request body usersdto.LoginRequest true "Login Payload"
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Router /v1/auth/login [post]
+func (h authServiceHandler) Login(w http.ResponseWriter, r *http.Request) {
+	login := usersdto.LoginRequest{}
+	err := json.NewDecoder(r.Body).Decode(&login)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(login)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, tokenResponse, err := h.UserService.Login(r.Context(), &login)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "user logged in", tokenResponse)
+}
+
+// Refresh - Handles access token refreshes
+// @Summary Refresh an access token.
+// @Description This API is used to exchange a refresh token for a new access and refresh token
+// @Param request body usersdto.RefreshRequest true "Refresh Payload"
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Router /v1/auth/refresh [post]
+func (h authServiceHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	refresh := usersdto.RefreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(&refresh)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(refresh)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, tokenResponse, err := h.UserService.Refresh(r.Context(), &refresh)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "token refreshed", tokenResponse)
+}
+
+// Logout - Handles user logouts
+// @Summary Log a user out.
+// @Description This API is used to revoke a refresh token
+// @Param request body usersdto.RefreshRequest true "Logout Payload"
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Router /v1/auth/logout [post]
+func (h authServiceHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	logout := usersdto.RefreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(&logout)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(logout)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.UserService.Logout(r.Context(), &logout)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "user logged out", env)
+}
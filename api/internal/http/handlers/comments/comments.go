@@ -0,0 +1,197 @@
+package comments
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	commentmodel "github.com/pedromspeixoto/posts-api/internal/data/models/comments"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/domain/comments"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	commentsdto "github.com/pedromspeixoto/posts-api/internal/dto/comments"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+// CommentServiceHandler serves the comments of a post. Its routes are mounted under
+// a post, they expect the postId URL parameter to be set.
+type CommentServiceHandler interface {
+	Routes() chi.Router
+}
+
+type commentServiceDeps struct {
+	fx.In
+
+	Config         *config.Config
+	Logger         *logger.LoggingClient
+	Validator      *validator.Validate
+	CommentService comments.CommentService
+}
+
+type commentServiceHandler struct {
+	commentServiceDeps
+	logger.Logger
+}
+
+func NewCommentServiceHandler(deps commentServiceDeps) CommentServiceHandler {
+	return &commentServiceHandler{
+		commentServiceDeps: deps,
+		Logger:             deps.Logger.GetLogger(),
+	}
+}
+
+func (h commentServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// comments (read)
+	r.With(middlewares.Paginate(commentmodel.QuerySchema)).Get("/", h.ListComments)
+
+	// comments (write)
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.RequireScopes(auth.ScopeCommentsWrite))
+		r.Post("/", h.CreateComment)
+		r.Put("/{commentId}", h.UpdateComment)
+		r.Delete("/{commentId}", h.DeleteComment)
+	})
+
+	return r
+}
+
+// ListComments - Handles listing the comments of a post
+// @Summary Gets the comments of a post.
+// @Description This API is used to list the comments of a post, oldest first. Replies carry the id of the comment they answer as parent_id
+// @Param post_id path string true "Post Id"
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. created_at.desc"
+// @Param filter query string false "Filters as field:operator:value separated by commas, e.g. parent_id:{comment_id} for the replies to a comment"
+// @Param search query string false "Free text searched in all searchable fields"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Param count query bool false "Whether to count total rows, defaults to false with a cursor"
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Router /v1/posts/{post_id}/comments [get]
+func (h commentServiceHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.CommentService.ListComments(r.Context(), postId, pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "comments retrieved", env)
+}
+
+// CreateComment - Handles commenting on posts
+// @Summary Comment on a post.
+// @Description This API is used to comment on a post, or to reply to one of its comments by setting parent_id
+// @Param post_id path string true "Post Id"
+// @Param request body commentsdto.CommentRequest true "Comment Payload"
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/comments [post]
+func (h commentServiceHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	comment := commentsdto.CommentRequest{}
+	err := json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(comment)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, commentResponse, err := h.CommentService.CreateComment(r.Context(), postId, &comment)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "new comment created", commentResponse)
+}
+
+// UpdateComment - Handles comment updates
+// @Summary Update a comment.
+// @Description This API is used to update the content of a comment, only its author or an admin can
+// @Param post_id path string true "Post Id"
+// @Param comment_id path string true "Comment Id"
+// @Param request body commentsdto.CommentUpdateRequest true "Comment Update Payload"
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/comments/{comment_id} [put]
+func (h commentServiceHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	commentId := chi.URLParam(r, "commentId")
+	comment := commentsdto.CommentUpdateRequest{}
+	err := json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(comment)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, commentResponse, err := h.CommentService.UpdateComment(r.Context(), postId, commentId, &comment)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "comment updated", commentResponse)
+}
+
+// DeleteComment - Handles comment deletions
+// @Summary Delete a comment.
+// @Description This API is used to delete a comment, only its author or an admin can. Replies to it are kept
+// @Param post_id path string true "Post Id"
+// @Param comment_id path string true "Comment Id"
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/comments/{comment_id} [delete]
+func (h commentServiceHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	commentId := chi.URLParam(r, "commentId")
+	statusCode, env, err := h.CommentService.DeleteComment(r.Context(), postId, commentId)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "comment deleted", env)
+}
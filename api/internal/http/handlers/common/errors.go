@@ -43,6 +43,20 @@ func Unauthorization(msg string) error {
 	}
 }
 
+func Forbidden(msg string) error {
+	return &RespError{
+		Code:    http.StatusForbidden,
+		Message: msg,
+	}
+}
+
+func TooManyRequests(msg string) error {
+	return &RespError{
+		Code:    http.StatusTooManyRequests,
+		Message: msg,
+	}
+}
+
 func FindErrorType(err error) error {
 	re := regexp.MustCompile(`not found.?`)
 	if re.FindString(err.Error()) != "" {
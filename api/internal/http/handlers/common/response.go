@@ -2,6 +2,7 @@ package common
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -12,8 +13,9 @@ type Response struct {
 }
 
 type ErrorResponse struct {
-	StatusCode   int    `json:"status_code,omitempty"`
-	ErrorMessage string `json:"error,omitempty"`
+	StatusCode   int         `json:"status_code,omitempty"`
+	ErrorMessage string      `json:"error,omitempty"`
+	Details      interface{} `json:"details,omitempty"`
 }
 
 func (r *ErrorResponse) Error() string {
@@ -45,3 +47,24 @@ func Err(w http.ResponseWriter, statusCode int, errorMessage string) {
 	}
 	json.NewEncoder(w).Encode(res)
 }
+
+// RespErr writes a RespError with its status code, any other error as a 500.
+func RespErr(w http.ResponseWriter, err error) {
+	var respErr *RespError
+	if errors.As(err, &respErr) {
+		Err(w, respErr.Code, respErr.Message)
+		return
+	}
+	Err(w, http.StatusInternalServerError, err.Error())
+}
+
+func ErrDetails(w http.ResponseWriter, statusCode int, errorMessage string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	res := ErrorResponse{
+		StatusCode:   statusCode,
+		ErrorMessage: errorMessage,
+		Details:      details,
+	}
+	json.NewEncoder(w).Encode(res)
+}
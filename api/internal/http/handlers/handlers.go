@@ -1,14 +1,30 @@
 package handlers
 
 import (
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/apikeys"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/auth"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/comments"
 	"github.com/pedromspeixoto/posts-api/internal/http/handlers/health"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/media"
 	"github.com/pedromspeixoto/posts-api/internal/http/handlers/posts"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/tags"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/trash"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/users"
 	"go.uber.org/fx"
 )
 
 func ProvideHandlers() fx.Option {
 	return fx.Provide(
 		health.NewHealthServiceHandler,
+		auth.NewAuthServiceHandler,
 		posts.NewPostServiceHandler,
+		users.NewUserServiceHandler,
+		apikeys.NewAPIKeyServiceHandler,
+		trash.NewTrashServiceHandler,
+		tags.NewTagServiceHandler,
+		comments.NewCommentServiceHandler,
+		media.NewMediaServiceHandler,
+		jobs.NewJobServiceHandler,
 	)
 }
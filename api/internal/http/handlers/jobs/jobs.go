@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/domain/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+type JobServiceHandler interface {
+	Routes() chi.Router
+}
+
+type jobServiceDeps struct {
+	fx.In
+
+	Config     *config.Config
+	Logger     *logger.LoggingClient
+	JobService jobs.JobService
+}
+
+type jobServiceHandler struct {
+	jobServiceDeps
+	logger.Logger
+}
+
+func NewJobServiceHandler(deps jobServiceDeps) JobServiceHandler {
+	return &jobServiceHandler{
+		jobServiceDeps: deps,
+		Logger:         deps.Logger.GetLogger(),
+	}
+}
+
+func (h jobServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// jobs (read), callers only see the jobs they submitted
+	r.Get("/{jobId}", h.GetJob)
+
+	return r
+}
+
+// GetJob - Handles job status requests
+// @Summary Get the status of a job.
+// @Description This API is used to follow a background job, like a large import of posts, until it finishes. The result holds what the job came to, e.g. the report of an import. Only whoever submitted the job and admins can see it
+// @Param job_id path string true "Job Id"
+// @Tags jobs
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/jobs/{job_id} [get]
+func (h jobServiceHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	statusCode, job, err := h.JobService.GetJob(r.Context(), chi.URLParam(r, "jobId"))
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "job retrieved", job)
+}
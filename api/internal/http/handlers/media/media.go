@@ -0,0 +1,143 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/domain/media"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/etag"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+const (
+	// fileField is the name of the multipart form field uploads are read from.
+	fileField = "file"
+	// multipartOverhead is how much of an upload body may be taken by the multipart
+	// boundaries, headers and other fields on top of the file itself.
+	multipartOverhead = 1 << 20
+	// cacheControl lets clients and proxies cache downloads for good, media never
+	// change once uploaded.
+	cacheControl = "public, max-age=31536000, immutable"
+)
+
+type MediaServiceHandler interface {
+	Routes() chi.Router
+}
+
+type mediaServiceDeps struct {
+	fx.In
+
+	Config       *config.Config
+	Logger       *logger.LoggingClient
+	MediaService media.MediaService
+}
+
+type mediaServiceHandler struct {
+	mediaServiceDeps
+	logger.Logger
+}
+
+func NewMediaServiceHandler(deps mediaServiceDeps) MediaServiceHandler {
+	return &mediaServiceHandler{
+		mediaServiceDeps: deps,
+		Logger:           deps.Logger.GetLogger(),
+	}
+}
+
+func (h mediaServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// media (read)
+	r.Get("/{mediaId}", h.GetMedia)
+
+	// media (write), uploads are attached to posts
+	r.With(middlewares.RequireScopes(auth.ScopePostsWrite)).Post("/", h.UploadMedia)
+
+	return r
+}
+
+// UploadMedia - Handles media uploads
+// @Summary Upload media.
+// @Description This API is used to upload an image or a file that posts can then reference by id. The type is detected from the content and must be one of the allowed types, and the size is limited
+// @Param file formData file true "File to upload"
+// @Tags media
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/media [post]
+func (h mediaServiceHandler) UploadMedia(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.Config.MediaMaxSize+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, "expected a multipart/form-data body: "+err.Error())
+		return
+	}
+
+	// the file is streamed to the blob store as it's read, never buffered whole
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			common.Err(w, http.StatusBadRequest, "missing "+fileField+" field")
+			return
+		}
+		if err != nil {
+			common.Err(w, http.StatusBadRequest, "malformed multipart body: "+err.Error())
+			return
+		}
+		if part.FormName() != fileField {
+			part.Close()
+			continue
+		}
+
+		statusCode, mediaResponse, err := h.MediaService.UploadMedia(r.Context(), part.FileName(), part)
+		part.Close()
+		if err != nil {
+			common.Err(w, statusCode, err.Error())
+			return
+		}
+
+		w.Header().Set("Location", mediaResponse.URL)
+		common.Json(w, statusCode, "media uploaded", mediaResponse)
+		return
+	}
+}
+
+// GetMedia - Handles media downloads
+// @Summary Download media.
+// @Description This API is used to download the content of media. Range requests are supported and responses can be cached for good, as media never change
+// @Param mediaId path string true "Media ID"
+// @Param Range header string false "Byte range to download, e.g. bytes=0-1023"
+// @Tags media
+// @Produce  octet-stream
+// @Router /v1/media/{mediaId} [get]
+func (h mediaServiceHandler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	statusCode, content, err := h.MediaService.OpenMedia(r.Context(), chi.URLParam(r, "mediaId"))
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+	defer content.Content.Close()
+
+	w.Header().Set("Content-Type", content.ContentType)
+	disposition := map[string]string{}
+	if content.Filename != "" {
+		disposition["filename"] = content.Filename
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", disposition))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag.FromChecksum(content.Checksum))
+
+	// ServeContent answers range and conditional requests
+	http.ServeContent(w, r, content.Filename, content.CreatedAt, content.Content)
+}
@@ -0,0 +1,127 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/domain/media"
+	mediadto "github.com/pedromspeixoto/posts-api/internal/dto/media"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/etag"
+	"gorm.io/gorm"
+)
+
+// fakeMediaService serves a single media. Other calls panic.
+type fakeMediaService struct {
+	media.MediaService
+
+	content []byte
+	closed  bool
+}
+
+type closer struct {
+	io.ReadSeeker
+	closed *bool
+}
+
+func (c closer) Close() error {
+	*c.closed = true
+	return nil
+}
+
+func (s *fakeMediaService) OpenMedia(ctx context.Context, uuid string) (int, *mediadto.MediaContent, error) {
+	if uuid != "m1" {
+		return http.StatusNotFound, nil, gorm.ErrRecordNotFound
+	}
+	return http.StatusOK, &mediadto.MediaContent{
+		MediaResponse: mediadto.MediaResponse{MediaId: "m1", Filename: "report.pdf", ContentType: "application/pdf", Checksum: "abc", CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		Content:       closer{ReadSeeker: bytes.NewReader(s.content), closed: &s.closed},
+	}, nil
+}
+
+func TestGetMedia(t *testing.T) {
+	content := []byte("0123456789")
+
+	tests := []struct {
+		name       string
+		mediaId    string
+		header     http.Header
+		wantStatus int
+		wantBody   string
+		wantRange  string
+	}{
+		{name: "whole content", mediaId: "m1", wantStatus: http.StatusOK, wantBody: "0123456789"},
+		{name: "range", mediaId: "m1", header: http.Header{"Range": {"bytes=2-5"}}, wantStatus: http.StatusPartialContent, wantBody: "2345", wantRange: "bytes 2-5/10"},
+		{name: "suffix range", mediaId: "m1", header: http.Header{"Range": {"bytes=-3"}}, wantStatus: http.StatusPartialContent, wantBody: "789", wantRange: "bytes 7-9/10"},
+		{name: "unsatisfiable range", mediaId: "m1", header: http.Header{"Range": {"bytes=20-30"}}, wantStatus: http.StatusRequestedRangeNotSatisfiable, wantRange: "bytes */10"},
+		{name: "cached", mediaId: "m1", header: http.Header{"If-None-Match": {etag.FromChecksum("abc")}}, wantStatus: http.StatusNotModified},
+		{name: "missing", mediaId: "m2", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeMediaService{content: content}
+			h := mediaServiceHandler{mediaServiceDeps: mediaServiceDeps{MediaService: service}}
+
+			r := httptest.NewRequest(http.MethodGet, "/"+tt.mediaId, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			h.Routes().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code == http.StatusNotFound {
+				return
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+			if got := w.Header().Get("Content-Range"); got != tt.wantRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantRange)
+			}
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want the type never sniffed by browsers", got)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != `inline; filename=report.pdf` {
+				t.Errorf("Content-Disposition = %q", got)
+			}
+			if !service.closed {
+				t.Error("content wasn't closed")
+			}
+		})
+	}
+}
+
+func TestUploadMediaRejectsMalformedBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "not multipart", contentType: "application/json", body: `{}`},
+		{name: "missing file field", contentType: "multipart/form-data; boundary=b", body: "--b\r\nContent-Disposition: form-data; name=\"other\"\r\n\r\nx\r\n--b--\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mediaServiceHandler{mediaServiceDeps: mediaServiceDeps{Config: &config.Config{MediaMaxSize: 64}, MediaService: &fakeMediaService{}}}
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			h.UploadMedia(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+		})
+	}
+}
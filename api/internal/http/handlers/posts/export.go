@@ -0,0 +1,79 @@
+package posts
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+)
+
+// ExportPath is the path posts are exported from. Exports stream for as long as they
+// take, so the path is exempt from the request timeout.
+const ExportPath = "/v1/posts:export"
+
+// exportErrorTrailer is the trailer reporting errors that interrupted an export after
+// its response had started, when the status can no longer change.
+const exportErrorTrailer = "Export-Error"
+
+// Export formats
+const (
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+)
+
+// postEncoder writes exported posts in one format.
+type postEncoder interface {
+	// Begin writes what comes before the posts.
+	Begin() error
+	Encode(post *postsdto.PostResponse) error
+	// Flush writes out anything buffered.
+	Flush() error
+}
+
+// newPostEncoder returns the encoder of an export format along with its content type,
+// or nil if the format isn't supported.
+func newPostEncoder(format string, w io.Writer) (postEncoder, string) {
+	switch format {
+	case exportFormatNDJSON:
+		return &ndjsonEncoder{encoder: json.NewEncoder(w)}, "application/x-ndjson"
+	case exportFormatCSV:
+		return &csvEncoder{writer: csv.NewWriter(w)}, "text/csv; charset=utf-8"
+	}
+	return nil, ""
+}
+
+// ndjsonEncoder writes posts as one JSON document per line.
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Begin() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) Encode(post *postsdto.PostResponse) error {
+	return e.encoder.Encode(post)
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+// csvEncoder writes posts as CSV records, after a header.
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvEncoder) Begin() error {
+	return e.writer.Write(postsdto.CSVHeader)
+}
+
+func (e *csvEncoder) Encode(post *postsdto.PostResponse) error {
+	return e.writer.Write(post.CSVRecord())
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
@@ -0,0 +1,24 @@
+package posts
+
+import (
+	"mime"
+	"net/http"
+
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+)
+
+// ImportPath is the path posts are imported at. Imports that run synchronously take
+// as long as they take, so the path is exempt from the request timeout.
+const ImportPath = "/v1/posts:import"
+
+// importFormat is the format of an import, given by the format query parameter or
+// else by the content type of the request. Anything but CSV is taken for NDJSON.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "text/csv" {
+		return postsdto.ImportFormatCSV
+	}
+	return postsdto.ImportFormatNDJSON
+}
@@ -1,23 +1,44 @@
 package posts
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/go-playground/validator/v10"
 	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/idempotency"
+	postmodel "github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/domain/jobs"
 	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
 	"github.com/pedromspeixoto/posts-api/internal/dto"
 	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/comments"
 	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
 	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/etag"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/patch"
 	"go.uber.org/fx"
 )
 
+// ErrConcurrentPatch is answered when the post changed between being read and patched
+// by a request without an If-Match header.
+var ErrConcurrentPatch = errors.New("post was modified while being patched, retry the request")
+
 type PostServiceHandler interface {
 	Routes() chi.Router
+	// CollectionRoutes registers the custom methods of the posts collection, whose
+	// paths like /v1/posts:batch fall outside of the /v1/posts mount.
+	CollectionRoutes(r chi.Router)
 }
 
 type postServiceDeps struct {
@@ -27,6 +48,12 @@ type postServiceDeps struct {
 	Logger      *logger.LoggingClient
 	Validator   *validator.Validate
 	PostService posts.PostService
+	// imports too large to run synchronously run as jobs
+	JobService jobs.JobService
+	// creating posts can be retried safely with an Idempotency-Key
+	IdempotencyKeyRepository idempotency.IdempotencyKeyRepository
+	// comments are served under the post they belong to
+	CommentServiceHandler comments.CommentServiceHandler
 }
 
 type postServiceHandler struct {
@@ -44,23 +71,57 @@ func NewPostServiceHandler(deps postServiceDeps) PostServiceHandler {
 func (h postServiceHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	// posts
-	r.With(middlewares.Paginate).Get("/", h.ListPosts)
-	r.Post("/", h.CreatePost)
+	// posts (read)
+	r.With(middlewares.Paginate(postmodel.QuerySchemaWithReactions(h.Config.ReactionKinds))).Get("/", h.ListPosts)
+	r.With(middlewares.Paginate(postmodel.QuerySchema)).Get("/search", h.SearchPosts)
+	r.Get("/by-slug/{slug}", h.GetPostBySlug)
 	r.Get("/{postId}", h.GetPost)
-	r.Put("/{postId}", h.UpdatePost)
-	r.Delete("/{postId}", h.DeletePost)
+
+	// comments
+	r.Mount("/{postId}/comments", h.CommentServiceHandler.Routes())
+
+	// reactions
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.RequireScopes(auth.ScopeReactionsWrite))
+		r.Put("/{postId}/reactions/{kind}", h.ReactToPost)
+		r.Delete("/{postId}/reactions/{kind}", h.UnreactToPost)
+	})
+
+	// posts (write)
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares.RequireScopes(auth.ScopePostsWrite))
+		r.With(middlewares.Idempotency(h.IdempotencyKeyRepository, h.Config.IdempotencyKeyTTL, h.Config.IdempotencyKeyLease, h.Config.IdempotencyMaxBodySize, h.Logger)).Post("/", h.CreatePost)
+		r.Put("/{postId}", h.UpsertPost)
+		r.Patch("/{postId}", h.PatchPost)
+		r.Delete("/{postId}", h.DeletePost)
+		r.Post("/{postId}:{action}", h.PostAction)
+
+		// revisions, the history of a post is an editorial tool
+		r.With(middlewares.Paginate(postmodel.RevisionQuerySchema)).Get("/{postId}/revisions", h.ListRevisions)
+		r.Get("/{postId}/revisions/{revision}", h.GetRevision)
+		r.Post("/{postId}/revisions/{revision}:restore", h.RestoreRevision)
+		r.Get("/{postId}/diff", h.DiffRevisions)
+	})
 
 	return r
 }
 
+func (h postServiceHandler) CollectionRoutes(r chi.Router) {
+	r.With(middlewares.Paginate(postmodel.QuerySchemaWithReactions(h.Config.ReactionKinds))).Get(ExportPath, h.ExportPosts)
+	r.With(middlewares.RequireScopes(auth.ScopePostsWrite)).Post("/v1/posts:batch", h.BatchPosts)
+	r.With(middlewares.RequireScopes(auth.ScopePostsWrite)).Post(ImportPath, h.ImportPosts)
+}
+
 // CreatePost Handler - Handles posts requests creation
 // @Summary Create a new post request.
 // @Description This API is used to create a new post request
 // @Param request body postsdto.PostRequest true "Post Payload"
+// @Param Idempotency-Key header string false "Unique key that makes retries of the request return the first response instead of creating another post"
 // @Tags posts
 // @Accept  json
 // @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /v1/posts [post]
 func (h postServiceHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	post := postsdto.PostRequest{}
@@ -82,6 +143,7 @@ func (h postServiceHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", postResponse.ETag())
 	common.Json(w, statusCode, "new post created", postResponse)
 }
 
@@ -90,6 +152,12 @@ func (h postServiceHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 // @Description This API is used to list all post request created
 // @Param limit query int false "Limit"
 // @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. created_at.desc,content.asc. Reaction counts sort as the plural of the kind, e.g. likes.desc"
+// @Param filter query string false "Filters as field:operator:value separated by commas, e.g. content:contains:foo,status:draft,tag:go. Only published posts are listed to callers who cannot write posts"
+// @Param search query string false "Free text searched in all searchable fields"
+// @Param status query string false "Only list posts in this status: draft, in_review, published, archived or scheduled for unpublished posts with a publish_at"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Param count query bool false "Whether to count total rows, defaults to false with a cursor"
 // @Tags posts
 // @Accept  json
 // @Produce  json
@@ -97,16 +165,25 @@ func (h postServiceHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 func (h postServiceHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
 	limit := r.Context().Value(middlewares.LimitKey).(int)
 	page := r.Context().Value(middlewares.PageKey).(int)
-	sort := r.Context().Value(middlewares.SortKey).(string)
-	filter := r.Context().Value(middlewares.FilterKey).(map[string]string)
-	search := r.Context().Value(middlewares.SearchKey).(map[string]string)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+	}
 
-	pageRequest, err := dto.NewPaginationRequest(limit, page, sort, filter, search)
+	list := postsdto.PostListRequest{
+		Status: r.URL.Query().Get("status"),
+	}
+	err = h.Validator.Struct(list)
 	if err != nil {
 		common.Err(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	statusCode, env, err := h.postServiceDeps.PostService.ListPosts(r.Context(), pageRequest)
+	statusCode, env, err := h.postServiceDeps.PostService.ListPosts(r.Context(), &list, pageRequest)
 	if err != nil {
 		common.Err(w, statusCode, err.Error())
 		return
@@ -115,10 +192,218 @@ func (h postServiceHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
 	common.Json(w, statusCode, "posts retrieved", env)
 }
 
+// ExportPosts - Handles posts exports
+// @Summary Export posts.
+// @Description This API is used to export every post matching the same filters, search and sort as the list of posts, streamed as NDJSON or CSV. CSV cells starting with =, +, -, @ or a quote are prefixed with a quote so spreadsheets don't evaluate them, imports strip it. Errors interrupting the export once started are reported in the Export-Error trailer
+// @Param format query string false "Export format: ndjson, the default, or csv"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. created_at.desc,content.asc. Reaction counts sort as the plural of the kind, e.g. likes.desc"
+// @Param filter query string false "Filters as field:operator:value separated by commas, e.g. content:contains:foo,status:draft,tag:go. Only published posts are exported to callers who cannot write posts"
+// @Param search query string false "Free text searched in all searchable fields"
+// @Param status query string false "Only export posts in this status: draft, in_review, published, archived or scheduled for unpublished posts with a publish_at"
+// @Tags posts
+// @Produce  application/x-ndjson
+// @Produce  text/csv
+// @Router /v1/posts:export [get]
+func (h postServiceHandler) ExportPosts(w http.ResponseWriter, r *http.Request) {
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatNDJSON
+	}
+	encoder, contentType := newPostEncoder(format, w)
+	if encoder == nil {
+		common.Err(w, http.StatusBadRequest, fmt.Sprintf("unsupported export format %q, expected %s or %s", format, exportFormatNDJSON, exportFormatCSV))
+		return
+	}
+
+	list := postsdto.PostListRequest{
+		Status: r.URL.Query().Get("status"),
+	}
+	err := h.Validator.Struct(list)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the response starts with the first posts, so errors found before can still be
+	// answered with their status
+	started := false
+	begin := func() error {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"posts.%s\"", format))
+		w.Header().Set("Trailer", exportErrorTrailer)
+		w.WriteHeader(http.StatusOK)
+		return encoder.Begin()
+	}
+	flusher, _ := w.(http.Flusher)
+
+	statusCode, err := h.PostService.ExportPosts(r.Context(), &list, q, func(posts []*postsdto.PostResponse) error {
+		if !started {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		for _, post := range posts {
+			if err := encoder.Encode(post); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = begin()
+	}
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		if !started {
+			common.Err(w, statusCode, err.Error())
+			return
+		}
+		h.Errorf("export interrupted: %v", err)
+		w.Header().Set(exportErrorTrailer, err.Error())
+	}
+}
+
+// ImportPosts - Handles posts imports
+// @Summary Import posts.
+// @Description This API is used to import posts from NDJSON or CSV, in the same shape they are exported in, each row validated like a new post and the rows inserted in batched transactions. Rows whose post_id is taken are skipped, overwritten or failed as asked. Small imports run right away and answer with a report listing the line of every row that failed, large ones or async ones are queued as a job whose status and report are at the Location returned
+// @Param format query string false "Import format: ndjson or csv, defaults to csv for text/csv bodies and to ndjson otherwise"
+// @Param dry_run query bool false "Whether to validate and apply the import without keeping any of it"
+// @Param on_conflict query string false "What to do with rows whose post_id is taken: skip, overwrite or fail, the default"
+// @Param async query bool false "Whether to queue the import as a job whatever its size"
+// @Param request body string true "Posts to import"
+// @Tags posts
+// @Accept  application/x-ndjson
+// @Accept  text/csv
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts:import [post]
+func (h postServiceHandler) ImportPosts(w http.ResponseWriter, r *http.Request) {
+	request := postsdto.ImportRequest{
+		Format:     importFormat(r),
+		OnConflict: r.URL.Query().Get("on_conflict"),
+	}
+	if request.OnConflict == "" {
+		request.OnConflict = postsdto.OnConflictFail
+	}
+	var err error
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		request.DryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			common.Err(w, http.StatusBadRequest, "dry_run must be a boolean")
+			return
+		}
+	}
+	async := false
+	if raw := r.URL.Query().Get("async"); raw != "" {
+		async, err = strconv.ParseBool(raw)
+		if err != nil {
+			common.Err(w, http.StatusBadRequest, "async must be a boolean")
+			return
+		}
+	}
+	err = h.Validator.Struct(request)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// reading one byte past the synchronous limit tells small imports from large ones
+	head, err := io.ReadAll(io.LimitReader(r.Body, h.Config.ImportSyncMaxSize+1))
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, "error reading request body: "+err.Error())
+		return
+	}
+
+	if async || int64(len(head)) > h.Config.ImportSyncMaxSize {
+		statusCode, job, err := h.JobService.SubmitImport(r.Context(), &request, io.MultiReader(bytes.NewReader(head), r.Body))
+		if err != nil {
+			if errors.Is(err, jobs.ErrJobsUnavailable) && !async {
+				common.Err(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("imports over %d bytes run in the background, which is unavailable: %v", h.Config.ImportSyncMaxSize, err))
+				return
+			}
+			common.Err(w, statusCode, err.Error())
+			return
+		}
+
+		w.Header().Set("Location", job.URL)
+		common.Json(w, statusCode, "import queued", job)
+		return
+	}
+
+	statusCode, report, err := h.PostService.ImportPosts(r.Context(), &request, bytes.NewReader(head), nil)
+	if err != nil {
+		if report != nil {
+			common.ErrDetails(w, statusCode, err.Error(), report)
+			return
+		}
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "posts imported", report)
+}
+
+// SearchPosts - Handles posts full-text searches
+// @Summary Searches post requests.
+// @Description This API is used to search post requests by title and content, ranked by relevance. Results can't be sorted otherwise, a sort parameter is rejected with 400
+// @Param q query string true "Search query"
+// @Param mode query string false "Search mode, natural (default) or boolean"
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param filter query string false "Filters as field:operator:value separated by commas"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Router /v1/posts/search [get]
+func (h postServiceHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
+	search := postsdto.PostSearchRequest{
+		Query: r.URL.Query().Get("q"),
+		Mode:  r.URL.Query().Get("mode"),
+	}
+	err := h.Validator.Struct(search)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.PostService.SearchPosts(r.Context(), &search, pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "posts found", env)
+}
+
 // GetPost - Handles posts requests creation
 // @Summary Get an post request.
 // @Description This API is used to get post request created
 // @Param post_id path string true "Post Id"
+// @Param If-None-Match header string false "ETag of a cached representation"
 // @Tags posts
 // @Accept  json
 // @Produce  json
@@ -131,19 +416,106 @@ func (h postServiceHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", post.ETag())
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etag.NoneMatch(ifNoneMatch, post.ETag()) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	common.Json(w, statusCode, "post retrieved", post)
 }
 
-// UpdatePost - Handles posts requests updates
-// @Summary Updates an post request.
-// @Description This API is used to update an post request
+// GetPostBySlug - Handles posts lookups by slug
+// @Summary Get a post by slug.
+// @Description This API is used to get a post by its slug. Slugs a post went by before being renamed redirect to its current slug with a 301
+// @Param slug path string true "Slug"
+// @Param If-None-Match header string false "ETag of a cached representation"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Router /v1/posts/by-slug/{slug} [get]
+func (h postServiceHandler) GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	statusCode, post, err := h.PostService.GetPostBySlug(r.Context(), slug)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	if statusCode == http.StatusMovedPermanently {
+		w.Header().Set("Location", "/v1/posts/by-slug/"+url.PathEscape(post.Slug))
+		common.Json(w, statusCode, "post moved", post)
+		return
+	}
+
+	w.Header().Set("ETag", post.ETag())
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etag.NoneMatch(ifNoneMatch, post.ETag()) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	common.Json(w, statusCode, "post retrieved", post)
+}
+
+// BatchPosts - Handles batches of posts operations
+// @Summary Run a batch of post operations.
+// @Description This API is used to create, update and delete many posts in a single request, each operation reporting its own status and error. Atomic batches are applied all or nothing in a single transaction and answer with the status of the operation that failed, other batches apply every operation they can
+// @Param request body postsdto.BatchRequest true "Batch Payload"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts:batch [post]
+func (h postServiceHandler) BatchPosts(w http.ResponseWriter, r *http.Request) {
+	// the body is decoded whole before operations are counted, so its size is bounded
+	r.Body = http.MaxBytesReader(w, r.Body, h.Config.BatchMaxSize)
+
+	batch := postsdto.BatchRequest{}
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			common.Err(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch is too large, the limit is %d bytes", tooLarge.Limit))
+			return
+		}
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// operations are validated one by one, so they can fail on their own
+	err = h.Validator.Struct(batch)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, batchResponse, err := h.PostService.BatchPosts(r.Context(), &batch)
+	if err != nil {
+		if batchResponse != nil {
+			common.ErrDetails(w, statusCode, err.Error(), batchResponse)
+			return
+		}
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "batch applied", batchResponse)
+}
+
+// UpsertPost - Handles posts requests creation or replacement
+// @Summary Creates or replaces a post request.
+// @Description This API is used to create a post at a client chosen id, answering with 201, or to replace the post already there, answering with 200. The id must be a UUID and If-Match only applies to replacements. Creates and replaces racing on the same id resolve in a single statement
 // @Param post_id path string true "Post Id"
-// @Param request body postsdto.PostRequest true "Post Update Payload"
+// @Param If-Match header string false "ETag of the post being replaced"
+// @Param request body postsdto.PostRequest true "Post Payload"
 // @Tags posts
 // @Accept  json
 // @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /v1/posts/{post_id} [put]
-func (h postServiceHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+func (h postServiceHandler) UpsertPost(w http.ResponseWriter, r *http.Request) {
 	postId := chi.URLParam(r, "postId")
 	post := postsdto.PostRequest{}
 	err := json.NewDecoder(r.Body).Decode(&post)
@@ -158,26 +530,125 @@ func (h postServiceHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	statusCode, postResponse, err := h.PostService.UpdatePost(r.Context(), postId, &post)
+	statusCode, postResponse, err := h.PostService.UpsertPost(r.Context(), postId, &post, r.Header.Get("If-Match"))
 	if err != nil {
 		common.Err(w, statusCode, err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", postResponse.ETag())
+	if statusCode == http.StatusCreated {
+		w.Header().Set("Location", "/v1/posts/"+postResponse.PostId)
+		common.Json(w, statusCode, "new post created", postResponse)
+		return
+	}
+	common.Json(w, statusCode, "post updated", postResponse)
+}
+
+// PatchPost - Handles posts partial updates
+// @Summary Partially updates an post request.
+// @Description This API is used to partially update an post request using JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902). Without an If-Match header the patch only applies to the version it was computed against, a concurrent change answers 409 Conflict.
+// @Param post_id path string true "Post Id"
+// @Param If-Match header string false "ETag of the post being updated"
+// @Param request body object true "Merge Patch or JSON Patch document"
+// @Tags posts
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id} [patch]
+func (h postServiceHandler) PatchPost(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	patchDoc, err := io.ReadAll(r.Body)
+	if err != nil {
+		common.Err(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode, current, err := h.PostService.GetPost(r.Context(), postId)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	original, err := json.Marshal(postsdto.NewPostRequestFromResponse(current))
+	if err != nil {
+		common.Err(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	patched, err := patch.Apply(r.Header.Get("Content-Type"), original, patchDoc)
+	if err != nil {
+		if errors.Is(err, patch.ErrUnsupportedMediaType) {
+			common.Err(w, http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	post := postsdto.PostRequest{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&post)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Validator.Struct(post)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the patch applies to the post as read above, so without an If-Match it's updated
+	// only if nobody else changed it in the meantime
+	ifMatch, implicit := r.Header.Get("If-Match"), false
+	if ifMatch == "" && !h.Config.RequireIfMatch {
+		ifMatch, implicit = etag.FromVersion(current.Version), true
+	}
+
+	statusCode, postResponse, err := h.PostService.UpdatePost(r.Context(), postId, &post, ifMatch)
+	if err != nil {
+		if implicit && statusCode == http.StatusPreconditionFailed {
+			common.Err(w, http.StatusConflict, ErrConcurrentPatch.Error())
+			return
+		}
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", postResponse.ETag())
 	common.Json(w, statusCode, "post updated", postResponse)
 }
 
 // DeletePost - Handles posts requests creation
 // @Summary Delete an post request.
-// @Description This API is used to delete an post request created
+// @Description This API is used to move a post to the trash, or to delete it for good when purging
 // @Param post_id path string true "Post Id"
+// @Param purge query bool false "Hard delete the post, trashed or not, admins only"
+// @Param If-Match header string false "ETag of the post being deleted"
 // @Tags posts
 // @Accept  json
 // @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /v1/posts/{post_id} [delete]
 func (h postServiceHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	postId := chi.URLParam(r, "postId")
-	statusCode, env, err := h.postServiceDeps.PostService.DeletePost(r.Context(), postId)
+	purge := false
+	if raw := r.URL.Query().Get("purge"); raw != "" {
+		var err error
+		purge, err = strconv.ParseBool(raw)
+		if err != nil {
+			common.Err(w, http.StatusBadRequest, "purge must be a boolean")
+			return
+		}
+	}
+
+	statusCode, env, err := h.postServiceDeps.PostService.DeletePost(r.Context(), postId, purge, r.Header.Get("If-Match"))
 	if err != nil {
 		common.Err(w, statusCode, err.Error())
 		return
@@ -185,3 +656,253 @@ func (h postServiceHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 
 	common.Json(w, statusCode, "", env)
 }
+
+// PostAction routes the custom methods of a post, /v1/posts/{post_id}:{action}.
+func (h postServiceHandler) PostAction(w http.ResponseWriter, r *http.Request) {
+	if chi.URLParam(r, "action") == "restore" {
+		h.RestorePost(w, r)
+		return
+	}
+	h.TransitionPost(w, r)
+}
+
+// RestorePost - Handles restoring posts from the trash
+// @Summary Restore a post from the trash.
+// @Description This API is used to restore a deleted post
+// @Param post_id path string true "Post Id"
+// @Param If-Match header string false "ETag of the post being restored"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}:restore [post]
+func (h postServiceHandler) RestorePost(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+
+	statusCode, postResponse, err := h.PostService.RestorePost(r.Context(), postId, r.Header.Get("If-Match"))
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", postResponse.ETag())
+	common.Json(w, statusCode, "post restored", postResponse)
+}
+
+// ReactToPost - Handles reactions to posts
+// @Summary React to a post.
+// @Description This API is used to react to a post with one of the configured reaction kinds. Each caller reacts at most once per kind, reacting again changes nothing
+// @Param post_id path string true "Post Id"
+// @Param kind path string true "Reaction kind, e.g. like"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/reactions/{kind} [put]
+func (h postServiceHandler) ReactToPost(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	kind := chi.URLParam(r, "kind")
+	statusCode, postResponse, err := h.PostService.ReactToPost(r.Context(), postId, kind)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	// the counts are part of the tag, so reacting changes it
+	w.Header().Set("ETag", postResponse.ETag())
+	common.Json(w, statusCode, "reaction recorded", postResponse)
+}
+
+// UnreactToPost - Handles removing reactions to posts
+// @Summary Remove a reaction to a post.
+// @Description This API is used to remove the reaction of the caller to a post, removing a reaction that isn't there changes nothing
+// @Param post_id path string true "Post Id"
+// @Param kind path string true "Reaction kind, e.g. like"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/reactions/{kind} [delete]
+func (h postServiceHandler) UnreactToPost(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	kind := chi.URLParam(r, "kind")
+	statusCode, postResponse, err := h.PostService.UnreactToPost(r.Context(), postId, kind)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	// the counts are part of the tag, so reacting changes it
+	w.Header().Set("ETag", postResponse.ETag())
+	common.Json(w, statusCode, "reaction removed", postResponse)
+}
+
+// TransitionPost - Handles posts lifecycle transitions
+// @Summary Moves a post through its lifecycle.
+// @Description This API is used to submit a draft for review, publish, unpublish or archive a post
+// @Param post_id path string true "Post Id"
+// @Param If-Match header string false "ETag of the post being transitioned"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}:submit [post]
+// @Router /v1/posts/{post_id}:publish [post]
+// @Router /v1/posts/{post_id}:unpublish [post]
+// @Router /v1/posts/{post_id}:archive [post]
+func (h postServiceHandler) TransitionPost(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	action := posts.Action(chi.URLParam(r, "action"))
+
+	statusCode, postResponse, err := h.PostService.TransitionPost(r.Context(), postId, action, r.Header.Get("If-Match"))
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", postResponse.ETag())
+	common.Json(w, statusCode, "post status updated", postResponse)
+}
+
+// ListRevisions - Handles listing the revisions of a post
+// @Summary Gets all revisions of a post.
+// @Description This API is used to list the content history of a post
+// @Param post_id path string true "Post Id"
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. revision.desc"
+// @Param filter query string false "Filters as field:operator:value separated by commas, e.g. editor_id:some-uuid"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Param count query bool false "Whether to count total rows, defaults to false with a cursor"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/revisions [get]
+func (h postServiceHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.PostService.ListRevisions(r.Context(), postId, pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "revisions retrieved", env)
+}
+
+// GetRevision - Handles getting a revision of a post
+// @Summary Get a revision of a post.
+// @Description This API is used to get the content of a post at a given revision
+// @Param post_id path string true "Post Id"
+// @Param revision path int true "Revision number"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/revisions/{revision} [get]
+func (h postServiceHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	revision, err := parseRevision("revision", chi.URLParam(r, "revision"))
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, revisionResponse, err := h.PostService.GetRevision(r.Context(), postId, revision)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "revision retrieved", revisionResponse)
+}
+
+// DiffRevisions - Handles diffing two revisions of a post
+// @Summary Diff two revisions of a post.
+// @Description This API is used to get the unified diff between the content of two revisions of a post
+// @Param post_id path string true "Post Id"
+// @Param from query int true "Revision number to diff from"
+// @Param to query int true "Revision number to diff to"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/diff [get]
+func (h postServiceHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	from, err := parseRevision("from", r.URL.Query().Get("from"))
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := parseRevision("to", r.URL.Query().Get("to"))
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, diffResponse, err := h.PostService.DiffRevisions(r.Context(), postId, from, to)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "revisions diffed", diffResponse)
+}
+
+// RestoreRevision - Handles restoring a revision of a post
+// @Summary Restore a revision of a post.
+// @Description This API is used to make the content of an old revision current again, recorded as a new revision
+// @Param post_id path string true "Post Id"
+// @Param revision path int true "Revision number"
+// @Param If-Match header string false "ETag of the post being restored"
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/posts/{post_id}/revisions/{revision}:restore [post]
+func (h postServiceHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	postId := chi.URLParam(r, "postId")
+	revision, err := parseRevision("revision", chi.URLParam(r, "revision"))
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, postResponse, err := h.PostService.RestoreRevision(r.Context(), postId, revision, r.Header.Get("If-Match"))
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", postResponse.ETag())
+	common.Json(w, statusCode, "revision restored", postResponse)
+}
+
+func parseRevision(name, value string) (uint, error) {
+	revision, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || revision == 0 {
+		return 0, fmt.Errorf("%s must be a positive revision number", name)
+	}
+	return uint(revision), nil
+}
@@ -0,0 +1,172 @@
+package posts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-playground/validator/v10"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	postsdto "github.com/pedromspeixoto/posts-api/internal/dto/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/patch"
+)
+
+// fakePostService answers GetPost with the current post and UpdatePost with status,
+// recording the request and precondition it was called with. Other calls panic.
+type fakePostService struct {
+	posts.PostService
+
+	current *postsdto.PostResponse
+	status  int
+	ifMatch *string
+	request *postsdto.PostRequest
+}
+
+func (s *fakePostService) GetPost(ctx context.Context, uuid string) (int, *postsdto.PostResponse, error) {
+	return http.StatusOK, s.current, nil
+}
+
+func (s *fakePostService) UpdatePost(ctx context.Context, uuid string, request *postsdto.PostRequest, ifMatch string) (int, *postsdto.PostResponse, error) {
+	s.ifMatch, s.request = &ifMatch, request
+	if s.status != http.StatusOK {
+		return s.status, nil, posts.ErrPreconditionFailed
+	}
+	updated := *s.current
+	updated.Title, updated.Content, updated.Version = request.Title, request.Content, s.current.Version+1
+	return http.StatusOK, &updated, nil
+}
+
+func TestPatchPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		requireIfMatch bool
+		ifMatch        string
+		contentType    string
+		status         int
+		wantStatus     int
+		wantIfMatch    *string
+	}{
+		{name: "without If-Match patches the version read", status: http.StatusOK, wantStatus: http.StatusOK, wantIfMatch: stringPtr(`"3"`)},
+		{name: "concurrent change without If-Match conflicts", status: http.StatusPreconditionFailed, wantStatus: http.StatusConflict, wantIfMatch: stringPtr(`"3"`)},
+		{name: "If-Match is passed on", ifMatch: `"3-x"`, status: http.StatusOK, wantStatus: http.StatusOK, wantIfMatch: stringPtr(`"3-x"`)},
+		{name: "stale If-Match fails the precondition", ifMatch: `"2"`, status: http.StatusPreconditionFailed, wantStatus: http.StatusPreconditionFailed, wantIfMatch: stringPtr(`"2"`)},
+		{name: "required If-Match isn't made up", requireIfMatch: true, status: http.StatusPreconditionRequired, wantStatus: http.StatusPreconditionRequired, wantIfMatch: stringPtr("")},
+		{name: "plain JSON isn't a patch", contentType: "application/json", wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakePostService{
+				current: &postsdto.PostResponse{PostId: "p1", Title: "Hello", Content: "World", Format: "plain", Version: 3},
+				status:  tt.status,
+			}
+			h := postServiceHandler{postServiceDeps: postServiceDeps{
+				Config:      &config.Config{RequireIfMatch: tt.requireIfMatch},
+				Validator:   validator.New(),
+				PostService: service,
+			}}
+			router := chi.NewRouter()
+			router.Patch("/{postId}", h.PatchPost)
+
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = patch.MergePatchContentType
+			}
+			r := httptest.NewRequest(http.MethodPatch, "/p1", strings.NewReader(`{"title":"Hi"}`))
+			r.Header.Set("Content-Type", contentType)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("PatchPost() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if (service.ifMatch == nil) != (tt.wantIfMatch == nil) || (service.ifMatch != nil && *service.ifMatch != *tt.wantIfMatch) {
+				t.Errorf("UpdatePost() If-Match = %v, want %v", deref(service.ifMatch), deref(tt.wantIfMatch))
+			}
+			if service.request != nil && (service.request.Title != "Hi" || service.request.Content != "World") {
+				t.Errorf("UpdatePost() request = %+v, want the patched post", service.request)
+			}
+			if tt.wantStatus == http.StatusOK && w.Header().Get("ETag") == "" {
+				t.Error("PatchPost() didn't answer with an ETag")
+			}
+		})
+	}
+}
+
+// fakeBatchService answers BatchPosts like an atomic batch whose second operation
+// failed, recording the batches it was called with. Other calls panic.
+type fakeBatchService struct {
+	posts.PostService
+
+	batches []*postsdto.BatchRequest
+}
+
+func (s *fakeBatchService) BatchPosts(ctx context.Context, request *postsdto.BatchRequest) (int, *postsdto.BatchResponse, error) {
+	s.batches = append(s.batches, request)
+	if len(request.Operations) < 2 {
+		return http.StatusOK, postsdto.NewBatchResponse(request.Atomic, []postsdto.BatchResult{{Op: postsdto.BatchOpDelete, Status: http.StatusOK}}), nil
+	}
+	return http.StatusNotFound, postsdto.NewBatchResponse(true, []postsdto.BatchResult{
+		{Index: 0, Op: postsdto.BatchOpDelete, Status: http.StatusFailedDependency, Error: posts.ErrBatchRolledBack.Error()},
+		{Index: 1, Op: postsdto.BatchOpDelete, Status: http.StatusNotFound, Error: "record not found"},
+	}), posts.ErrBatchRolledBack
+}
+
+func TestBatchPosts(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCalled bool
+	}{
+		{name: "applied", body: `{"operations":[{"op":"delete","post_id":"p1"}]}`, wantStatus: http.StatusOK, wantBody: `"succeeded":1`, wantCalled: true},
+		{name: "rolled back with the result of every operation", body: `{"atomic":true,"operations":[{"op":"delete","post_id":"p1"},{"op":"delete","post_id":"p2"}]}`, wantStatus: http.StatusNotFound, wantBody: `"failed":2`, wantCalled: true},
+		{name: "over the size limit", body: `{"operations":[{"op":"delete","post_id":"` + strings.Repeat("p", 128) + `"}]}`, wantStatus: http.StatusRequestEntityTooLarge, wantBody: "the limit is 128 bytes"},
+		{name: "malformed", body: `{"operations":`, wantStatus: http.StatusBadRequest},
+		{name: "no operations", body: `{"operations":[]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeBatchService{}
+			h := postServiceHandler{postServiceDeps: postServiceDeps{
+				Config:      &config.Config{BatchMaxSize: 128},
+				Validator:   validator.New(),
+				PostService: service,
+			}}
+
+			r := httptest.NewRequest(http.MethodPost, "/posts:batch", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.BatchPosts(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("BatchPosts() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("BatchPosts() body = %s, want it to contain %s", w.Body, tt.wantBody)
+			}
+			if called := len(service.batches) > 0; called != tt.wantCalled {
+				t.Errorf("batch applied = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<not called>"
+	}
+	return *s
+}
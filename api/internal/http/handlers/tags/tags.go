@@ -0,0 +1,119 @@
+package tags
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	postmodel "github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+type TagServiceHandler interface {
+	Routes() chi.Router
+}
+
+type tagServiceDeps struct {
+	fx.In
+
+	Config      *config.Config
+	Logger      *logger.LoggingClient
+	PostService posts.PostService
+}
+
+type tagServiceHandler struct {
+	tagServiceDeps
+	logger.Logger
+}
+
+func NewTagServiceHandler(deps tagServiceDeps) TagServiceHandler {
+	return &tagServiceHandler{
+		tagServiceDeps: deps,
+		Logger:         deps.Logger.GetLogger(),
+	}
+}
+
+func (h tagServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// tags
+	r.With(middlewares.Paginate(postmodel.TagQuerySchema)).Get("/", h.ListTags)
+	r.With(middlewares.Paginate(postmodel.QuerySchemaWithReactions(h.Config.ReactionKinds))).Get("/{tag}/posts", h.ListTagPosts)
+
+	return r
+}
+
+// ListTags - Handles listing tags
+// @Summary Gets all tags.
+// @Description This API is used to list the tags in use along with how many posts are filed under them, most used first. Only published posts are counted for callers who cannot write posts
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. name.asc"
+// @Param filter query string false "Filters as field:operator:value separated by commas, e.g. name:prefix:go,post_count:gte:10"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Param count query bool false "Whether to count total rows, defaults to false with a cursor"
+// @Tags tags
+// @Accept  json
+// @Produce  json
+// @Router /v1/tags [get]
+func (h tagServiceHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	pageRequest, err := paginationRequest(r)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.PostService.ListTags(r.Context(), pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "tags retrieved", env)
+}
+
+// ListTagPosts - Handles listing the posts of a tag
+// @Summary Gets the posts filed under a tag.
+// @Description This API is used to list the posts filed under a tag, the tag name is normalized so Go and go are the same tag
+// @Param tag path string true "Tag"
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas, e.g. created_at.desc"
+// @Param filter query string false "Filters as field:operator:value separated by commas"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Param count query bool false "Whether to count total rows, defaults to false with a cursor"
+// @Tags tags
+// @Accept  json
+// @Produce  json
+// @Router /v1/tags/{tag}/posts [get]
+func (h tagServiceHandler) ListTagPosts(w http.ResponseWriter, r *http.Request) {
+	pageRequest, err := paginationRequest(r)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.PostService.ListTagPosts(r.Context(), chi.URLParam(r, "tag"), pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "posts retrieved", env)
+}
+
+func paginationRequest(r *http.Request) (*dto.PaginationRequest, error) {
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	return dto.NewPaginationRequest(limit, page, q, cursor, count)
+}
@@ -0,0 +1,87 @@
+package trash
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	postmodel "github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+type TrashServiceHandler interface {
+	Routes() chi.Router
+}
+
+type trashServiceDeps struct {
+	fx.In
+
+	Config      *config.Config
+	Logger      *logger.LoggingClient
+	PostService posts.PostService
+}
+
+type trashServiceHandler struct {
+	trashServiceDeps
+	logger.Logger
+}
+
+func NewTrashServiceHandler(deps trashServiceDeps) TrashServiceHandler {
+	return &trashServiceHandler{
+		trashServiceDeps: deps,
+		Logger:           deps.Logger.GetLogger(),
+	}
+}
+
+func (h trashServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// trashed posts
+	r.With(middlewares.RequireScopes(auth.ScopePostsWrite), middlewares.Paginate(postmodel.QuerySchemaWithReactions(h.Config.ReactionKinds))).Get("/posts", h.ListTrashedPosts)
+
+	return r
+}
+
+// ListTrashedPosts - Handles listing trashed posts
+// @Summary Gets all trashed posts.
+// @Description This API is used to list deleted posts that can still be restored, admins see every author's posts
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas"
+// @Param filter query string false "Filters as field:operator:value separated by commas"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Param count query bool false "Whether to count total rows, defaults to false with a cursor"
+// @Tags trash
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/trash/posts [get]
+func (h trashServiceHandler) ListTrashedPosts(w http.ResponseWriter, r *http.Request) {
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.PostService.ListTrashedPosts(r.Context(), pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "trashed posts retrieved", env)
+}
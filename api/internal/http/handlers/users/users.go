@@ -0,0 +1,85 @@
+package users
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	postmodel "github.com/pedromspeixoto/posts-api/internal/data/models/posts"
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/dto"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+type UserServiceHandler interface {
+	Routes() chi.Router
+}
+
+type userServiceDeps struct {
+	fx.In
+
+	Config      *config.Config
+	Logger      *logger.LoggingClient
+	PostService posts.PostService
+}
+
+type userServiceHandler struct {
+	userServiceDeps
+	logger.Logger
+}
+
+func NewUserServiceHandler(deps userServiceDeps) UserServiceHandler {
+	return &userServiceHandler{
+		userServiceDeps: deps,
+		Logger:          deps.Logger.GetLogger(),
+	}
+}
+
+func (h userServiceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// user posts
+	r.With(middlewares.Paginate(postmodel.QuerySchemaWithReactions(h.Config.ReactionKinds))).Get("/{userId}/posts", h.ListUserPosts)
+
+	return r
+}
+
+// ListUserPosts - Handles listing the posts of a user
+// @Summary Gets all post requests of a user.
+// @Description This API is used to list all post requests written by a user
+// @Param user_id path string true "User Id"
+// @Param limit query int false "Limit"
+// @Param page  query int false "Page"
+// @Param sort query string false "Sort keys as field.direction separated by commas"
+// @Param filter query string false "Filters as field:operator:value separated by commas"
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor, replaces page"
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Router /v1/users/{user_id}/posts [get]
+func (h userServiceHandler) ListUserPosts(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "userId")
+	limit := r.Context().Value(middlewares.LimitKey).(int)
+	page := r.Context().Value(middlewares.PageKey).(int)
+	q := r.Context().Value(middlewares.QueryKey).(*query.Query)
+	cursor := r.Context().Value(middlewares.CursorKey).(string)
+	count := r.Context().Value(middlewares.CountKey).(bool)
+
+	pageRequest, err := dto.NewPaginationRequest(limit, page, q, cursor, count)
+	if err != nil {
+		common.Err(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statusCode, env, err := h.PostService.ListAuthorPosts(r.Context(), userId, pageRequest)
+	if err != nil {
+		common.Err(w, statusCode, err.Error())
+		return
+	}
+
+	common.Json(w, statusCode, "posts retrieved", env)
+}
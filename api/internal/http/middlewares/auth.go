@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+)
+
+const (
+	bearerScheme = "Bearer "
+	apiKeyScheme = "ApiKey "
+	apiKeyHeader = "X-API-Key"
+)
+
+// Authenticate validates the bearer token or API key of a request, if any, and stores
+// the principal in the request context. API keys are accepted either with the ApiKey
+// authorization scheme or in the X-API-Key header. Requests without credentials go
+// through anonymously, it's up to RequireRoles and RequireScopes to reject them.
+func Authenticate(verifier *auth.TokenVerifier, keys auth.APIKeyVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			key := r.Header.Get(apiKeyHeader)
+			if header == "" && key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var principal *auth.Principal
+			var err error
+			switch {
+			case hasScheme(header, bearerScheme):
+				principal, err = verifier.Verify(strings.TrimSpace(header[len(bearerScheme):]))
+			case hasScheme(header, apiKeyScheme):
+				principal, err = keys.VerifyAPIKey(r.Context(), strings.TrimSpace(header[len(apiKeyScheme):]))
+			case header == "":
+				principal, err = keys.VerifyAPIKey(r.Context(), strings.TrimSpace(key))
+			default:
+				unauthorized(w, "unsupported authorization scheme")
+				return
+			}
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrInvalidAPIKey) {
+					unauthorized(w, err.Error())
+					return
+				}
+				common.RespErr(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireRoles only lets authenticated principals holding at least one of the roles
+// through, answering 401 to anonymous callers and 403 to everyone else.
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, "authentication required")
+				return
+			}
+
+			for _, role := range roles {
+				if principal.HasRole(role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			common.RespErr(w, common.Forbidden("missing required role"))
+		})
+	}
+}
+
+// RequireScopes only lets authenticated principals granted every one of the scopes
+// through, answering 401 to anonymous callers and 403 to everyone else. User principals
+// get their scopes from their roles, API keys from the scopes they were issued with.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, "authentication required")
+				return
+			}
+
+			for _, scope := range scopes {
+				if !principal.Can(scope) {
+					common.RespErr(w, common.Forbidden(fmt.Sprintf("missing required scope %s", scope)))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasScheme(header, scheme string) bool {
+	return len(header) >= len(scheme) && strings.EqualFold(header[:len(scheme)], scheme)
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="posts-api"`)
+	common.RespErr(w, common.Unauthorization(msg))
+}
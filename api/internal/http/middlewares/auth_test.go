@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+// newTestAuth builds the token verifier and issuer the way the app does, from an HS256
+// secret.
+func newTestAuth(t *testing.T) (*auth.TokenVerifier, *auth.TokenIssuer) {
+	t.Helper()
+	var verifier *auth.TokenVerifier
+	var issuer *auth.TokenIssuer
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(&config.Config{JWTSecret: "secret", JWTAccessTokenTTL: time.Minute, LoggerLevel: logger.LoggingLevelNone}),
+		logger.ProvideLogger(),
+		auth.ProvideAuth(),
+		fx.Populate(&verifier, &issuer),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("fx.New() error = %v", err)
+	}
+	return verifier, issuer
+}
+
+// fakeAPIKeys accepts a single API key.
+type fakeAPIKeys struct {
+	key       string
+	principal *auth.Principal
+	err       error
+}
+
+func (f fakeAPIKeys) VerifyAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if key != f.key {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	return f.principal, nil
+}
+
+// principalHandler answers 200 and records the principal of the request, if any.
+func principalHandler(got **auth.Principal) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got, _ = auth.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	verifier, issuer := newTestAuth(t)
+	user := &auth.Principal{Subject: "user-1", Roles: []string{auth.RoleEditor}}
+	token, _, err := issuer.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	keyPrincipal := &auth.Principal{Subject: "user-2", Scopes: []string{auth.ScopePostsRead}, KeyId: "k1"}
+	keys := fakeAPIKeys{key: "pak_valid", principal: keyPrincipal}
+
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		keys          auth.APIKeyVerifier
+		wantStatus    int
+		wantPrincipal *auth.Principal
+	}{
+		{name: "anonymous", wantStatus: http.StatusOK},
+		{name: "bearer token", headers: map[string]string{"Authorization": "Bearer " + token}, wantStatus: http.StatusOK, wantPrincipal: user},
+		{name: "scheme is case insensitive", headers: map[string]string{"Authorization": "bearer " + token}, wantStatus: http.StatusOK, wantPrincipal: user},
+		{name: "invalid bearer token", headers: map[string]string{"Authorization": "Bearer " + token + "x"}, wantStatus: http.StatusUnauthorized},
+		{name: "api key scheme", headers: map[string]string{"Authorization": "ApiKey pak_valid"}, wantStatus: http.StatusOK, wantPrincipal: keyPrincipal},
+		{name: "api key header", headers: map[string]string{"X-API-Key": "pak_valid"}, wantStatus: http.StatusOK, wantPrincipal: keyPrincipal},
+		{name: "authorization header wins over the api key header", headers: map[string]string{"Authorization": "Bearer " + token, "X-API-Key": "pak_valid"}, wantStatus: http.StatusOK, wantPrincipal: user},
+		{name: "invalid api key", headers: map[string]string{"X-API-Key": "pak_invalid"}, wantStatus: http.StatusUnauthorized},
+		{name: "unsupported scheme", headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, wantStatus: http.StatusUnauthorized},
+		{name: "failing api key lookup", headers: map[string]string{"X-API-Key": "pak_valid"}, keys: fakeAPIKeys{err: errors.New("connection refused")}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifierKeys := tt.keys
+			if verifierKeys == nil {
+				verifierKeys = keys
+			}
+			var got *auth.Principal
+			handler := Authenticate(verifier, verifierKeys)(principalHandler(&got))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+			if !reflect.DeepEqual(got, tt.wantPrincipal) {
+				t.Errorf("principal = %+v, want %+v", got, tt.wantPrincipal)
+			}
+		})
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		scopes     []string
+		wantStatus int
+	}{
+		{name: "anonymous", scopes: []string{auth.ScopePostsWrite}, wantStatus: http.StatusUnauthorized},
+		{name: "role implies the scope", principal: &auth.Principal{Subject: "u", Roles: []string{auth.RoleEditor}}, scopes: []string{auth.ScopePostsWrite}, wantStatus: http.StatusOK},
+		{name: "role lacks the scope", principal: &auth.Principal{Subject: "u", Roles: []string{auth.RoleViewer}}, scopes: []string{auth.ScopePostsWrite}, wantStatus: http.StatusForbidden},
+		{name: "admins hold every scope", principal: &auth.Principal{Subject: "u", Roles: []string{auth.RoleAdmin}}, scopes: []string{auth.ScopePostsWrite, auth.ScopeAdmin}, wantStatus: http.StatusOK},
+		{name: "api key granted the scope", principal: &auth.Principal{Subject: "u", KeyId: "k", Scopes: []string{auth.ScopePostsWrite}}, scopes: []string{auth.ScopePostsWrite}, wantStatus: http.StatusOK},
+		{name: "api key needs every scope", principal: &auth.Principal{Subject: "u", KeyId: "k", Scopes: []string{auth.ScopePostsWrite}}, scopes: []string{auth.ScopePostsWrite, auth.ScopeCommentsWrite}, wantStatus: http.StatusForbidden},
+		{name: "api keys don't get scopes from roles", principal: &auth.Principal{Subject: "u", KeyId: "k", Roles: []string{auth.RoleAdmin}}, scopes: []string{auth.ScopePostsRead}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, RequireScopes(tt.scopes...), tt.principal, tt.wantStatus)
+		})
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		roles      []string
+		wantStatus int
+	}{
+		{name: "anonymous", roles: []string{auth.RoleEditor}, wantStatus: http.StatusUnauthorized},
+		{name: "one of the roles", principal: &auth.Principal{Subject: "u", Roles: []string{auth.RoleViewer}}, roles: []string{auth.RoleEditor, auth.RoleViewer}, wantStatus: http.StatusOK},
+		{name: "none of the roles", principal: &auth.Principal{Subject: "u", Roles: []string{auth.RoleViewer}}, roles: []string{auth.RoleEditor}, wantStatus: http.StatusForbidden},
+		{name: "admins hold every role", principal: &auth.Principal{Subject: "u", Roles: []string{auth.RoleAdmin}}, roles: []string{auth.RoleEditor}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, RequireRoles(tt.roles...), tt.principal, tt.wantStatus)
+		})
+	}
+}
+
+func assertStatus(t *testing.T, middleware func(http.Handler) http.Handler, principal *auth.Principal, want int) {
+	t.Helper()
+	var got *auth.Principal
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if principal != nil {
+		r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+	}
+	w := httptest.NewRecorder()
+	middleware(principalHandler(&got)).ServeHTTP(w, r)
+	if w.Code != want {
+		t.Errorf("status = %d, want %d: %s", w.Code, want, w.Body)
+	}
+}
@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/idempotency"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLength  = 255
+)
+
+// replayedHeaders are the response headers stored along with the response body and
+// replayed with it.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency makes requests sent with an Idempotency-Key header safe to retry. The
+// first request with a key runs and its response is stored for ttl; repeats of it get
+// the stored response back instead of running again. Reusing a key for a different
+// request is rejected with 422, and repeats sent while the first request is still in
+// flight with 409. The first request only holds the key for lease while in flight, so
+// a request that crashed without releasing it blocks retries for no longer than that.
+// Server errors aren't stored, so the request can be retried. Keys are scoped to the
+// client that sent them, so it has to run after Authenticate. Bodies over maxBodySize
+// bytes are rejected with 413 before claiming their key.
+func Idempotency(repository idempotency.IdempotencyKeyRepository, ttl, lease time.Duration, maxBodySize int64, log logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotencyKeyMaxLength {
+				common.Err(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					common.Err(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is too large, the limit is %d bytes", tooLarge.Limit))
+					return
+				}
+				common.Err(w, http.StatusBadRequest, "error reading request body: "+err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now, sum := time.Now(), fingerprint(r, body)
+			claim, claimed, err := repository.Claim(&idempotency.IdempotencyKey{
+				Scope:       clientKey(r),
+				Key:         key,
+				Fingerprint: sum,
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(lease),
+			})
+			if err != nil {
+				log.Errorf("error claiming idempotency key: %v", err)
+				common.Err(w, http.StatusInternalServerError, "error checking Idempotency-Key")
+				return
+			}
+
+			if !claimed {
+				switch {
+				case claim.Fingerprint != sum:
+					common.Err(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+				case claim.InFlight():
+					w.Header().Set("Retry-After", "1")
+					common.Err(w, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+				default:
+					replay(w, claim)
+				}
+				return
+			}
+
+			// a panicking handler leaves nothing to replay, the claim is given up
+			completed := false
+			defer func() {
+				if !completed {
+					release(repository, claim, log)
+				}
+			}()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var response bytes.Buffer
+			ww.Tee(&response)
+			next.ServeHTTP(ww, r)
+			completed = true
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				release(repository, claim, log)
+				return
+			}
+
+			claim.StatusCode = &status
+			claim.ExpiresAt = time.Now().Add(ttl)
+			claim.ResponseBody = response.Bytes()
+			claim.ResponseHeaders = map[string]string{}
+			for _, header := range replayedHeaders {
+				if value := ww.Header().Get(header); value != "" {
+					claim.ResponseHeaders[header] = value
+				}
+			}
+			if err := repository.Complete(claim); err != nil {
+				log.Errorf("error storing idempotent response: %v", err)
+				release(repository, claim, log)
+			}
+		})
+	}
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replay writes the stored response of a completed request.
+func replay(w http.ResponseWriter, claim *idempotency.IdempotencyKey) {
+	for header, value := range claim.ResponseHeaders {
+		w.Header().Set(header, value)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(*claim.StatusCode)
+	w.Write(claim.ResponseBody)
+}
+
+// release gives up a claim, failures only keep the key in flight until it expires.
+func release(repository idempotency.IdempotencyKeyRepository, claim *idempotency.IdempotencyKey, log logger.Logger) {
+	if err := repository.Release(claim); err != nil {
+		log.Errorf("error releasing idempotency key: %v", err)
+	}
+}
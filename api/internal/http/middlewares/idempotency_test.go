@@ -0,0 +1,320 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/models/idempotency"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+)
+
+// fakeIdempotencyKeys is an in memory IdempotencyKeyRepository claiming keys the way
+// the MySQL one does.
+type fakeIdempotencyKeys struct {
+	keys     map[string]*idempotency.IdempotencyKey
+	nextId   uint
+	released int
+}
+
+func newFakeIdempotencyKeys() *fakeIdempotencyKeys {
+	return &fakeIdempotencyKeys{keys: map[string]*idempotency.IdempotencyKey{}}
+}
+
+func (f *fakeIdempotencyKeys) Claim(key *idempotency.IdempotencyKey) (*idempotency.IdempotencyKey, bool, error) {
+	if existing, ok := f.keys[key.Scope+"/"+key.Key]; ok && existing.ExpiresAt.After(key.CreatedAt) {
+		copied := *existing
+		return &copied, false, nil
+	}
+	f.nextId++
+	key.ID = f.nextId
+	stored := *key
+	f.keys[key.Scope+"/"+key.Key] = &stored
+	return key, true, nil
+}
+
+func (f *fakeIdempotencyKeys) Complete(key *idempotency.IdempotencyKey) error {
+	stored, ok := f.keys[key.Scope+"/"+key.Key]
+	if !ok || stored.ID != key.ID {
+		return idempotency.ErrClaimLost
+	}
+	*stored = *key
+	return nil
+}
+
+func (f *fakeIdempotencyKeys) Release(key *idempotency.IdempotencyKey) error {
+	if stored, ok := f.keys[key.Scope+"/"+key.Key]; ok && stored.ID == key.ID {
+		delete(f.keys, key.Scope+"/"+key.Key)
+	}
+	f.released++
+	return nil
+}
+
+func (f *fakeIdempotencyKeys) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	return 0, nil
+}
+
+const (
+	testTTL         = time.Hour
+	testLease       = time.Minute
+	testMaxBodySize = 64
+)
+
+// creating answers 201 with a new id each time it runs, or status if set.
+type creating struct {
+	runs   int
+	status int
+}
+
+func (c *creating) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.runs++
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/posts/"+strconv.Itoa(c.runs))
+	w.Header().Set("X-Not-Replayed", "true")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"run":` + strconv.Itoa(c.runs) + `}`))
+}
+
+func withIdempotency(repository idempotency.IdempotencyKeyRepository, next http.Handler) http.Handler {
+	return Idempotency(repository, testTTL, testLease, testMaxBodySize, logger.NewStdoutLogger(logger.LoggingLevelNone))(next)
+}
+
+func idempotentRequest(key, body string, principal *auth.Principal) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if principal != nil {
+		r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+	}
+	return r
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysCompletedRequests(t *testing.T) {
+	repository := newFakeIdempotencyKeys()
+	next := &creating{}
+	handler := withIdempotency(repository, next)
+	user := &auth.Principal{Subject: "user-1"}
+
+	first := serve(handler, idempotentRequest("k1", `{"content":"a"}`, user))
+	if first.Code != http.StatusCreated || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("first request = %d, replayed %q", first.Code, first.Header().Get(IdempotentReplayedHeader))
+	}
+	stored := repository.keys["user:user-1/k1"]
+	if stored == nil || stored.InFlight() {
+		t.Fatal("the response wasn't stored")
+	}
+	if until := time.Until(stored.ExpiresAt); until < testTTL-time.Minute || until > testTTL {
+		t.Errorf("completed key expires in %s, want %s", until, testTTL)
+	}
+
+	retry := serve(handler, idempotentRequest("k1", `{"content":"a"}`, user))
+	if next.runs != 1 {
+		t.Errorf("handler ran %d times, want once", next.runs)
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("replay isn't marked as such")
+	}
+	for _, header := range []string{"Content-Type", "Location"} {
+		if retry.Header().Get(header) != first.Header().Get(header) {
+			t.Errorf("replayed %s = %q, want %q", header, retry.Header().Get(header), first.Header().Get(header))
+		}
+	}
+	if retry.Header().Get("X-Not-Replayed") != "" {
+		t.Error("replay includes headers that aren't stored")
+	}
+
+	// keys are scoped to the client that sent them
+	other := serve(handler, idempotentRequest("k1", `{"content":"a"}`, &auth.Principal{Subject: "user-2"}))
+	if other.Code != http.StatusCreated || next.runs != 2 {
+		t.Errorf("same key from another client = %d after %d runs, want it to run", other.Code, next.runs)
+	}
+}
+
+func TestIdempotencyRejects(t *testing.T) {
+	user := &auth.Principal{Subject: "user-1"}
+	now := time.Now()
+	status := http.StatusCreated
+
+	tests := []struct {
+		name       string
+		existing   *idempotency.IdempotencyKey
+		key        string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "key reused for another request",
+			existing:   &idempotency.IdempotencyKey{Fingerprint: "other", StatusCode: &status, ExpiresAt: now.Add(testTTL)},
+			key:        "k1",
+			body:       `{"content":"a"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "request still in flight",
+			existing:   &idempotency.IdempotencyKey{ExpiresAt: now.Add(testLease)},
+			key:        "k1",
+			body:       `{"content":"a"}`,
+			wantStatus: http.StatusConflict,
+		},
+		{name: "key too long", key: strings.Repeat("k", idempotencyKeyMaxLength+1), body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "body too large", key: "k1", body: `{"content":"` + strings.Repeat("a", testMaxBodySize) + `"}`, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeIdempotencyKeys()
+			if tt.existing != nil {
+				tt.existing.Scope, tt.existing.Key = "user:user-1", tt.key
+				if tt.existing.Fingerprint == "" {
+					tt.existing.Fingerprint = fingerprint(idempotentRequest(tt.key, tt.body, user), []byte(tt.body))
+				}
+				repository.keys["user:user-1/"+tt.key] = tt.existing
+			}
+			next := &creating{}
+			handler := withIdempotency(repository, next)
+
+			w := serve(handler, idempotentRequest(tt.key, tt.body, user))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if next.runs != 0 {
+				t.Error("rejected request ran")
+			}
+			if tt.wantStatus == http.StatusConflict && w.Header().Get("Retry-After") == "" {
+				t.Error("409 without a Retry-After header")
+			}
+			if tt.existing == nil && len(repository.keys) != 0 {
+				t.Error("rejected request claimed its key")
+			}
+		})
+	}
+}
+
+func TestIdempotencyTakesOverExpiredLeases(t *testing.T) {
+	user := &auth.Principal{Subject: "user-1"}
+	body := `{"content":"a"}`
+	repository := newFakeIdempotencyKeys()
+	repository.keys["user:user-1/k1"] = &idempotency.IdempotencyKey{
+		ID:          100,
+		Scope:       "user:user-1",
+		Key:         "k1",
+		Fingerprint: fingerprint(idempotentRequest("k1", body, user), []byte(body)),
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+	next := &creating{}
+	handler := withIdempotency(repository, next)
+
+	if w := serve(handler, idempotentRequest("k1", body, user)); w.Code != http.StatusCreated || next.runs != 1 {
+		t.Errorf("retry after the lease = %d after %d runs, want it to run", w.Code, next.runs)
+	}
+}
+
+func TestIdempotencyHoldsKeysForTheLease(t *testing.T) {
+	repository := newFakeIdempotencyKeys()
+	var lease time.Duration
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lease = time.Until(repository.keys["user:user-1/k1"].ExpiresAt)
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := withIdempotency(repository, next)
+
+	serve(handler, idempotentRequest("k1", `{}`, &auth.Principal{Subject: "user-1"}))
+	if lease <= 0 || lease > testLease {
+		t.Errorf("in flight key expires in %s, want %s", lease, testLease)
+	}
+}
+
+func TestIdempotencyReleasesFailedRequests(t *testing.T) {
+	user := &auth.Principal{Subject: "user-1"}
+
+	t.Run("server error", func(t *testing.T) {
+		repository := newFakeIdempotencyKeys()
+		next := &creating{status: http.StatusServiceUnavailable}
+		handler := withIdempotency(repository, next)
+
+		serve(handler, idempotentRequest("k1", `{}`, user))
+		if len(repository.keys) != 0 {
+			t.Fatal("failed request kept its key")
+		}
+		serve(handler, idempotentRequest("k1", `{}`, user))
+		if next.runs != 2 {
+			t.Errorf("handler ran %d times, want the retry to run", next.runs)
+		}
+	})
+
+	t.Run("client error is stored", func(t *testing.T) {
+		repository := newFakeIdempotencyKeys()
+		next := &creating{status: http.StatusBadRequest}
+		handler := withIdempotency(repository, next)
+
+		serve(handler, idempotentRequest("k1", `{}`, user))
+		w := serve(handler, idempotentRequest("k1", `{}`, user))
+		if next.runs != 1 || w.Code != http.StatusBadRequest {
+			t.Errorf("retry = %d after %d runs, want the 400 replayed", w.Code, next.runs)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		repository := newFakeIdempotencyKeys()
+		handler := withIdempotency(repository, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("the panic was swallowed")
+				}
+			}()
+			serve(handler, idempotentRequest("k1", `{}`, user))
+		}()
+		if len(repository.keys) != 0 || repository.released != 1 {
+			t.Error("panicking request kept its key")
+		}
+	})
+
+	t.Run("lost claim", func(t *testing.T) {
+		repository := newFakeIdempotencyKeys()
+		handler := withIdempotency(repository, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the lease ran out and a retry took the key over
+			repository.keys["user:user-1/k1"].ID = 100
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		serve(handler, idempotentRequest("k1", `{}`, user))
+		if stored := repository.keys["user:user-1/k1"]; stored == nil || stored.ID != 100 || !stored.InFlight() {
+			t.Error("completing a lost claim overwrote the request that took it over")
+		}
+	})
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	repository := newFakeIdempotencyKeys()
+	next := &creating{}
+	handler := withIdempotency(repository, next)
+
+	serve(handler, idempotentRequest("", `{}`, nil))
+	serve(handler, idempotentRequest("", `{}`, nil))
+	if next.runs != 2 || len(repository.keys) != 0 {
+		t.Errorf("requests without a key ran %d times and stored %d keys, want them left alone", next.runs, len(repository.keys))
+	}
+}
@@ -2,114 +2,106 @@ package middlewares
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
+
+	"github.com/pedromspeixoto/posts-api/internal/data/query"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
 )
 
 const (
 	PageKey   string = "page"
 	LimitKey  string = "limit"
-	SortKey   string = "sort"
-	FilterKey string = "filter"
-	SearchKey string = "search"
+	QueryKey  string = "query"
+	CursorKey string = "cursor"
+	CountKey  string = "count"
 )
 
 const (
-	DefaultLimit int    = 10
-	DefaultPage  int    = 1
-	DefaultSort  string = "created_at asc"
+	DefaultLimit int = 10
+	DefaultPage  int = 1
+	MaxLimit     int = 100
 )
 
-func Paginate(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// Paginate parses the pagination, filter, sort and search query parameters. Only the
+// fields and operators allowed by the schema are accepted, anything else is rejected
+// with a 400 listing every invalid parameter.
+func Paginate(schema query.Schema) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		// defaults
-		limit := DefaultLimit
-		page := DefaultPage
-		sort := DefaultSort
-		filter := map[string]string{}
-		search := map[string]string{}
+			// defaults
+			limit := DefaultLimit
+			page := DefaultPage
+			q := &query.Query{}
+			cursor := ""
+			count := true
+			countSet := false
 
-		query := r.URL.Query()
-		for key, value := range query {
-			queryValue := value[len(value)-1]
-			switch key {
-			case "limit":
-				limit, _ = strconv.Atoi(queryValue)
-				break
-			case "page":
-				page, _ = strconv.Atoi(queryValue)
-				break
-			case "sort":
-				formattedSort, err := validateSort(queryValue)
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					return
-				}
-				sort = formattedSort
-				break
-			case "filter":
-				formattedFilter, err := validateFilter(queryValue)
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					return
+			var errs query.Errors
+			values := r.URL.Query()
+			for key, value := range values {
+				queryValue := value[len(value)-1]
+				switch key {
+				case "limit":
+					formattedLimit, err := strconv.Atoi(queryValue)
+					if err != nil || formattedLimit < 1 || formattedLimit > MaxLimit {
+						errs = append(errs, query.Error{Parameter: key, Value: queryValue, Message: "should be an integer between 1 and " + strconv.Itoa(MaxLimit)})
+						break
+					}
+					limit = formattedLimit
+				case "page":
+					formattedPage, err := strconv.Atoi(queryValue)
+					if err != nil || formattedPage < 1 {
+						errs = append(errs, query.Error{Parameter: key, Value: queryValue, Message: "should be a positive integer"})
+						break
+					}
+					page = formattedPage
+				case "sort":
+					sorts, sortErrs := schema.ParseSort(queryValue)
+					errs = append(errs, sortErrs...)
+					q.Sorts = sorts
+				case "filter":
+					// filters may be repeated as well as comma separated
+					for _, filter := range value {
+						filters, filterErrs := schema.ParseFilters(filter)
+						errs = append(errs, filterErrs...)
+						q.Filters = append(q.Filters, filters...)
+					}
+				case "search":
+					q.Search = queryValue
+					q.SearchFields = schema.SearchFields()
+				case "cursor":
+					cursor = queryValue
+				case "count":
+					formattedCount, err := strconv.ParseBool(queryValue)
+					if err != nil {
+						errs = append(errs, query.Error{Parameter: key, Value: queryValue, Message: "should be a boolean"})
+						break
+					}
+					count = formattedCount
+					countSet = true
 				}
-				filter = formattedFilter
-				break
-			case "search":
-				formatedSearch, err := validateSearch(queryValue)
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					return
-				}
-				search = formatedSearch
-				break
 			}
-		}
-
-		// set final pagination context values
-		ctx := context.WithValue(r.Context(), LimitKey, limit)
-		ctx = context.WithValue(ctx, PageKey, page)
-		ctx = context.WithValue(ctx, SortKey, sort)
-		ctx = context.WithValue(ctx, FilterKey, filter)
-		ctx = context.WithValue(ctx, SearchKey, search)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
-}
-
-func validateFilter(filter string) (map[string]string, error) {
-	splits := strings.Split(filter, ".")
-	if len(splits) != 2 {
-		return nil, fmt.Errorf("malformed filter query parameter, should be field.filter")
-	}
 
-	field, value := splits[0], splits[1]
-	return map[string]string{field: value}, nil
-}
-
-func validateSearch(filter string) (map[string]string, error) {
-	splits := strings.Split(filter, ".")
-	if len(splits) != 2 {
-		return nil, fmt.Errorf("malformed search query parameter, should be field.value")
-	}
+			if len(errs) > 0 {
+				common.ErrDetails(w, http.StatusBadRequest, "invalid query parameters", errs)
+				return
+			}
 
-	field, value := splits[0], splits[1]
-	return map[string]string{field: value}, nil
-}
+			// cursor pages skip the total count unless explicitly asked for
+			if cursor != "" && !countSet {
+				count = false
+			}
 
-func validateSort(sort string) (string, error) {
-	splits := strings.Split(sort, ".")
-	if len(splits) != 2 {
-		return "", fmt.Errorf("malformed sort query, should be field.orderdirection")
-	}
+			// set final pagination context values
+			ctx := context.WithValue(r.Context(), LimitKey, limit)
+			ctx = context.WithValue(ctx, PageKey, page)
+			ctx = context.WithValue(ctx, QueryKey, q)
+			ctx = context.WithValue(ctx, CursorKey, cursor)
+			ctx = context.WithValue(ctx, CountKey, count)
 
-	field, order := splits[0], splits[1]
-	if order != "desc" && order != "asc" {
-		return "", fmt.Errorf("malformed order in sort query, should be asc or desc")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
-
-	return fmt.Sprintf("%s %s", field, strings.ToUpper(order)), nil
 }
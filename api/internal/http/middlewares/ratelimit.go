@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/common"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/ratelimit"
+)
+
+// RateLimit limits each client of a route group with token buckets kept in the store.
+// Safe methods draw from the read bucket and everything else from the write bucket.
+// Clients are told apart by API key, then user, then IP address, so it has to run
+// after Authenticate. Store failures let requests through rather than take the API
+// down with them.
+func RateLimit(store ratelimit.Store, log logger.Logger, group string, read, write ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			kind, limit := "write", write
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				kind, limit = "read", read
+			}
+			if !limit.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(r.Context(), group+":"+kind+":"+clientKey(r), limit)
+			if err != nil {
+				log.Warningf("rate limit store error, letting request through: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+			if !result.Allowed {
+				w.Header().Set("Retry-After", seconds(result.RetryAfter))
+				common.RespErr(w, common.TooManyRequests("rate limit exceeded, retry later"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies the client a request is accounted to.
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		if principal.KeyId != "" {
+			return "apikey:" + principal.KeyId
+		}
+		return "user:" + principal.Subject
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds formats a duration as whole seconds, rounded up so clients never retry early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
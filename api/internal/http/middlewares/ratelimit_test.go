@@ -0,0 +1,125 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/ratelimit"
+)
+
+// failingStore fails every take, like a store that can't be reached.
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	key := func(keyId string) *auth.Principal {
+		return &auth.Principal{Subject: "u1", KeyId: keyId, Scopes: []string{auth.ScopePostsWrite}}
+	}
+
+	type request struct {
+		method     string
+		principal  *auth.Principal
+		remoteAddr string
+		wantStatus int
+	}
+	tests := []struct {
+		name     string
+		store    ratelimit.Store
+		write    ratelimit.Limit
+		requests []request
+	}{
+		{
+			name:  "client over its quota",
+			store: ratelimit.NewMemoryStore(),
+			write: limit,
+			requests: []request{
+				{method: http.MethodGet, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodGet, remoteAddr: "10.0.0.1:5678", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "reads and writes drawn from their own buckets",
+			store: ratelimit.NewMemoryStore(),
+			write: limit,
+			requests: []request{
+				{method: http.MethodGet, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodPost, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodPost, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "api keys of the same user limited on their own",
+			store: ratelimit.NewMemoryStore(),
+			write: limit,
+			requests: []request{
+				{method: http.MethodGet, principal: key("k1"), remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodGet, principal: key("k2"), remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodGet, principal: key("k1"), remoteAddr: "10.0.0.2:1234", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "unlimited writes",
+			store: ratelimit.NewMemoryStore(),
+			requests: []request{
+				{method: http.MethodPost, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodPost, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:  "store failures let requests through",
+			store: failingStore{},
+			write: limit,
+			requests: []request{
+				{method: http.MethodGet, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+				{method: http.MethodGet, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RateLimit(tt.store, logger.NewStdoutLogger(logger.LoggingLevelNone), "posts", limit, tt.write)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(req.method, "/", nil)
+				r.RemoteAddr = req.remoteAddr
+				if req.principal != nil {
+					r = r.WithContext(auth.WithPrincipal(r.Context(), req.principal))
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if w.Code != req.wantStatus {
+					t.Fatalf("request %d status = %d, want %d", i, w.Code, req.wantStatus)
+				}
+				if _, failing := tt.store.(failingStore); failing || (req.method == http.MethodPost && !tt.write.Enabled()) {
+					if w.Header().Get("RateLimit-Limit") != "" {
+						t.Errorf("request %d answered with rate limit headers without being limited", i)
+					}
+					continue
+				}
+				if got := w.Header().Get("RateLimit-Limit"); got != "1" {
+					t.Errorf("request %d RateLimit-Limit = %q, want 1", i, got)
+				}
+				if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+					t.Errorf("request %d RateLimit-Remaining = %q, want 0", i, got)
+				}
+				retryAfter := w.Header().Get("Retry-After")
+				if (w.Code == http.StatusTooManyRequests) != (retryAfter == "60") {
+					t.Errorf("request %d Retry-After = %q, want 60 seconds only when limited", i, retryAfter)
+				}
+			}
+		})
+	}
+}
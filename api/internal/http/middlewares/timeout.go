@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// Timeout cancels the context of requests that take longer than timeout, like
+// middleware.Timeout, except for requests to the exempt paths, which stream responses
+// for as long as it takes.
+func Timeout(timeout time.Duration, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := middleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range exempt {
+				if r.URL.Path == path {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
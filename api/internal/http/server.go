@@ -18,10 +18,20 @@ import (
 	"github.com/go-chi/chi/middleware"
 	_ "github.com/pedromspeixoto/posts-api/docs"
 	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/domain/apikeys"
+	apikeyhandler "github.com/pedromspeixoto/posts-api/internal/http/handlers/apikeys"
+	authhandler "github.com/pedromspeixoto/posts-api/internal/http/handlers/auth"
 	"github.com/pedromspeixoto/posts-api/internal/http/handlers/health"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/media"
 	"github.com/pedromspeixoto/posts-api/internal/http/handlers/posts"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/tags"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/trash"
+	"github.com/pedromspeixoto/posts-api/internal/http/handlers/users"
 	"github.com/pedromspeixoto/posts-api/internal/http/middlewares"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/auth"
 	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/ratelimit"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/fx"
 )
@@ -37,8 +47,18 @@ type serverDependencies struct {
 	Config               *config.Config
 	Logger               *logger.LoggingClient
 	Sentry               *sentry.Sentry
+	TokenVerifier        *auth.TokenVerifier
+	RateLimitStore       ratelimit.Store
+	APIKeyService        apikeys.APIKeyService
 	HealthServiceHandler health.HealthServiceHandler
+	AuthServiceHandler   authhandler.AuthServiceHandler
 	PostServiceHandler   posts.PostServiceHandler
+	UserServiceHandler   users.UserServiceHandler
+	APIKeyServiceHandler apikeyhandler.APIKeyServiceHandler
+	TrashServiceHandler  trash.TrashServiceHandler
+	TagServiceHandler    tags.TagServiceHandler
+	MediaServiceHandler  media.MediaServiceHandler
+	JobServiceHandler    jobs.JobServiceHandler
 }
 
 func NewHTTPServer(lc fx.Lifecycle, deps serverDependencies) *http.Server {
@@ -70,7 +90,8 @@ func NewHTTPServer(lc fx.Lifecycle, deps serverDependencies) *http.Server {
 				<-signals
 
 				// shutdown signal with grace period of 30 seconds
-				shutdownCtx, _ := context.WithTimeout(serverCtx, 30*time.Second)
+				shutdownCtx, cancel := context.WithTimeout(serverCtx, 30*time.Second)
+				defer cancel()
 				go func() {
 					<-shutdownCtx.Done()
 					if shutdownCtx.Err() == context.DeadlineExceeded {
@@ -99,14 +120,15 @@ func registerRoutes(server *http.Server, deps serverDependencies) {
 	r.Use(middleware.RequestID)
 	r.Use(middlewares.RequestsLogger(deps.Logger.GetLogger()))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(middlewares.Timeout(60*time.Second, posts.ExportPath, posts.ImportPath))
 	r.Use(render.SetContentType(render.ContentTypeJSON))
 
 	// cors support
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "sentry-trace", "baggage"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "If-Match", "If-None-Match", "Range", "Idempotency-Key", "sentry-trace", "baggage"},
+		ExposedHeaders: []string{"ETag", "WWW-Authenticate", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Content-Range", "Accept-Ranges", "Location", "Idempotent-Replayed"},
 	}))
 
 	// define Sentry middleware if Sentry is enabled
@@ -134,8 +156,41 @@ func registerRoutes(server *http.Server, deps serverDependencies) {
 
 	// routes
 	r.Group(func(r chi.Router) {
-		r.Mount("/v1/posts", deps.PostServiceHandler.Routes())
+		r.Use(middlewares.Authenticate(deps.TokenVerifier, deps.APIKeyService))
+
+		// auth endpoints get a tighter limit of their own against credential stuffing
+		r.Group(func(r chi.Router) {
+			limit := ratelimit.Limit{Requests: deps.Config.RateLimitAuthRequests, Period: deps.Config.RateLimitAuthPeriod}
+			r.Use(rateLimit(deps, "auth", limit, limit))
+			r.Mount("/v1/auth", deps.AuthServiceHandler.Routes())
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(rateLimit(deps, "api",
+				ratelimit.Limit{Requests: deps.Config.RateLimitReadRequests, Period: deps.Config.RateLimitReadPeriod},
+				ratelimit.Limit{Requests: deps.Config.RateLimitWriteRequests, Period: deps.Config.RateLimitWritePeriod},
+			))
+			r.Mount("/v1/posts", deps.PostServiceHandler.Routes())
+			r.Group(deps.PostServiceHandler.CollectionRoutes)
+			r.Mount("/v1/users", deps.UserServiceHandler.Routes())
+			r.Mount("/v1/trash", deps.TrashServiceHandler.Routes())
+			r.Mount("/v1/tags", deps.TagServiceHandler.Routes())
+			r.Mount("/v1/media", deps.MediaServiceHandler.Routes())
+			r.Mount("/v1/jobs", deps.JobServiceHandler.Routes())
+			r.Mount("/v1/admin/api-keys", deps.APIKeyServiceHandler.Routes())
+		})
 	})
 
 	server.Handler = r
 }
+
+// rateLimit returns the rate limiting middleware of a route group, or a pass-through
+// one when rate limiting is disabled.
+func rateLimit(deps serverDependencies, group string, read, write ratelimit.Limit) func(http.Handler) http.Handler {
+	if !deps.Config.RateLimitEnabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	return middlewares.RateLimit(deps.RateLimitStore, deps.Logger.GetLogger(), group, read, write)
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// APIKeyPrefix prefixes every generated API key so they're easy to spot in configs
+// and secret scanners.
+const APIKeyPrefix = "pak_"
+
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked api key")
+
+// APIKeyVerifier resolves API keys to the principal they were issued for.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// NewAPIKey generates a random API key. It returns the key, to be handed out once,
+// and its hash, to be stored.
+func NewAPIKey() (key string, hash string, err error) {
+	token, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + token
+	return key, HashToken(key), nil
+}
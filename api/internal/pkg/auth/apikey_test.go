@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("NewAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		t.Errorf("NewAPIKey() key = %s, want it prefixed with %s", key, APIKeyPrefix)
+	}
+	if hash != HashToken(key) {
+		t.Errorf("NewAPIKey() hash = %s, want the hash of the whole key %s", hash, HashToken(key))
+	}
+	if strings.Contains(hash, strings.TrimPrefix(key, APIKeyPrefix)) {
+		t.Error("NewAPIKey() hash contains the key")
+	}
+
+	other, _, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("NewAPIKey() error = %v", err)
+	}
+	if other == key {
+		t.Error("NewAPIKey() generated the same key twice")
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	const want = "9d2d993ba08e8d9475ce85c7f21a2bb4afc42381c3cdc8f17ecfddb0e1d3f688"
+	if got := HashToken("pak_test"); got != want {
+		t.Errorf("HashToken() = %s, want the hex encoded SHA-256 hash %s", got, want)
+	}
+}
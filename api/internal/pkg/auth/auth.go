@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+func ProvideAuth() fx.Option {
+	return fx.Provide(
+		NewTokenVerifier,
+		NewTokenIssuer,
+	)
+}
+
+type authDeps struct {
+	fx.In
+
+	Config *config.Config
+	Logger *logger.LoggingClient
+}
+
+// TokenVerifier validates HS256 and RS256 signed JWT bearer tokens.
+type TokenVerifier struct {
+	issuer   string
+	audience string
+	secret   []byte
+	rsaKey   *rsa.PublicKey
+	rsaKeys  map[string]*rsa.PublicKey
+	hmacKeys map[string][]byte
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	Role  string   `json:"role,omitempty"`
+}
+
+func NewTokenVerifier(deps authDeps) (*TokenVerifier, error) {
+	verifier := &TokenVerifier{
+		issuer:   deps.Config.JWTIssuer,
+		audience: deps.Config.JWTAudience,
+		secret:   []byte(deps.Config.JWTSecret),
+	}
+
+	if deps.Config.JWTPublicKeyFile != "" {
+		pem, err := os.ReadFile(deps.Config.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading jwt public key: %v", err)
+		}
+		verifier.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing jwt public key: %v", err)
+		}
+	}
+
+	if deps.Config.JWTJWKSFile != "" {
+		var err error
+		verifier.rsaKeys, verifier.hmacKeys, err = loadJWKS(deps.Config.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(verifier.secret) == 0 && verifier.rsaKey == nil && len(verifier.rsaKeys) == 0 && len(verifier.hmacKeys) == 0 {
+		deps.Logger.GetLogger().Warning("no jwt keys configured, every bearer token will be rejected")
+	}
+
+	return verifier, nil
+}
+
+// Verify validates the signature and claims of a token and returns its principal.
+// Tokens must expire and name their subject.
+func (v *TokenVerifier) Verify(token string) (*Principal, error) {
+	parsed := &claims{}
+	_, err := jwt.ParseWithClaims(token, parsed, v.key, jwt.WithValidMethods([]string{
+		jwt.SigningMethodHS256.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+	}))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// a token without an expiry would be valid for as long as its key is
+	if parsed.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	if v.issuer != "" && !parsed.VerifyIssuer(v.issuer, true) {
+		return nil, ErrInvalidToken
+	}
+	if v.audience != "" && !parsed.VerifyAudience(v.audience, true) {
+		return nil, ErrInvalidToken
+	}
+	if parsed.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	roles := parsed.Roles
+	if parsed.Role != "" {
+		roles = append(roles, parsed.Role)
+	}
+	return &Principal{
+		Subject: parsed.Subject,
+		Roles:   roles,
+	}, nil
+}
+
+// key resolves the verification key of a token from its algorithm and key id, falling
+// back to the statically configured key when the key id isn't in the JWKS.
+func (v *TokenVerifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if key, ok := v.hmacKeys[kid]; ok {
+			return key, nil
+		}
+		if len(v.secret) > 0 {
+			return v.secret, nil
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		if v.rsaKey != nil {
+			return v.rsaKey, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
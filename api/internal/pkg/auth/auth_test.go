@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testIssuer   = "posts-api"
+	testAudience = "posts"
+)
+
+var (
+	testSecret = []byte("secret")
+	testRSAKey = mustGenerateRSAKey()
+)
+
+func mustGenerateRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// validClaims returns claims every test verifier accepts.
+func validClaims() claims {
+	now := time.Now()
+	return claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Roles: []string{RoleEditor},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, c claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestTokenVerifierVerify(t *testing.T) {
+	verifier := &TokenVerifier{issuer: testIssuer, audience: testAudience, secret: testSecret, rsaKey: &testRSAKey.PublicKey}
+	otherKey := mustGenerateRSAKey()
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		want  *Principal
+	}{
+		{
+			name:  "hs256",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS256, "", testSecret, validClaims()) },
+			want:  &Principal{Subject: "user-1", Roles: []string{RoleEditor}},
+		},
+		{
+			name:  "rs256",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "", testRSAKey, validClaims()) },
+			want:  &Principal{Subject: "user-1", Roles: []string{RoleEditor}},
+		},
+		{
+			name: "single role claim",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Roles, c.Role = nil, RoleAdmin
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+			want: &Principal{Subject: "user-1", Roles: []string{RoleAdmin}},
+		},
+		{
+			name:  "bad hmac signature",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS256, "", []byte("other"), validClaims()) },
+		},
+		{
+			name:  "bad rsa signature",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "", otherKey, validClaims()) },
+		},
+		{
+			name:  "algorithm outside the allow-list",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS512, "", testSecret, validClaims()) },
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Issuer = "someone-else"
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name: "missing issuer",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Issuer = ""
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Audience = jwt.ClaimStrings{"comments"}
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name: "not valid yet",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name: "missing expiry",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name: "missing subject",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Subject = ""
+				return sign(t, jwt.SigningMethodHS256, "", testSecret, c)
+			},
+		},
+		{
+			name:  "malformed",
+			token: func(t *testing.T) string { return "not.a.token" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifier.Verify(tt.token(t))
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify() = %+v, %v, want %v", got, err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenVerifierWithoutIssuerAndAudience(t *testing.T) {
+	verifier := &TokenVerifier{secret: testSecret}
+	c := validClaims()
+	c.Issuer, c.Audience = "", nil
+	if _, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, "", testSecret, c)); err != nil {
+		t.Errorf("Verify() error = %v, want issuer and audience left unchecked", err)
+	}
+}
+
+func TestTokenVerifierJWKS(t *testing.T) {
+	rotated := mustGenerateRSAKey()
+	path := writeJWKS(t, jwks{Keys: []jwk{
+		rsaJWK("rsa-1", &testRSAKey.PublicKey),
+		rsaJWK("rsa-2", &rotated.PublicKey),
+		{Kty: "oct", Kid: "hmac-1", K: base64.RawURLEncoding.EncodeToString([]byte("jwks-secret"))},
+		{Kty: "oct", Kid: "enc-1", Use: "enc", K: base64.RawURLEncoding.EncodeToString([]byte("enc-secret"))},
+	}})
+	rsaKeys, hmacKeys, err := loadJWKS(path)
+	if err != nil {
+		t.Fatalf("loadJWKS() error = %v", err)
+	}
+	if _, ok := hmacKeys["enc-1"]; ok {
+		t.Error("loadJWKS() kept a key that isn't for signatures")
+	}
+	verifier := &TokenVerifier{issuer: testIssuer, audience: testAudience, secret: testSecret, rsaKeys: rsaKeys, hmacKeys: hmacKeys}
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		valid bool
+	}{
+		{name: "rsa key by kid", token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa-1", testRSAKey, validClaims()) }, valid: true},
+		{name: "rotated rsa key by kid", token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa-2", rotated, validClaims()) }, valid: true},
+		{name: "kid of another rsa key", token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa-2", testRSAKey, validClaims()) }},
+		{name: "hmac key by kid", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "hmac-1", []byte("jwks-secret"), validClaims())
+		}, valid: true},
+		{name: "unknown hmac kid falls back to the secret", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "unknown", testSecret, validClaims())
+		}, valid: true},
+		{name: "unknown rsa kid without a static key", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, "unknown", testRSAKey, validClaims())
+		}},
+		{name: "key not for signatures", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "enc-1", []byte("enc-secret"), validClaims())
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(tt.token(t))
+			if tt.valid && err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestLoadJWKSRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "malformed", raw: `{"keys":`},
+		{name: "bad modulus", raw: `{"keys":[{"kty":"RSA","kid":"a","n":"!","e":"AQAB"}]}`},
+		{name: "bad exponent", raw: `{"keys":[{"kty":"RSA","kid":"a","n":"AQAB","e":"!"}]}`},
+		{name: "bad secret", raw: `{"keys":[{"kty":"oct","kid":"a","k":"!"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, []byte(tt.raw), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := loadJWKS(path); err == nil {
+				t.Error("loadJWKS() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func writeJWKS(t *testing.T, set jwks) string {
+	t.Helper()
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/uuid"
+)
+
+var ErrIssuingDisabled = errors.New("token issuing is not configured, JWT_HS256_SECRET is not set")
+
+// TokenIssuer signs HS256 access tokens for locally authenticated users.
+type TokenIssuer struct {
+	issuer   string
+	audience string
+	secret   []byte
+	ttl      time.Duration
+}
+
+func NewTokenIssuer(deps authDeps) *TokenIssuer {
+	return &TokenIssuer{
+		issuer:   deps.Config.JWTIssuer,
+		audience: deps.Config.JWTAudience,
+		secret:   []byte(deps.Config.JWTSecret),
+		ttl:      deps.Config.JWTAccessTokenTTL,
+	}
+}
+
+// Issue signs an access token for the principal and returns it with its expiry.
+func (i *TokenIssuer) Issue(principal *Principal) (string, time.Time, error) {
+	if len(i.secret) == 0 {
+		return "", time.Time{}, ErrIssuingDisabled
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+	registered := jwt.RegisteredClaims{
+		ID:        uuid.GenerateUUID(),
+		Subject:   principal.Subject,
+		Issuer:    i.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	if i.audience != "" {
+		registered.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		RegisteredClaims: registered,
+		Roles:            principal.Roles,
+	}).SignedString(i.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// NewOpaqueToken generates a random token and the hash it should be stored as.
+func NewOpaqueToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of an opaque token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
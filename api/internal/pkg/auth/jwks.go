@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwk is the subset of a JSON Web Key (RFC 7517) needed for RSA and HMAC keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// loadJWKS reads a local JWKS file into RSA public keys and HMAC secrets indexed by kid.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, map[string][]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading jwks file: %v", err)
+	}
+
+	set := jwks{}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, nil, fmt.Errorf("error parsing jwks file: %v", err)
+	}
+
+	rsaKeys := map[string]*rsa.PublicKey{}
+	hmacKeys := map[string][]byte{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		switch key.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(key.N)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error decoding modulus of key %s: %v", key.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(key.E)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error decoding exponent of key %s: %v", key.Kid, err)
+			}
+			rsaKeys[key.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "oct":
+			k, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error decoding secret of key %s: %v", key.Kid, err)
+			}
+			hmacKeys[key.Kid] = k
+		}
+	}
+	return rsaKeys, hmacKeys, nil
+}
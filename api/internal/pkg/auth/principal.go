@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+const (
+	ScopePostsRead      = "posts:read"
+	ScopePostsWrite     = "posts:write"
+	ScopeCommentsWrite  = "comments:write"
+	ScopeReactionsWrite = "reactions:write"
+	// ScopeAdmin lets API keys act on what others own, as admins do.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{ScopePostsRead, ScopePostsWrite, ScopeCommentsWrite, ScopeReactionsWrite, ScopeAdmin}
+
+// roleScopes maps the roles of user principals to the scopes they imply. Admins hold
+// every scope.
+var roleScopes = map[string][]string{
+	RoleEditor: {ScopePostsRead, ScopePostsWrite, ScopeCommentsWrite, ScopeReactionsWrite},
+	RoleViewer: {ScopePostsRead, ScopeCommentsWrite, ScopeReactionsWrite},
+}
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Roles   []string
+	// Scopes and KeyId are only set when the caller authenticated with an API key.
+	Scopes []string
+	KeyId  string
+}
+
+// HasRole reports whether the principal was granted the role. Admins hold every role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the principal was granted the scope, either directly by its
+// API key or through one of its roles.
+func (p *Principal) Can(scope string) bool {
+	if p.KeyId != "" {
+		return contains(p.Scopes, scope)
+	}
+	for _, role := range p.Roles {
+		if role == RoleAdmin || contains(roleScopes[role], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal may act on what others own. Users do with the
+// admin role, API keys only when granted ScopeAdmin, as they don't carry the role of
+// whoever created them.
+func (p *Principal) IsAdmin() bool {
+	return p.Can(ScopeAdmin)
+}
+
+// ValidScope reports whether the scope is one that can be granted to an API key.
+func ValidScope(scope string) bool {
+	return contains(Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of the context carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the request, if it was authenticated.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
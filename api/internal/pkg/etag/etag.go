@@ -0,0 +1,92 @@
+package etag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Any is the wildcard value that matches any current representation.
+const Any = "*"
+
+// FromVersion builds a strong entity tag from a resource version.
+func FromVersion(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// FromVersionAndState builds a strong entity tag from a resource version and a digest
+// of the parts of the representation that change without bumping the version, like
+// counters. MatchVersion only compares the version part of such tags.
+func FromVersionAndState(version uint, state string) string {
+	return fmt.Sprintf(`"%d-%s"`, version, state)
+}
+
+// FromChecksum builds a strong entity tag from a checksum of the representation.
+func FromChecksum(checksum string) string {
+	return fmt.Sprintf(`"%s"`, checksum)
+}
+
+// Match reports whether an If-Match header value matches the entity tag, using the
+// strong comparison function (weak tags never match).
+func Match(header, tag string) bool {
+	for _, candidate := range split(header) {
+		if candidate == Any {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchVersion reports whether an If-Match header value matches a resource version,
+// comparing only the version part of tags built by FromVersion or FromVersionAndState.
+// Weak tags never match.
+func MatchVersion(header string, version uint) bool {
+	want := strconv.FormatUint(uint64(version), 10)
+	for _, candidate := range split(header) {
+		if candidate == Any {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") || len(candidate) < 2 || !strings.HasPrefix(candidate, `"`) || !strings.HasSuffix(candidate, `"`) {
+			continue
+		}
+		opaque := candidate[1 : len(candidate)-1]
+		if i := strings.IndexByte(opaque, '-'); i >= 0 {
+			opaque = opaque[:i]
+		}
+		if opaque == want {
+			return true
+		}
+	}
+	return false
+}
+
+// NoneMatch reports whether an If-None-Match header value matches the entity tag, using
+// the weak comparison function.
+func NoneMatch(header, tag string) bool {
+	for _, candidate := range split(header) {
+		if candidate == Any {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func split(header string) []string {
+	var tags []string
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			tags = append(tags, value)
+		}
+	}
+	return tags
+}
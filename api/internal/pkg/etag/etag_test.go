@@ -0,0 +1,104 @@
+package etag
+
+import "testing"
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "version", got: FromVersion(3), want: `"3"`},
+		{name: "version and state", got: FromVersionAndState(3, "1a2b"), want: `"3-1a2b"`},
+		{name: "checksum", got: FromChecksum("deadbeef"), want: `"deadbeef"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %s, want %s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		tag    string
+		want   bool
+	}{
+		{name: "same tag", header: `"3"`, tag: `"3"`, want: true},
+		{name: "other tag", header: `"2"`, tag: `"3"`, want: false},
+		{name: "wildcard", header: "*", tag: `"3"`, want: true},
+		{name: "one of a list", header: `"1", "2" ,"3"`, tag: `"3"`, want: true},
+		{name: "none of a list", header: `"1", "2"`, tag: `"3"`, want: false},
+		{name: "weak candidate", header: `W/"3"`, tag: `"3"`, want: false},
+		{name: "weak tag", header: `"3"`, tag: `W/"3"`, want: false},
+		{name: "unquoted", header: "3", tag: `"3"`, want: false},
+		{name: "empty", header: "", tag: `"3"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.header, tt.tag); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.header, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version uint
+		want    bool
+	}{
+		{name: "version tag", header: `"3"`, version: 3, want: true},
+		{name: "version and state tag", header: `"3-1a2b"`, version: 3, want: true},
+		{name: "stale state", header: `"3-0000"`, version: 3, want: true},
+		{name: "other version", header: `"2-1a2b"`, version: 3, want: false},
+		{name: "version prefix", header: `"31"`, version: 3, want: false},
+		{name: "wildcard", header: "*", version: 3, want: true},
+		{name: "one of a list", header: `"1-a", "3-b"`, version: 3, want: true},
+		{name: "weak", header: `W/"3"`, version: 3, want: false},
+		{name: "unquoted", header: "3", version: 3, want: false},
+		{name: "lone quote", header: `"`, version: 3, want: false},
+		{name: "empty", header: "", version: 3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchVersion(tt.header, tt.version); got != tt.want {
+				t.Errorf("MatchVersion(%q, %d) = %v, want %v", tt.header, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoneMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		tag    string
+		want   bool
+	}{
+		{name: "same tag", header: `"3"`, tag: `"3"`, want: true},
+		{name: "other tag", header: `"2"`, tag: `"3"`, want: false},
+		{name: "wildcard", header: "*", tag: `"3"`, want: true},
+		{name: "one of a list", header: `"1","3"`, tag: `"3"`, want: true},
+		{name: "weak candidate", header: `W/"3"`, tag: `"3"`, want: true},
+		{name: "weak tag", header: `"3"`, tag: `W/"3"`, want: true},
+		{name: "empty", header: "", tag: `"3"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NoneMatch(tt.header, tt.tag); got != tt.want {
+				t.Errorf("NoneMatch(%q, %q) = %v, want %v", tt.header, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	OpenTag  = "<mark>"
+	CloseTag = "</mark>"
+	ellipsis = "…"
+)
+
+// Terms extracts the words of a full-text search query, dropping the MySQL boolean
+// mode operators and the words excluded with them.
+func Terms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, token := range strings.Fields(query) {
+		if strings.HasPrefix(token, "-") {
+			continue
+		}
+		fields := strings.FieldsFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_' && r != '\''
+		})
+		for _, field := range fields {
+			term := strings.ToLower(strings.Trim(field, "'"))
+			if term != "" && !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	// longest first so overlapping terms highlight the widest match
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	return terms
+}
+
+// Snippet returns an HTML escaped excerpt of about width runes around the first match
+// of any of the terms, with every match wrapped in mark tags.
+func Snippet(text string, terms []string, width int) string {
+	if len(terms) == 0 {
+		return html.EscapeString(truncate([]rune(text), 0, width))
+	}
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	re := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+
+	runes := []rune(text)
+	start := 0
+	if loc := re.FindStringIndex(text); loc != nil {
+		// center the window on the first match
+		start = len([]rune(text[:loc[0]])) - width/3
+		if start < 0 {
+			start = 0
+		}
+	}
+	excerpt := truncate(runes, start, width)
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(excerpt, -1) {
+		b.WriteString(html.EscapeString(excerpt[last:loc[0]]))
+		b.WriteString(OpenTag)
+		b.WriteString(html.EscapeString(excerpt[loc[0]:loc[1]]))
+		b.WriteString(CloseTag)
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(excerpt[last:]))
+	return b.String()
+}
+
+func truncate(runes []rune, start, width int) string {
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+	}
+	excerpt := string(runes[start:end])
+	if start > 0 {
+		excerpt = ellipsis + excerpt
+	}
+	if end < len(runes) {
+		excerpt = excerpt + ellipsis
+	}
+	return excerpt
+}
@@ -0,0 +1,51 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+const (
+	// MergePatchContentType is the media type for JSON Merge Patch documents (RFC 7396).
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type for JSON Patch documents (RFC 6902).
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported patch media type, should be application/merge-patch+json or application/json-patch+json")
+	ErrMalformedPatch       = errors.New("malformed patch document")
+)
+
+// Apply applies the patch document to the original JSON document according to the
+// semantics of the given content type and returns the patched document.
+func Apply(contentType string, original, patch []byte) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	switch mediaType {
+	case MergePatchContentType:
+		patched, err := jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedPatch, err)
+		}
+		return patched, nil
+	case JSONPatchContentType:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedPatch, err)
+		}
+		patched, err := ops.Apply(original)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedPatch, err)
+		}
+		return patched, nil
+	}
+
+	return nil, ErrUnsupportedMediaType
+}
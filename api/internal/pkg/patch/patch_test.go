@@ -0,0 +1,78 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const original = `{"title":"Hello","content":"World","tags":["go","api"]}`
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        string
+	}{
+		{name: "merge patch replaces", contentType: MergePatchContentType, patch: `{"title":"Hi"}`, want: `{"title":"Hi","content":"World","tags":["go","api"]}`},
+		{name: "merge patch removes nulls", contentType: MergePatchContentType, patch: `{"content":null}`, want: `{"title":"Hello","tags":["go","api"]}`},
+		{name: "merge patch replaces arrays", contentType: MergePatchContentType, patch: `{"tags":["rest"]}`, want: `{"title":"Hello","content":"World","tags":["rest"]}`},
+		{name: "merge patch with parameters", contentType: MergePatchContentType + "; charset=utf-8", patch: `{"title":"Hi"}`, want: `{"title":"Hi","content":"World","tags":["go","api"]}`},
+		{name: "json patch replace", contentType: JSONPatchContentType, patch: `[{"op":"replace","path":"/title","value":"Hi"}]`, want: `{"title":"Hi","content":"World","tags":["go","api"]}`},
+		{name: "json patch add to array", contentType: JSONPatchContentType, patch: `[{"op":"add","path":"/tags/-","value":"rest"}]`, want: `{"title":"Hello","content":"World","tags":["go","api","rest"]}`},
+		{name: "json patch remove", contentType: JSONPatchContentType, patch: `[{"op":"remove","path":"/tags/0"}]`, want: `{"title":"Hello","content":"World","tags":["api"]}`},
+		{name: "json patch passing test", contentType: JSONPatchContentType, patch: `[{"op":"test","path":"/title","value":"Hello"},{"op":"replace","path":"/title","value":"Hi"}]`, want: `{"title":"Hi","content":"World","tags":["go","api"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.contentType, []byte(original), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        error
+	}{
+		{name: "plain json", contentType: "application/json", patch: `{"title":"Hi"}`, want: ErrUnsupportedMediaType},
+		{name: "missing content type", contentType: "", patch: `{"title":"Hi"}`, want: ErrUnsupportedMediaType},
+		{name: "malformed content type", contentType: "application/", patch: `{"title":"Hi"}`, want: ErrUnsupportedMediaType},
+		{name: "malformed merge patch", contentType: MergePatchContentType, patch: `{"title":`, want: ErrMalformedPatch},
+		{name: "json patch that isn't a list", contentType: JSONPatchContentType, patch: `{"op":"remove"}`, want: ErrMalformedPatch},
+		{name: "json patch on a missing path", contentType: JSONPatchContentType, patch: `[{"op":"remove","path":"/missing"}]`, want: ErrMalformedPatch},
+		{name: "json patch failing test", contentType: JSONPatchContentType, patch: `[{"op":"test","path":"/title","value":"Bye"}]`, want: ErrMalformedPatch},
+		{name: "json patch unknown operation", contentType: JSONPatchContentType, patch: `[{"op":"rename","path":"/title"}]`, want: ErrMalformedPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Apply(tt.contentType, []byte(original), []byte(tt.patch)); !errors.Is(err, tt.want) {
+				t.Errorf("Apply() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
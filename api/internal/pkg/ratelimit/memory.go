@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from memory.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill adds the tokens accrued since the last update, up to the bucket capacity.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated)
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+float64(elapsed)/float64(b.limit.interval()))
+	b.updated = now
+}
+
+// MemoryStore keeps token buckets in process memory. Limits are enforced per replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		m.buckets[key] = b
+	}
+	b.refill(now)
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(limit.interval()))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(limit.Requests) - b.tokens) * float64(limit.interval()))
+	return result, nil
+}
+
+// sweep drops the buckets that have refilled completely, as they're no different from
+// a missing bucket. It must be called with the lock held.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	for key, b := range m.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Requests: 2, Period: 2 * time.Second}
+
+	type take struct {
+		after      time.Duration
+		key        string
+		limit      Limit
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "bucket starts full and empties",
+			takes: []take{
+				{key: "a", limit: limit, allowed: true, remaining: 1},
+				{key: "a", limit: limit, allowed: true, remaining: 0},
+				{key: "a", limit: limit, allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+		{
+			name: "tokens refill over time",
+			takes: []take{
+				{key: "a", limit: limit, allowed: true, remaining: 1},
+				{key: "a", limit: limit, allowed: true, remaining: 0},
+				{after: 500 * time.Millisecond, key: "a", limit: limit, allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+				{after: 500 * time.Millisecond, key: "a", limit: limit, allowed: true, remaining: 0},
+			},
+		},
+		{
+			name: "refill stops at capacity",
+			takes: []take{
+				{key: "a", limit: limit, allowed: true, remaining: 1},
+				{after: time.Hour, key: "a", limit: limit, allowed: true, remaining: 1},
+				{key: "a", limit: limit, allowed: true, remaining: 0},
+				{key: "a", limit: limit, allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+		{
+			name: "keys have their own buckets",
+			takes: []take{
+				{key: "a", limit: limit, allowed: true, remaining: 1},
+				{key: "a", limit: limit, allowed: true, remaining: 0},
+				{key: "b", limit: limit, allowed: true, remaining: 1},
+				{key: "a", limit: limit, allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+		{
+			name: "changed limits start a new bucket",
+			takes: []take{
+				{key: "a", limit: limit, allowed: true, remaining: 1},
+				{key: "a", limit: limit, allowed: true, remaining: 0},
+				{key: "a", limit: Limit{Requests: 5, Period: time.Second}, allowed: true, remaining: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			store := NewMemoryStore()
+			store.now = func() time.Time { return now }
+
+			for i, take := range tt.takes {
+				now = now.Add(take.after)
+				result, err := store.Take(context.Background(), take.key, take.limit)
+				if err != nil {
+					t.Fatalf("take %d: Take() error = %v", i, err)
+				}
+				if result.Allowed != take.allowed || result.Remaining != take.remaining || result.RetryAfter != take.retryAfter {
+					t.Errorf("take %d: Take() = allowed %v, remaining %d, retry after %s, want allowed %v, remaining %d, retry after %s",
+						i, result.Allowed, result.Remaining, result.RetryAfter, take.allowed, take.remaining, take.retryAfter)
+				}
+				if result.Limit != take.limit.Requests {
+					t.Errorf("take %d: Take() limit = %d, want %d", i, result.Limit, take.limit.Requests)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 4, Period: 4 * time.Second}
+
+	// every take leaves the bucket another token short of full
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		result, err := store.Take(context.Background(), "a", limit)
+		if err != nil {
+			t.Fatalf("take %d: Take() error = %v", i, err)
+		}
+		if result.Reset != want {
+			t.Errorf("take %d: Take() reset = %s, want %s", i, result.Reset, want)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.lastSweep = now
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 1, Period: time.Hour}
+	if _, err := store.Take(context.Background(), "a", Limit{Requests: 1, Period: time.Second}); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if _, err := store.Take(context.Background(), "b", limit); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+
+	now = now.Add(sweepInterval)
+	if _, err := store.Take(context.Background(), "c", limit); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("sweep kept the refilled bucket")
+	}
+	if _, ok := store.buckets["b"]; !ok {
+		t.Error("sweep dropped a bucket that isn't full")
+	}
+}
+
+func TestLimitEnabled(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+		want  bool
+	}{
+		{name: "enabled", limit: Limit{Requests: 1, Period: time.Second}, want: true},
+		{name: "no requests", limit: Limit{Period: time.Second}, want: false},
+		{name: "no period", limit: Limit{Requests: 1}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Enabled(); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+const StoreMemory = "memory"
+
+func ProvideRateLimit() fx.Option {
+	return fx.Provide(
+		NewStore,
+	)
+}
+
+type rateLimitDeps struct {
+	fx.In
+
+	Config *config.Config
+	Logger *logger.LoggingClient
+}
+
+// Limit is a token bucket holding Requests tokens, refilled at Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled reports whether the limit applies at all.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// interval is how long it takes for a single token to be refilled.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until a token is available, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets. Implementations have to make Take atomic per key so
+// concurrent requests, possibly from several replicas, can't overdraw a bucket.
+type Store interface {
+	// Take takes a token from the bucket of the key, creating it full if missing.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+func NewStore(deps rateLimitDeps) (Store, error) {
+	switch deps.Config.RateLimitStore {
+	case StoreMemory, "":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unsupported rate limit store %q", deps.Config.RateLimitStore)
+}
@@ -0,0 +1,159 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	nethtml "golang.org/x/net/html"
+)
+
+// Formats content can be written in.
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+const (
+	// ExcerptLength is the maximum length, in runes, of excerpts before the ellipsis.
+	ExcerptLength = 280
+	// WordsPerMinute is the reading speed reading times are estimated with.
+	WordsPerMinute = 200
+	ellipsis       = "…"
+)
+
+var (
+	// markdown renders CommonMark with the GitHub flavoured extensions. Raw HTML is
+	// passed through, it's sanitized along with the rest of the output.
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+
+	// policy is the allow-list rendered HTML is sanitized against. It keeps the
+	// formatting user generated content needs and strips scripts, styles, event
+	// handlers and unsafe URLs.
+	policy = newPolicy()
+
+	paragraphs = regexp.MustCompile(`\n\s*\n`)
+)
+
+// blocks are the elements whose boundaries separate words in the text of a document.
+var blocks = map[string]bool{
+	"address": true, "blockquote": true, "br": true, "dd": true, "div": true, "dl": true,
+	"dt": true, "figcaption": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "hr": true, "li": true, "ol": true, "p": true, "pre": true,
+	"table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// Document is content rendered for display.
+type Document struct {
+	// HTML is the sanitized HTML of the content.
+	HTML string
+	// Excerpt is the beginning of the text of the content, cut at a word boundary.
+	Excerpt string
+	// ReadingTime is the estimated time to read the content, in minutes.
+	ReadingTime uint
+}
+
+// Render renders content written in a format to sanitized HTML, along with a plain
+// text excerpt and a reading time estimate. Plain text is escaped, with blank lines
+// separating paragraphs. An empty format is plain text.
+func Render(content string, format string) (*Document, error) {
+	var unsafe string
+	switch format {
+	case FormatPlain, "":
+		unsafe = plain(content)
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			return nil, fmt.Errorf("error rendering markdown: %v", err)
+		}
+		unsafe = buf.String()
+	case FormatHTML:
+		unsafe = content
+	default:
+		return nil, fmt.Errorf("unknown content format %s", format)
+	}
+
+	safe := policy.Sanitize(unsafe)
+	text := Text(safe)
+	return &Document{
+		HTML:        safe,
+		Excerpt:     Excerpt(text, ExcerptLength),
+		ReadingTime: ReadingTime(text),
+	}, nil
+}
+
+// Text extracts the text of an HTML document, with runs of whitespace collapsed into
+// single spaces.
+func Text(document string) string {
+	var b strings.Builder
+	z := nethtml.NewTokenizer(strings.NewReader(document))
+	for {
+		switch z.Next() {
+		case nethtml.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case nethtml.TextToken:
+			b.Write(z.Text())
+		case nethtml.StartTagToken, nethtml.EndTagToken, nethtml.SelfClosingTagToken:
+			if name, _ := z.TagName(); blocks[string(name)] {
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// Excerpt cuts text to at most max runes, at the last word boundary before the limit,
+// and marks the cut with an ellipsis.
+func Excerpt(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:max])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + ellipsis
+}
+
+// ReadingTime estimates the time to read text in minutes, rounded up. Only empty text
+// takes no time.
+func ReadingTime(text string) uint {
+	words := len(strings.Fields(text))
+	return uint((words + WordsPerMinute - 1) / WordsPerMinute)
+}
+
+// plain renders plain text as HTML paragraphs, line breaks kept.
+func plain(content string) string {
+	var b strings.Builder
+	for _, paragraph := range paragraphs.Split(strings.ReplaceAll(content, "\r\n", "\n"), -1) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// language hints of fenced code blocks, for client side syntax highlighting
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	// task list checkboxes
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
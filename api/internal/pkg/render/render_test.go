@@ -0,0 +1,118 @@
+package render
+
+import "testing"
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		want    string
+	}{
+		{name: "script", content: `<script>alert(1)</script><p>hi</p>`, format: FormatHTML, want: `<p>hi</p>`},
+		{name: "event handler", content: `<img src=x onerror=alert(1)>`, format: FormatHTML, want: `<img src="x">`},
+		{name: "javascript url", content: `<a href="javascript:alert(1)">x</a>`, format: FormatHTML, want: `x`},
+		{name: "data url", content: `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, format: FormatHTML, want: `x`},
+		{name: "safe link", content: `<a href="https://example.com">x</a>`, format: FormatHTML, want: `<a href="https://example.com" rel="nofollow">x</a>`},
+		{name: "style and handler attributes", content: `<p style="color:red" onclick="x()">a</p>`, format: FormatHTML, want: `<p>a</p>`},
+		{name: "iframe and style elements", content: `<iframe src="https://example.com"></iframe><style>p{}</style>`, format: FormatHTML, want: ``},
+		{name: "svg", content: `<svg onload=alert(1)><circle/></svg>`, format: FormatHTML, want: ``},
+		{name: "code language class", content: `<code class="language-go">x</code>`, format: FormatHTML, want: `<code class="language-go">x</code>`},
+		{name: "other classes", content: `<code class="evil">x</code>`, format: FormatHTML, want: `<code>x</code>`},
+		{name: "checkbox", content: `<input type="checkbox" checked disabled>`, format: FormatHTML, want: `<input type="checkbox" checked="" disabled="">`},
+		{name: "other inputs", content: `<input type="text" value="x">`, format: FormatHTML, want: ``},
+		{name: "raw html in markdown", content: "<script>x</script>\n\n[a](javascript:alert(1))", format: FormatMarkdown, want: "\n<p>a</p>\n"},
+		{name: "markdown formatting", content: "**b** _i_ ~~s~~", format: FormatMarkdown, want: "<p><strong>b</strong> <em>i</em> <del>s</del></p>\n"},
+		{name: "fenced code", content: "```go\nx\n```", format: FormatMarkdown, want: "<pre><code class=\"language-go\">x\n</code></pre>\n"},
+		{name: "task list", content: "- [x] done", format: FormatMarkdown, want: "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n</ul>\n"},
+		{name: "plain text is escaped", content: "a <b>\n\nc\nd", format: FormatPlain, want: "<p>a &lt;b&gt;</p>\n<p>c<br>d</p>\n"},
+		{name: "empty format is plain text", content: "<i>x</i>", format: "", want: "<p>&lt;i&gt;x&lt;/i&gt;</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := Render(tt.content, tt.format)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if document.HTML != tt.want {
+				t.Errorf("Render() HTML = %q, want %q", document.HTML, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRejectsUnknownFormats(t *testing.T) {
+	if _, err := Render("x", "rst"); err == nil {
+		t.Error("Render() error = nil, want an error")
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{name: "blocks separate words", document: "<p>one</p><p>two</p>", want: "one two"},
+		{name: "inline elements don't", document: "<p>o<b>n</b>e</p>", want: "one"},
+		{name: "whitespace collapsed", document: "<p> a \n\t b </p>", want: "a b"},
+		{name: "entities decoded", document: "<p>a &amp; b</p>", want: "a & b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.document); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{name: "short", text: "hello world", max: 20, want: "hello world"},
+		{name: "cut at a word boundary", text: "hello wonderful world", max: 12, want: "hello…"},
+		{name: "trailing punctuation trimmed", text: "hello, wonderful world", max: 12, want: "hello…"},
+		{name: "single long word", text: "abcdefghij", max: 4, want: "abcd…"},
+		{name: "runes", text: "héllo wörld", max: 8, want: "héllo…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.text, tt.max); got != tt.want {
+				t.Errorf("Excerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		words int
+		want  uint
+	}{
+		{name: "empty", words: 0, want: 0},
+		{name: "one word", words: 1, want: 1},
+		{name: "one minute", words: WordsPerMinute, want: 1},
+		{name: "rounded up", words: WordsPerMinute + 1, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := ""
+			for i := 0; i < tt.words; i++ {
+				text += "word "
+			}
+			if got := ReadingTime(text); got != tt.want {
+				t.Errorf("ReadingTime() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// separator replaces every run of characters that aren't letters or digits.
+const separator = '-'
+
+// precomposed transliterates the letters whose diacritics change how they read, so
+// they are replaced before diacritics are stripped off.
+var precomposed = strings.NewReplacer(
+	"й", "y", "ё", "yo", "ї", "yi",
+)
+
+// letters transliterates the latin letters that don't decompose into a base letter
+// and diacritics, and the cyrillic and greek alphabets.
+var letters = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj",
+	'ћ': "c", 'џ': "dz",
+
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// Make normalizes a name into a URL safe slug: case folded, transliterated to ASCII,
+// with letters and digits kept and everything else collapsed into single dashes,
+// trimmed of leading and trailing dashes and cut to at most max characters. Letters
+// without an ASCII transliteration, like CJK ones, are dropped, so the slug is empty
+// if nothing else is left. Names that only differ in case, accents or punctuation
+// share a slug, so "Go", "go" and " GO! " are all "go", "Café" is "cafe" and
+// "Привет" is "privet".
+func Make(name string, max int) string {
+	return build(transliterate(strings.ToLower(name)), max, func(r rune) bool {
+		return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+}
+
+// Fold normalizes a name like Make, but keeps the letters without an ASCII
+// transliteration instead of dropping them, for names that identify things rather
+// than being part of URLs.
+func Fold(name string, max int) string {
+	return build(transliterate(strings.ToLower(name)), max, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	})
+}
+
+// build keeps the runes of name that are kept, collapsing the runs of the others into
+// single separators.
+func build(name string, max int, keep func(r rune) bool) string {
+	var b strings.Builder
+	length, pending := 0, false
+	for _, r := range name {
+		if !keep(r) {
+			pending = length > 0
+			continue
+		}
+		if pending {
+			if length+2 > max {
+				break
+			}
+			b.WriteRune(separator)
+			length++
+			pending = false
+		}
+		if length+1 > max {
+			break
+		}
+		b.WriteRune(r)
+		length++
+	}
+	return b.String()
+}
+
+// transliterate replaces letters by their ASCII transliteration, as in é to e and
+// ж to zh. Letters without one are left as they are.
+func transliterate(name string) string {
+	var b strings.Builder
+	for _, r := range stripMarks(precomposed.Replace(name)) {
+		if replacement, ok := letters[r]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// stripMarks strips diacritics off letters, as in é to e, by decomposing them and
+// dropping the combining marks.
+func stripMarks(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		return name
+	}
+	return stripped
+}
@@ -0,0 +1,61 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{name: "case folded", in: "Hello World", max: 80, want: "hello-world"},
+		{name: "punctuation collapsed and trimmed", in: "  GO!  -- rocks?? ", max: 80, want: "go-rocks"},
+		{name: "digits kept", in: "Top 10 of 2024", max: 80, want: "top-10-of-2024"},
+		{name: "accents stripped", in: "Café crème brûlée", max: 80, want: "cafe-creme-brulee"},
+		{name: "latin letters without decomposition", in: "Straße Æsir Łódź", max: 80, want: "strasse-aesir-lodz"},
+		{name: "cyrillic", in: "Привет, мир!", max: 80, want: "privet-mir"},
+		{name: "cyrillic letters with diacritics", in: "Йошкар-Ола ёлка", max: 80, want: "yoshkar-ola-yolka"},
+		{name: "ukrainian", in: "Київ є", max: 80, want: "kiyiv-ye"},
+		{name: "greek", in: "Καλημέρα κόσμε", max: 80, want: "kalimera-kosme"},
+		{name: "letters without transliteration dropped", in: "Go 日本 rocks", max: 80, want: "go-rocks"},
+		{name: "nothing left", in: "日本語", max: 80, want: ""},
+		{name: "non ascii digits dropped", in: "١٢٣", max: 80, want: ""},
+		{name: "empty", in: "", max: 80, want: ""},
+		{name: "cut to max", in: "abcdef", max: 4, want: "abcd"},
+		{name: "cut before a trailing separator", in: "abc def", max: 4, want: "abc"},
+		{name: "cut after a separator", in: "abc def", max: 5, want: "abc-d"},
+		{name: "transliteration counts towards max", in: "щука", max: 5, want: "shchu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.in, tt.max); got != tt.want {
+				t.Errorf("Make(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "case folded", in: "GoLang", want: "golang"},
+		{name: "accents stripped", in: "Café", want: "cafe"},
+		{name: "cyrillic transliterated", in: "Привет", want: "privet"},
+		{name: "letters without transliteration kept", in: "Go 日本 rocks", want: "go-日本-rocks"},
+		{name: "only letters without transliteration", in: "日本語", want: "日本語"},
+		{name: "punctuation only", in: "?!", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fold(tt.in, 80); got != tt.want {
+				t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalBlobStore keeps blobs as files under a root directory. Blobs are only shared
+// between replicas if the directory is, which only whoever deploys them can tell.
+type LocalBlobStore struct {
+	root   string
+	shared bool
+}
+
+// NewLocalBlobStore returns a blob store rooted at dir, creating it if missing. shared
+// tells whether every replica mounts the same dir.
+func NewLocalBlobStore(dir string, shared bool) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating media directory: %v", err)
+	}
+	return &LocalBlobStore{
+		root:   dir,
+		shared: shared,
+	}, nil
+}
+
+func (l *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file in the same directory and rename it over the blob,
+	// renames are atomic within a filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *LocalBlobStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (l *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalBlobStore) Shared() bool {
+	return l.shared
+}
+
+// path maps a key to a file under the root, rejecting keys that could escape it.
+func (l *LocalBlobStore) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// contextReader stops reading once its context is done, so abandoned uploads don't
+// keep writing.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStorePath(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalBlobStore(root, false)
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr error
+	}{
+		{name: "nested key", key: "ab/abcdef", want: filepath.Join(root, "ab", "abcdef")},
+		{name: "parent directory", key: "../secret", wantErr: ErrInvalidKey},
+		{name: "parent directory inside the key", key: "ab/../../secret", wantErr: ErrInvalidKey},
+		{name: "absolute path", key: "/etc/passwd", wantErr: ErrInvalidKey},
+		{name: "current directory", key: "./ab", wantErr: ErrInvalidKey},
+		{name: "root itself", key: ".", wantErr: ErrInvalidKey},
+		{name: "empty", key: "", wantErr: ErrInvalidKey},
+		{name: "trailing slash", key: "ab/", wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.path(tt.key)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("path(%q) = %q, %v, want %q, %v", tt.key, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() error = %v", err)
+	}
+
+	if err := store.Put(ctx, "ab/abc", strings.NewReader("first")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(ctx, "ab/abc", strings.NewReader("second")); err != nil {
+		t.Fatalf("Put() replacing error = %v", err)
+	}
+	blob, err := store.Open(ctx, "ab/abc")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	content, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil || string(content) != "second" {
+		t.Errorf("Open() content = %q, %v, want the blob put last", content, err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(store.root, "ab", ".upload-*")); len(temps) > 0 {
+		t.Errorf("temporary files left behind: %v", temps)
+	}
+
+	if err := store.Delete(ctx, "ab/abc"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Open(ctx, "ab/abc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() deleted blob error = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete(ctx, "ab/abc"); err != nil {
+		t.Errorf("Delete() missing blob error = %v", err)
+	}
+}
+
+func TestLocalBlobStorePutCancelled(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := store.Put(ctx, "ab/abc", strings.NewReader("content")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Put() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(filepath.Join(store.root, "ab", "abc")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("abandoned upload left a blob behind: %v", err)
+	}
+}
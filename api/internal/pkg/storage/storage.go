@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+const StoreLocal = "local"
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+func ProvideStorage() fx.Option {
+	return fx.Provide(
+		NewBlobStore,
+	)
+}
+
+type storageDeps struct {
+	fx.In
+
+	Config *config.Config
+	Logger *logger.LoggingClient
+}
+
+// BlobStore keeps opaque blobs of content under keys. Keys are slash separated paths
+// without . or .. elements. Implementations have to make Put atomic, a blob is either
+// fully written or not there at all, so readers never see partial content.
+type BlobStore interface {
+	// Put stores the content read from r under key, replacing any blob already there.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open opens the blob stored under key for reading. Blobs are seekable so they
+	// can be served with range requests. ErrNotFound is returned for missing blobs.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete deletes the blob stored under key. Deleting a missing blob isn't an error.
+	Delete(ctx context.Context, key string) error
+	// Shared reports whether every replica sees the same blobs, so a blob put by one
+	// can be opened by another.
+	Shared() bool
+}
+
+// NewBlobStore returns the blob store configured with MEDIA_STORE. Only the local
+// filesystem is supported for now, an S3 compatible store fits the same interface.
+func NewBlobStore(deps storageDeps) (BlobStore, error) {
+	switch deps.Config.MediaStore {
+	case StoreLocal, "":
+		return NewLocalBlobStore(deps.Config.MediaLocalDir, deps.Config.MediaLocalShared)
+	}
+	return nil, fmt.Errorf("unsupported media store %q", deps.Config.MediaStore)
+}
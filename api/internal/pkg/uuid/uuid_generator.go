@@ -7,3 +7,9 @@ import (
 func GenerateUUID() string {
 	return uuid.Generate().String()
 }
+
+// IsValid reports whether s is a UUID in its canonical form.
+func IsValid(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsTransient(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: mysqlErrDeadlock}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadlock", err: deadlock, want: true},
+		{name: "lock wait timeout", err: &mysql.MySQLError{Number: mysqlErrLockWaitTimeout}, want: true},
+		{name: "wrapped deadlock", err: fmt.Errorf("error claiming job: %w", deadlock), want: true},
+		{name: "deadlock flattened into a message", err: fmt.Errorf("error claiming job: %v", deadlock), want: false},
+		{name: "duplicate key", err: &mysql.MySQLError{Number: 1062}, want: false},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "invalid connection", err: mysql.ErrInvalidConn, want: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "other error", err: errors.New("can't publish"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/idempotency"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/jobs"
+	jobservice "github.com/pedromspeixoto/posts-api/internal/domain/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/domain/posts"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+	"go.uber.org/fx"
+)
+
+func InvokeScheduler() fx.Option {
+	return fx.Invoke(NewScheduler)
+}
+
+// Job is a unit of background work run on every tick of the scheduler. Run returns
+// how many items it processed.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) (int, error)
+}
+
+type schedulerDependencies struct {
+	fx.In
+
+	Config                   *config.Config
+	Logger                   *logger.LoggingClient
+	JobRunRepository         jobs.JobRunRepository
+	IdempotencyKeyRepository idempotency.IdempotencyKeyRepository
+	PostService              posts.PostService
+	JobService               jobservice.JobService
+}
+
+// Scheduler runs background jobs at a fixed interval. Every replica of the API runs
+// its own scheduler, so jobs have to be safe to run concurrently.
+type Scheduler struct {
+	schedulerDependencies
+	logger.Logger
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(lc fx.Lifecycle, deps schedulerDependencies) *Scheduler {
+	scheduler := &Scheduler{
+		schedulerDependencies: deps,
+		Logger:                deps.Logger.GetLogger(),
+	}
+	scheduler.jobs = []Job{
+		{
+			Name: "publish_scheduled_posts",
+			Run: func(ctx context.Context) (int, error) {
+				return deps.PostService.PublishScheduledPosts(ctx, deps.Config.SchedulerBatchSize)
+			},
+		},
+	}
+	if deps.Config.TrashRetention > 0 {
+		scheduler.jobs = append(scheduler.jobs, Job{
+			Name: "purge_trashed_posts",
+			Run: func(ctx context.Context) (int, error) {
+				return deps.PostService.PurgeTrashedPosts(ctx, deps.Config.TrashRetention, deps.Config.SchedulerBatchSize)
+			},
+		})
+	}
+
+	scheduler.jobs = append(scheduler.jobs, Job{
+		Name: "purge_expired_idempotency_keys",
+		Run: func(ctx context.Context) (int, error) {
+			return deps.IdempotencyKeyRepository.PurgeExpired(ctx, time.Now(), deps.Config.SchedulerBatchSize)
+		},
+	})
+
+	// every tick of every job on every replica records a run
+	if deps.Config.JobRunRetention > 0 {
+		scheduler.jobs = append(scheduler.jobs, Job{
+			Name: "purge_job_runs",
+			Run: func(ctx context.Context) (int, error) {
+				return deps.JobRunRepository.PurgeStarted(ctx, time.Now().Add(-deps.Config.JobRunRetention), deps.Config.SchedulerBatchSize)
+			},
+		})
+	}
+
+	scheduler.jobs = append(scheduler.jobs, Job{
+		Name: "run_queued_jobs",
+		Run: func(ctx context.Context) (int, error) {
+			return deps.JobService.RunQueuedJobs(ctx)
+		},
+	})
+
+	if !deps.Config.SchedulerEnabled {
+		scheduler.Info("scheduler is disabled")
+		return scheduler
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			scheduler.Info("starting scheduler")
+			ctx, cancel := context.WithCancel(context.Background())
+			scheduler.cancel = cancel
+			for _, job := range scheduler.jobs {
+				scheduler.wg.Add(1)
+				go scheduler.loop(ctx, job)
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			scheduler.Info("stopping scheduler")
+			scheduler.cancel()
+
+			// wait for running jobs to notice, up to the stop timeout
+			done := make(chan struct{})
+			go func() {
+				scheduler.wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	return scheduler
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.Config.SchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs a job, retrying transient failures with exponential backoff, and records
+// the run.
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	run := &jobs.JobRun{
+		Job:       job.Name,
+		Status:    jobs.StatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := s.JobRunRepository.Create(run); err != nil {
+		s.Warningf("error recording start of job %s: %v", job.Name, err)
+	}
+
+	backoff := s.Config.SchedulerRetryBackoff
+	var err error
+	for {
+		run.Attempts++
+		run.Processed, err = job.Run(ctx)
+		if err == nil || !isTransient(err) || run.Attempts >= s.Config.SchedulerMaxAttempts {
+			break
+		}
+
+		s.Warningf("job %s failed on attempt %d, retrying in %s: %v", job.Name, run.Attempts, backoff, err)
+		if !sleep(ctx, backoff) {
+			err = ctx.Err()
+			break
+		}
+		backoff *= 2
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = jobs.StatusSucceeded
+	if err != nil {
+		run.Status = jobs.StatusFailed
+		run.Error = err.Error()
+		s.Errorf("job %s failed after %d attempts: %v", job.Name, run.Attempts, err)
+	}
+
+	// a run that never got created has no id to update, it's recorded as a new row
+	if err := s.JobRunRepository.Update(run); err != nil {
+		s.Warningf("error recording outcome of job %s: %v", job.Name, err)
+	}
+}
+
+// sleep waits for the duration, returning false if the context is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pedromspeixoto/posts-api/internal/config"
+	"github.com/pedromspeixoto/posts-api/internal/data/models/jobs"
+	"github.com/pedromspeixoto/posts-api/internal/pkg/logger"
+)
+
+// fakeJobRuns records the job runs it's asked to store.
+type fakeJobRuns struct {
+	jobs.JobRunRepository
+
+	created int
+	updated []jobs.JobRun
+}
+
+func (f *fakeJobRuns) Create(run *jobs.JobRun) error {
+	f.created++
+	return nil
+}
+
+func (f *fakeJobRuns) Update(run *jobs.JobRun) error {
+	f.updated = append(f.updated, *run)
+	return nil
+}
+
+func TestSchedulerRun(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: mysqlErrDeadlock}
+	backoff := 5 * time.Millisecond
+
+	tests := []struct {
+		name         string
+		errs         []error
+		cancelled    bool
+		wantAttempts int
+		wantStatus   string
+		wantErr      string
+		wantElapsed  time.Duration
+	}{
+		{name: "succeeds", errs: []error{nil}, wantAttempts: 1, wantStatus: jobs.StatusSucceeded},
+		{name: "transient failures are retried with backoff", errs: []error{deadlock, deadlock, nil}, wantAttempts: 3, wantStatus: jobs.StatusSucceeded, wantElapsed: backoff + 2*backoff},
+		{name: "other failures aren't retried", errs: []error{errors.New("can't publish")}, wantAttempts: 1, wantStatus: jobs.StatusFailed, wantErr: "can't publish"},
+		{name: "retries are bounded", errs: []error{deadlock, deadlock, deadlock, nil}, wantAttempts: 3, wantStatus: jobs.StatusFailed, wantErr: deadlock.Error()},
+		{name: "stopping interrupts the backoff", errs: []error{deadlock, nil}, cancelled: true, wantAttempts: 1, wantStatus: jobs.StatusFailed, wantErr: context.Canceled.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := &fakeJobRuns{}
+			scheduler := &Scheduler{
+				schedulerDependencies: schedulerDependencies{
+					Config:           &config.Config{SchedulerMaxAttempts: 3, SchedulerRetryBackoff: backoff},
+					JobRunRepository: runs,
+				},
+				Logger: logger.NewStdoutLogger(logger.LoggingLevelNone),
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+
+			attempts := 0
+			start := time.Now()
+			scheduler.run(ctx, Job{Name: "test", Run: func(ctx context.Context) (int, error) {
+				err := tt.errs[attempts]
+				attempts++
+				return attempts, err
+			}})
+			elapsed := time.Since(start)
+
+			if runs.created != 1 || len(runs.updated) != 1 {
+				t.Fatalf("recorded %d starts and %d outcomes, want 1 of each", runs.created, len(runs.updated))
+			}
+			run := runs.updated[0]
+			if run.Attempts != tt.wantAttempts || attempts != tt.wantAttempts {
+				t.Errorf("ran %d times, recorded %d attempts, want %d", attempts, run.Attempts, tt.wantAttempts)
+			}
+			if run.Status != tt.wantStatus || run.Error != tt.wantErr {
+				t.Errorf("run = %s %q, want %s %q", run.Status, run.Error, tt.wantStatus, tt.wantErr)
+			}
+			if run.FinishedAt == nil {
+				t.Error("run has no finish time")
+			}
+			if elapsed < tt.wantElapsed {
+				t.Errorf("retries took %s, want the backoff to double from %s", elapsed, backoff)
+			}
+		})
+	}
+}
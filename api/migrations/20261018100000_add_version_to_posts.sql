@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts` ADD COLUMN `version` int unsigned NOT NULL DEFAULT 1 AFTER `content`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP COLUMN `version`;
+-- +goose StatementEnd
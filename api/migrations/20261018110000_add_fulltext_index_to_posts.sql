@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts` ADD FULLTEXT INDEX `ft_posts_content` (`content`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP INDEX `ft_posts_content`;
+-- +goose StatementEnd
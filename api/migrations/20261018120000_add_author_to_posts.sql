@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts` ADD COLUMN `author_id` varchar(45) DEFAULT NULL AFTER `post_id`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX `idx_posts_author_id` ON `posts` (`author_id`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_posts_author_id` ON `posts`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP COLUMN `author_id`;
+-- +goose StatementEnd
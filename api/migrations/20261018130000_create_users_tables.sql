@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `users` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `user_id`       varchar(45) NOT NULL,
+                        `email`         varchar(255) NOT NULL,
+                        `password_hash` varchar(255) NOT NULL,
+                        `role`          varchar(32) NOT NULL,
+                        created_at      datetime(3) NULL,
+                        updated_at      datetime(3) NULL,
+                        deleted_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_users_user_id` (`user_id`),
+                        UNIQUE KEY `uq_users_email` (`email`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE `refresh_tokens` (
+                        `id`          int NOT NULL AUTO_INCREMENT,
+                        `user_id`     varchar(45) NOT NULL,
+                        `token_hash`  char(64) NOT NULL,
+                        `expires_at`  datetime(3) NOT NULL,
+                        `revoked_at`  datetime(3) NULL,
+                        created_at    datetime(3) NULL,
+                        updated_at    datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_refresh_tokens_token_hash` (`token_hash`),
+                        KEY `idx_refresh_tokens_user_id` (`user_id`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE refresh_tokens;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE users;
+-- +goose StatementEnd
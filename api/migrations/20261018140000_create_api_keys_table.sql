@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `api_keys` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `key_id`        varchar(45) NOT NULL,
+                        `name`          varchar(255) NOT NULL,
+                        `prefix`        varchar(16) NOT NULL,
+                        `key_hash`      char(64) NOT NULL,
+                        `scopes`        varchar(255) NOT NULL,
+                        `owner_id`      varchar(45) NOT NULL,
+                        `expires_at`    datetime(3) NULL,
+                        `last_used_at`  datetime(3) NULL,
+                        `revoked_at`    datetime(3) NULL,
+                        created_at      datetime(3) NULL,
+                        updated_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_api_keys_key_id` (`key_id`),
+                        UNIQUE KEY `uq_api_keys_key_hash` (`key_hash`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts`
+    ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'draft' AFTER `content`,
+    ADD COLUMN `published_at` datetime(3) DEFAULT NULL AFTER `status`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- posts were public as soon as they were created until now, keep them that way
+UPDATE `posts` SET `status` = 'published', `published_at` = `created_at`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX `idx_posts_status_published_at` ON `posts` (`status`, `published_at`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_posts_status_published_at` ON `posts`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP COLUMN `published_at`, DROP COLUMN `status`;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts` ADD COLUMN `publish_at` datetime(3) DEFAULT NULL AFTER `published_at`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX `idx_posts_status_publish_at` ON `posts` (`status`, `publish_at`);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE `job_runs` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `job`           varchar(100) NOT NULL,
+                        `status`        varchar(20) NOT NULL,
+                        `attempts`      int NOT NULL DEFAULT 0,
+                        `processed`     int NOT NULL DEFAULT 0,
+                        `error`         text NULL,
+                        `started_at`    datetime(3) NOT NULL,
+                        `finished_at`   datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        KEY `idx_job_runs_job_started_at` (`job`, `started_at`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE job_runs;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP INDEX `idx_posts_status_publish_at` ON `posts`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP COLUMN `publish_at`;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- later migrations key revisions and slugs by post id, which has to be set and unique
+UPDATE `posts` SET `post_id` = UUID() WHERE `post_id` IS NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- the oldest post keeps a duplicated post id, the others get a new one
+UPDATE `posts` JOIN (
+    SELECT `id` FROM (
+        SELECT `id`, ROW_NUMBER() OVER (PARTITION BY `post_id` ORDER BY `id`) AS `position` FROM `posts`
+    ) AS `ranked` WHERE `position` > 1
+) AS `duplicates` ON `duplicates`.`id` = `posts`.`id`
+SET `posts`.`post_id` = UUID();
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` MODIFY COLUMN `post_id` varchar(45) NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `posts` MODIFY COLUMN `post_id` varchar(45) DEFAULT NULL;
+-- +goose StatementEnd
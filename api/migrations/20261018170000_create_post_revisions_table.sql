@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `post_revisions` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `post_id`       varchar(45) NOT NULL,
+                        `revision`      int unsigned NOT NULL,
+                        `editor_id`     varchar(45) DEFAULT NULL,
+                        `content`       text NOT NULL,
+                        created_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_post_revisions_post_id_revision` (`post_id`, `revision`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- the current content of existing posts becomes their first revision
+INSERT INTO `post_revisions` (`post_id`, `revision`, `editor_id`, `content`, `created_at`)
+SELECT `post_id`, 1, `author_id`, `content`, COALESCE(`updated_at`, `created_at`) FROM `posts`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_revisions;
+-- +goose StatementEnd
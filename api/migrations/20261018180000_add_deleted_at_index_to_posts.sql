@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX `idx_posts_deleted_at` ON `posts` (`deleted_at`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_posts_deleted_at` ON `posts`;
+-- +goose StatementEnd
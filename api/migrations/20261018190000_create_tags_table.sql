@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `tags` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `name`          varchar(64) NOT NULL,
+                        created_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_tags_name` (`name`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE `post_tags` (
+                        `post_id`       varchar(45) NOT NULL,
+                        `tag_id`        int NOT NULL,
+                        PRIMARY KEY (`post_id`, `tag_id`),
+                        KEY `idx_post_tags_tag_id` (`tag_id`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_tags;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE tags;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts`
+    ADD COLUMN `title` varchar(255) NOT NULL DEFAULT '' AFTER `author_id`,
+    ADD COLUMN `slug` varchar(128) DEFAULT NULL AFTER `title`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- posts without a title go by their post id until they get one, post ids are set and
+-- unique since 20261018165000
+UPDATE `posts` SET `slug` = `post_id`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts`
+    MODIFY COLUMN `slug` varchar(128) NOT NULL,
+    ADD UNIQUE KEY `uq_posts_slug` (`slug`);
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- every slug a post ever had, so old links keep resolving
+CREATE TABLE `post_slugs` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `slug`          varchar(128) NOT NULL,
+                        `post_id`       varchar(45) NOT NULL,
+                        created_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_post_slugs_slug` (`slug`),
+                        KEY `idx_post_slugs_post_id` (`post_id`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+INSERT INTO `post_slugs` (`slug`, `post_id`, `created_at`)
+SELECT `slug`, `post_id`, `created_at` FROM `posts`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_slugs;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP INDEX `uq_posts_slug`, DROP COLUMN `slug`, DROP COLUMN `title`;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `comments` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `comment_id`    varchar(45) NOT NULL,
+                        `post_id`       varchar(45) NOT NULL,
+                        `parent_id`     varchar(45) DEFAULT NULL,
+                        `author_id`     varchar(45) NOT NULL,
+                        `content`       text NOT NULL,
+                        created_at      datetime(3) NULL,
+                        updated_at      datetime(3) NULL,
+                        deleted_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_comments_comment_id` (`comment_id`),
+                        KEY `idx_comments_post_id_created_at` (`post_id`, `created_at`),
+                        KEY `idx_comments_parent_id` (`parent_id`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` ADD COLUMN `comment_count` int unsigned NOT NULL DEFAULT 0 AFTER `version`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP COLUMN `comment_count`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE comments;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `post_reactions` (
+                        `post_id`       varchar(45) NOT NULL,
+                        `kind`          varchar(32) NOT NULL,
+                        `principal_id`  varchar(45) NOT NULL,
+                        created_at      datetime(3) NULL,
+                        PRIMARY KEY (`post_id`, `kind`, `principal_id`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- denormalized counts of post_reactions, kept in step with it in the same transaction
+CREATE TABLE `post_reaction_counts` (
+                        `post_id`       varchar(45) NOT NULL,
+                        `kind`          varchar(32) NOT NULL,
+                        `count`         int unsigned NOT NULL DEFAULT 0,
+                        PRIMARY KEY (`post_id`, `kind`),
+                        KEY `idx_post_reaction_counts_kind_count` (`kind`, `count`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_reaction_counts;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE post_reactions;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `posts`
+    MODIFY COLUMN `content` mediumtext,
+    ADD COLUMN `format` varchar(16) NOT NULL DEFAULT 'plain' AFTER `content`,
+    ADD COLUMN `content_html` mediumtext AFTER `format`,
+    ADD COLUMN `excerpt` varchar(300) NOT NULL DEFAULT '' AFTER `content_html`,
+    ADD COLUMN `reading_time` int unsigned NOT NULL DEFAULT 0 AFTER `excerpt`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- existing posts are plain text, rendered as a single escaped paragraph
+UPDATE `posts` SET
+    `content_html` = CONCAT('<p>', REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(COALESCE(`content`, ''),
+        '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'), '</p>'),
+    `excerpt` = LEFT(COALESCE(`content`, ''), 280),
+    `reading_time` = CEIL((CHAR_LENGTH(TRIM(COALESCE(`content`, ''))) - CHAR_LENGTH(REPLACE(TRIM(COALESCE(`content`, '')), ' ', '')) + 1) / 200);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `post_revisions`
+    MODIFY COLUMN `content` mediumtext NOT NULL,
+    ADD COLUMN `format` varchar(16) NOT NULL DEFAULT 'plain' AFTER `content`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `post_revisions`
+    DROP COLUMN `format`,
+    MODIFY COLUMN `content` text NOT NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts`
+    DROP COLUMN `reading_time`,
+    DROP COLUMN `excerpt`,
+    DROP COLUMN `content_html`,
+    DROP COLUMN `format`,
+    MODIFY COLUMN `content` varchar(255) DEFAULT NULL;
+-- +goose StatementEnd
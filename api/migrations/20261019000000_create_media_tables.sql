@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `media` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `media_id`      varchar(45) NOT NULL,
+                        `owner_id`      varchar(45) NOT NULL,
+                        `filename`      varchar(255) NOT NULL DEFAULT '',
+                        `content_type`  varchar(100) NOT NULL,
+                        `size`          bigint NOT NULL,
+                        `checksum`      char(64) NOT NULL,
+                        `storage_key`   varchar(255) NOT NULL,
+                        created_at      datetime(3) NULL,
+                        updated_at      datetime(3) NULL,
+                        deleted_at      datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_media_media_id` (`media_id`),
+                        KEY `idx_media_owner_id` (`owner_id`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- the media attached to a post, in the order they were given
+CREATE TABLE `post_media` (
+                        `post_id`       varchar(45) NOT NULL,
+                        `media_id`      varchar(45) NOT NULL,
+                        `position`      int unsigned NOT NULL DEFAULT 0,
+                        PRIMARY KEY (`post_id`, `media_id`),
+                        KEY `idx_post_media_media_id` (`media_id`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_media;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE media;
+-- +goose StatementEnd
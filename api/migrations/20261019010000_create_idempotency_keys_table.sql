@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `idempotency_keys` (
+                        `id`                int NOT NULL AUTO_INCREMENT,
+                        `scope`             varchar(100) NOT NULL,
+                        `idempotency_key`   varchar(255) NOT NULL,
+                        `fingerprint`       char(64) NOT NULL,
+                        `status_code`       int DEFAULT NULL,
+                        `response_headers`  text,
+                        `response_body`     mediumblob,
+                        created_at          datetime(3) NULL,
+                        `expires_at`        datetime(3) NOT NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_idempotency_keys_scope_key` (`scope`, `idempotency_key`),
+                        KEY `idx_idempotency_keys_expires_at` (`expires_at`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE idempotency_keys;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- posts can be created at an id of the client's choosing, which has to be unique
+ALTER TABLE `posts` ADD UNIQUE KEY `uq_posts_post_id` (`post_id`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `posts` DROP INDEX `uq_posts_post_id`;
+-- +goose StatementEnd
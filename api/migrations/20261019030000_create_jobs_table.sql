@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `jobs` (
+                        `id`            int NOT NULL AUTO_INCREMENT,
+                        `job_id`        varchar(45) NOT NULL,
+                        `kind`          varchar(50) NOT NULL,
+                        `status`        varchar(20) NOT NULL,
+                        `owner`         text NOT NULL,
+                        `params`        text,
+                        `input_key`     varchar(255) NOT NULL DEFAULT '',
+                        `processed`     int NOT NULL DEFAULT 0,
+                        `result`        mediumtext,
+                        `error`         text NULL,
+                        created_at      datetime(3) NULL,
+                        updated_at      datetime(3) NULL,
+                        `started_at`    datetime(3) NULL,
+                        `finished_at`   datetime(3) NULL,
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `uq_jobs_job_id` (`job_id`),
+                        KEY `idx_jobs_status_created_at` (`status`, `created_at`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE jobs;
+-- +goose StatementEnd
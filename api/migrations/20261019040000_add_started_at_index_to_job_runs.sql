@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- job runs are purged by age across all jobs
+CREATE INDEX `idx_job_runs_started_at` ON `job_runs` (`started_at`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_job_runs_started_at` ON `job_runs`;
+-- +goose StatementEnd
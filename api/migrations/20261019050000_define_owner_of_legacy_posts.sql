@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- posts written before authors were recorded belong to no one, no principal has an
+-- empty subject so only admins can modify them
+UPDATE `posts` SET `author_id` = '' WHERE `author_id` IS NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `posts` MODIFY COLUMN `author_id` varchar(45) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `posts` MODIFY COLUMN `author_id` varchar(45) DEFAULT NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE `posts` SET `author_id` = NULL WHERE `author_id` = '';
+-- +goose StatementEnd